@@ -1,57 +1,200 @@
 package main
 
 import (
-	"encoding/base64"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"log"
 	"net/http"
+	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"docparser/internal/extract"
+	"docparser/internal/store"
 )
 
+// extractRequest is the /extract body. Its content_base64 field is decoded
+// into data by decodeExtractRequest; a multipart/form-data upload fills the
+// same fields through decodeMultipartRequest.
 type extractRequest struct {
+	Filename        string          `json:"filename"`
+	Format          string          `json:"format,omitempty"`
+	Encoding        string          `json:"encoding,omitempty"`
+	ExtractPattern  string          `json:"extract_pattern,omitempty"`
+	WordFrequencies int             `json:"word_frequencies,omitempty"`
+	Stopwords       []string        `json:"stopwords,omitempty"`
+	Tables          bool            `json:"tables,omitempty"`
+	ChunkSize       int             `json:"chunk_size,omitempty"`
+	ChunkOverlap    int             `json:"chunk_overlap,omitempty"`
+	Options         json.RawMessage `json:"options,omitempty"`
+
+	data []byte
+}
+
+type extractResponse struct {
+	Success          bool                `json:"success"`
+	Text             string              `json:"text"`
+	Format           string              `json:"format,omitempty"`
+	DetectedEncoding string              `json:"detected_encoding,omitempty"`
+	PageCount        int                 `json:"page_count,omitempty"`
+	Warnings         []string            `json:"warnings,omitempty"`
+	Matches          [][]string          `json:"matches,omitempty"`
+	InputSHA256      string              `json:"input_sha256,omitempty"`
+	TextSHA256       string              `json:"text_sha256,omitempty"`
+	TimingsMs        map[string]float64  `json:"timings_ms,omitempty"`
+	WordFrequencies  []extract.WordCount `json:"word_frequencies,omitempty"`
+	Tables           []extract.Table     `json:"tables,omitempty"`
+	Chunks           []string            `json:"chunks,omitempty"`
+	ResultID         string              `json:"result_id,omitempty"`
+}
+
+type detectEncodingRequest struct {
+	ContentBase64 string `json:"content_base64"`
+}
+
+type detectEncodingResponse struct {
+	Encoding   string  `json:"encoding"`
+	Confidence float64 `json:"confidence"`
+}
+
+type verifyRequest struct {
 	Filename      string `json:"filename"`
 	ContentBase64 string `json:"content_base64"`
 }
 
-type extractResponse struct {
-	Success bool   `json:"success"`
-	Text    string `json:"text"`
+type verifyResponse struct {
+	Filename       string `json:"filename"`
+	Extension      string `json:"extension"`
+	DetectedFormat string `json:"detected_format"`
+	Mismatch       bool   `json:"mismatch"`
 }
 
+// batchItem is a file of an /extract/batch body. Unlike /extract, a batch body
+// is decoded whole, content_base64 strings included; -max-body-bytes bounds it.
 type batchItem struct {
 	Filename      string `json:"filename"`
 	ContentBase64 string `json:"content_base64"`
+	Format        string `json:"format,omitempty"`
+	Encoding      string `json:"encoding,omitempty"`
 }
 
 type batchRequest struct {
-	Files []batchItem `json:"files"`
+	Files          []batchItem     `json:"files"`
+	ExtractPattern string          `json:"extract_pattern,omitempty"`
+	Options        json.RawMessage `json:"options,omitempty"`
 }
 
 type batchResponseItem struct {
-	Filename string `json:"filename"`
-	Success  bool   `json:"success"`
-	Text     string `json:"text"`
+	Filename    string             `json:"filename"`
+	Success     bool               `json:"success"`
+	Status      string             `json:"status,omitempty"`
+	Text        string             `json:"text"`
+	Matches     [][]string         `json:"matches,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	DurationMs  int64              `json:"duration_ms"`
+	InputSHA256 string             `json:"input_sha256,omitempty"`
+	TextSHA256  string             `json:"text_sha256,omitempty"`
+	TimingsMs   map[string]float64 `json:"timings_ms,omitempty"`
 }
 
 type batchResponse struct {
 	Results []batchResponseItem `json:"results"`
 }
 
+// extractTimeout bounds the extraction of an /extract request; zero disables it.
+var extractTimeout = 60 * time.Second
+
+// batchItemTimeout bounds the extraction of a single batch item; zero disables it.
+var batchItemTimeout = 60 * time.Second
+
+// batchMaxOutputBytes caps the text (or matches) returned by one /extract/batch
+// response; items after the cap is reached are skipped. Zero disables it.
+var batchMaxOutputBytes int64 = 64 << 20
+
+// batchConcurrency is the number of /extract/batch files extracted at once.
+var batchConcurrency = runtime.NumCPU()
+
+// maxBodyBytes caps the size of a request body; zero disables it. Decoded
+// content is smaller than the base64 text carrying it, so this bounds it too.
+var maxBodyBytes int64 = 32 << 20
+
+// resultStore keeps successful /extract responses for GET /results/{id} when
+// -result-ttl is set; nil disables it.
+var resultStore store.ResultStore
+
+// resultTTL is how long resultStore keeps a response.
+var resultTTL time.Duration
+
+// statusSkippedSizeLimit marks batch items left out by batchMaxOutputBytes.
+const statusSkippedSizeLimit = "skipped_size_limit"
+
+// limitBody caps r.Body at maxBodyBytes; reading past it fails with an error
+// that bodyTooLarge recognizes.
+func limitBody(w http.ResponseWriter, r *http.Request) {
+	if maxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	}
+}
+
+// bodyTooLarge reports whether err comes from reading past maxBodyBytes.
+func bodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+// bodyTooLargeMessage is the error returned with status 413.
+func bodyTooLargeMessage() string {
+	return "request body exceeds " + strconv.FormatInt(maxBodyBytes, 10) + " bytes"
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// debugRequested reports whether the client asked for diagnostics with ?debug=true.
+func debugRequested(r *http.Request) bool {
+	debug, _ := strconv.ParseBool(r.URL.Query().Get("debug"))
+	return debug
+}
+
+// timingsMs converts phase durations to fractional milliseconds for JSON.
+func timingsMs(timings map[string]time.Duration) map[string]float64 {
+	ms := make(map[string]float64, len(timings))
+	for phase, d := range timings {
+		ms[phase] = float64(d) / float64(time.Millisecond)
+	}
+	return ms
+}
+
+// compilePattern compiles an optional extract_pattern; an empty pattern yields nil.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	// a missing pdftotext does not fail the check, PDFs are still read by the
+	// built-in parser, but operators should see it
+	pdftotext := "available"
+	if !extract.PDFToolAvailable() {
+		pdftotext = "missing"
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "pdftotext": pdftotext})
 }
 
 func handleExtract(w http.ResponseWriter, r *http.Request) {
@@ -60,34 +203,161 @@ func handleExtract(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	limitBody(w, r)
 	var req extractRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	var err error
+	if isMultipart(r) {
+		req, err = decodeMultipartRequest(r)
+	} else {
+		req, err = decodeExtractRequest(r.Body, r.ContentLength)
+	}
+	if bodyTooLarge(err) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, extractResponse{Success: false, Text: bodyTooLargeMessage()})
+		return
+	}
+	var b64Err base64Error
+	if errors.As(err, &b64Err) {
+		writeJSON(w, http.StatusBadRequest, extractResponse{Success: false, Text: "invalid base64: " + err.Error()})
+		return
+	}
+	var fErr formError
+	if errors.As(err, &fErr) {
+		writeJSON(w, http.StatusBadRequest, extractResponse{Success: false, Text: "invalid form: " + err.Error()})
+		return
+	}
+	if err != nil {
 		writeJSON(w, http.StatusBadRequest, extractResponse{Success: false, Text: "invalid json: " + err.Error()})
 		return
 	}
+	opts, err := parseOptions(r, req.Options)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, extractResponse{Success: false, Text: "invalid options: " + err.Error()})
+		return
+	}
 
 	if strings.TrimSpace(req.Filename) == "" {
 		writeJSON(w, http.StatusBadRequest, extractResponse{Success: false, Text: "filename is required"})
 		return
 	}
-	if strings.TrimSpace(req.ContentBase64) == "" {
+	re, err := compilePattern(req.ExtractPattern)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, extractResponse{Success: false, Text: "invalid extract_pattern: " + err.Error()})
+		return
+	}
+	if req.ChunkSize != 0 || req.ChunkOverlap != 0 {
+		if _, err := extract.ChunkText("", req.ChunkSize, req.ChunkOverlap); err != nil {
+			writeJSON(w, http.StatusBadRequest, extractResponse{Success: false, Text: err.Error()})
+			return
+		}
+	}
+	if len(req.data) == 0 {
 		writeJSON(w, http.StatusBadRequest, extractResponse{Success: false, Text: "content_base64 is required"})
 		return
 	}
+	data := req.data
 
-	data, err := base64.StdEncoding.DecodeString(req.ContentBase64)
+	opts.Format = req.Format
+	opts.Encoding = req.Encoding
+	res, err := extractWithTimeout(r.Context(), extractTimeout, req.Filename, data, opts)
+	if errors.Is(err, context.Canceled) {
+		// the client is gone
+		return
+	}
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, extractResponse{Success: false, Text: "invalid base64: " + err.Error()})
+		resp := extractResponse{Success: false, Text: err.Error(), Format: res.Format, Warnings: res.Warnings}
+		if debugRequested(r) {
+			resp.TimingsMs = timingsMs(res.Timings)
+		}
+		writeJSON(w, http.StatusOK, resp)
 		return
 	}
 
-	text, err := extract.ExtractText(req.Filename, data)
-	if err != nil {
-		writeJSON(w, http.StatusOK, extractResponse{Success: false, Text: err.Error()})
+	resp := extractResponse{
+		Success:          true,
+		Text:             res.Text,
+		Format:           res.Format,
+		DetectedEncoding: res.DetectedEncoding,
+		PageCount:        res.PageCount,
+		Warnings:         res.Warnings,
+		InputSHA256:      res.InputSHA256,
+		TextSHA256:       res.TextSHA256,
+	}
+	if debugRequested(r) {
+		resp.TimingsMs = timingsMs(res.Timings)
+	}
+	if req.WordFrequencies > 0 {
+		stopwords := extract.DefaultStopwords
+		if req.Stopwords != nil {
+			stopwords = req.Stopwords
+		}
+		resp.WordFrequencies = extract.WordFrequenciesWithStopwords(res.Text, req.WordFrequencies, stopwords)
+	}
+	if req.Tables {
+		tables, err := extract.ExtractTablesWithOptions(req.Filename, data, opts)
+		if err != nil {
+			writeJSON(w, http.StatusOK, extractResponse{Success: false, Text: "tables: " + err.Error()})
+			return
+		}
+		resp.Tables = tables
+	}
+	if re != nil {
+		// only the matches are returned, each with its capture groups
+		resp.Text = ""
+		resp.Matches = re.FindAllStringSubmatch(res.Text, -1)
+	}
+	if req.ChunkSize > 0 {
+		// the chunks replace the text
+		resp.Text = ""
+		resp.Chunks, _ = extract.ChunkText(res.Text, req.ChunkSize, req.ChunkOverlap)
+	}
+	storeResult(&resp)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// storeResult saves resp in resultStore under a new id, which it records in
+// resp.ResultID. A failure is logged and the response goes out without an id.
+func storeResult(resp *extractResponse) {
+	if resultStore == nil {
+		return
+	}
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		log.Printf("store result: %v", err)
 		return
 	}
+	resp.ResultID = hex.EncodeToString(id[:])
+	data, err := json.Marshal(resp)
+	if err == nil {
+		err = resultStore.Put(resp.ResultID, data, resultTTL)
+	}
+	if err != nil {
+		log.Printf("store result: %v", err)
+		resp.ResultID = ""
+	}
+}
 
-	writeJSON(w, http.StatusOK, extractResponse{Success: true, Text: text})
+// handleResult returns an /extract response kept in resultStore.
+func handleResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/results/")
+	if resultStore == nil || id == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "result not found"})
+		return
+	}
+	data, ok, err := resultStore.Get(id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "result store: " + err.Error()})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "result not found"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(data)
 }
 
 func handleExtractBatch(w http.ResponseWriter, r *http.Request) {
@@ -97,59 +367,283 @@ func handleExtractBatch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req batchRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	limitBody(w, r)
+	if err := json.NewDecoder(r.Body).Decode(&req); bodyTooLarge(err) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": bodyTooLargeMessage()})
+		return
+	} else if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json: " + err.Error()})
 		return
 	}
+	opts, err := parseOptions(r, req.Options)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid options: " + err.Error()})
+		return
+	}
 	if len(req.Files) == 0 {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "files is required and must be non-empty"})
 		return
 	}
+	re, err := compilePattern(req.ExtractPattern)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid extract_pattern: " + err.Error()})
+		return
+	}
 
-	results := make([]batchResponseItem, 0, len(req.Files))
-	for _, f := range req.Files {
-		item := batchResponseItem{Filename: strings.TrimSpace(f.Filename)}
-		if item.Filename == "" {
-			item.Success = false
-			item.Text = "filename is required"
-			results = append(results, item)
-			continue
+	debug := debugRequested(r)
+	results := make([]batchResponseItem, len(req.Files))
+	// output bytes of every finished item, -1 while pending; items that are
+	// bound to fall past batchMaxOutputBytes are not extracted at all
+	var mu sync.Mutex
+	sizes := make([]int64, len(req.Files))
+	for i := range sizes {
+		sizes[i] = -1
+	}
+	pastLimit := func(i int) bool {
+		if batchMaxOutputBytes <= 0 {
+			return false
 		}
-		if strings.TrimSpace(f.ContentBase64) == "" {
-			item.Success = false
-			item.Text = "content_base64 is required"
-			results = append(results, item)
-			continue
+		mu.Lock()
+		defer mu.Unlock()
+		var n int64
+		for _, size := range sizes[:i] {
+			n += max(size, 0)
 		}
-		data, err := base64.StdEncoding.DecodeString(f.ContentBase64)
-		if err != nil {
-			item.Success = false
-			item.Text = "invalid base64: " + err.Error()
-			results = append(results, item)
+		return n >= batchMaxOutputBytes
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(batchConcurrency, 1), len(req.Files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// a skipped item is replaced when the limit is applied below
+				var size int64
+				if !pastLimit(i) {
+					results[i] = extractBatchFile(r.Context(), req.Files[i], opts, re, debug)
+					if results[i].Success {
+						size = itemOutputBytes(results[i])
+					}
+				}
+				mu.Lock()
+				sizes[i] = size
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range req.Files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// the limit applies in request order, whichever items finished first
+	var outputBytes int64
+	for i, f := range req.Files {
+		if batchMaxOutputBytes > 0 && outputBytes >= batchMaxOutputBytes {
+			results[i] = batchResponseItem{
+				Filename: strings.TrimSpace(f.Filename),
+				Status:   statusSkippedSizeLimit,
+				Text:     "skipped: batch output limit of " + strconv.FormatInt(batchMaxOutputBytes, 10) + " bytes reached",
+			}
 			continue
 		}
-		text, err := extract.ExtractText(item.Filename, data)
-		if err != nil {
-			item.Success = false
-			item.Text = err.Error()
-		} else {
-			item.Success = true
-			item.Text = text
-		}
-		results = append(results, item)
+		outputBytes += sizes[i]
 	}
 
 	writeJSON(w, http.StatusOK, batchResponse{Results: results})
 }
 
+// extractBatchFile extracts one file of an /extract/batch request; failures
+// are reported in the item rather than failing the batch.
+func extractBatchFile(ctx context.Context, f batchItem, opts extract.Options, re *regexp.Regexp, debug bool) batchResponseItem {
+	item := batchResponseItem{Filename: strings.TrimSpace(f.Filename)}
+	if item.Filename == "" {
+		item.Text = "filename is required"
+		return item
+	}
+	if strings.TrimSpace(f.ContentBase64) == "" {
+		item.Text = "content_base64 is required"
+		return item
+	}
+	data, err := decodeBase64(f.ContentBase64)
+	if err != nil {
+		item.Text = "invalid base64: " + err.Error()
+		return item
+	}
+	opts.Format = f.Format
+	opts.Encoding = f.Encoding
+	start := time.Now()
+	res, err := extractBatchItem(ctx, item.Filename, data, opts)
+	item.DurationMs = time.Since(start).Milliseconds()
+	item.Format = res.Format
+	if debug {
+		item.TimingsMs = timingsMs(res.Timings)
+	}
+	if err != nil {
+		item.Text = err.Error()
+		return item
+	}
+	item.Success = true
+	item.InputSHA256 = res.InputSHA256
+	item.TextSHA256 = res.TextSHA256
+	if re != nil {
+		item.Matches = re.FindAllStringSubmatch(res.Text, -1)
+	} else {
+		item.Text = res.Text
+	}
+	return item
+}
+
+// redactHook masks every match of re in extracted text.
+func redactHook(re *regexp.Regexp) func(string) (string, error) {
+	return func(text string) (string, error) {
+		return re.ReplaceAllString(text, "[REDACTED]"), nil
+	}
+}
+
+// itemOutputBytes is the size of the extracted output inlined in a batch item.
+func itemOutputBytes(item batchResponseItem) int64 {
+	n := int64(len(item.Text))
+	for _, m := range item.Matches {
+		for _, g := range m {
+			n += int64(len(g))
+		}
+	}
+	return n
+}
+
+// extractBatchItem extracts one batch file under batchItemTimeout, so a hung
+// extraction fails that item while the rest of the batch proceeds.
+func extractBatchItem(ctx context.Context, filename string, data []byte, opts extract.Options) (extract.Result, error) {
+	return extractWithTimeout(ctx, batchItemTimeout, filename, data, opts)
+}
+
+// extractWithTimeout extracts under ctx, which is the request's, so a client
+// disconnect aborts the work, and under timeout unless it is zero.
+func extractWithTimeout(ctx context.Context, timeout time.Duration, filename string, data []byte, opts extract.Options) (extract.Result, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	res, err := extract.ExtractDetailedContext(ctx, filename, data, opts)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = errors.New("extraction timed out after " + timeout.String())
+	}
+	return res, err
+}
+
+func handleDetectEncoding(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req detectEncodingRequest
+	limitBody(w, r)
+	if err := json.NewDecoder(r.Body).Decode(&req); bodyTooLarge(err) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": bodyTooLargeMessage()})
+		return
+	} else if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json: " + err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.ContentBase64) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "content_base64 is required"})
+		return
+	}
+	data, err := decodeBase64(req.ContentBase64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid base64: " + err.Error()})
+		return
+	}
+
+	name, confidence := extract.DetectEncodingWithOptions(data, baseOptions)
+	writeJSON(w, http.StatusOK, detectEncodingResponse{Encoding: name, Confidence: confidence})
+}
+
+// handleVerify reports whether the filename extension matches the format
+// detected from the content, without extracting text.
+func handleVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req verifyRequest
+	limitBody(w, r)
+	if err := json.NewDecoder(r.Body).Decode(&req); bodyTooLarge(err) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": bodyTooLargeMessage()})
+		return
+	} else if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json: " + err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Filename) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "filename is required"})
+		return
+	}
+	data, err := decodeBase64(req.ContentBase64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid base64: " + err.Error()})
+		return
+	}
+
+	ext, detected, mismatch := extract.VerifyExtension(req.Filename, data)
+	writeJSON(w, http.StatusOK, verifyResponse{Filename: req.Filename, Extension: ext, DetectedFormat: detected, Mismatch: mismatch})
+}
+
 func main() {
 	flagPort := flag.String("port", "8080", "port to listen on")
+	flag.Int64Var(&baseOptions.MaxDecompressedBytes, "max-decompressed-bytes", extract.DefaultMaxDecompressedBytes, "maximum size of compressed data (gzip input, PDF streams, damaged DOCX entries) after decompression")
+	flag.IntVar(&baseOptions.MaxRecursionDepth, "max-recursion-depth", extract.DefaultMaxRecursionDepth, "maximum nesting of embedded documents (gzip in gzip, DOCX imported into DOCX)")
+	flag.StringVar(&baseOptions.CHMTool, "chm-tool", "", "program used to unpack .chm files (7z or extract_chmLib; default: first found in PATH)")
+	flag.StringVar(&baseOptions.TesseractPath, "tesseract", "", "tesseract binary used when a request enables ocr (default: tesseract in PATH)")
+	flag.DurationVar(&extractTimeout, "extract-timeout", extractTimeout, "maximum extraction time per /extract request; 0 disables the limit")
+	flag.DurationVar(&batchItemTimeout, "batch-item-timeout", batchItemTimeout, "maximum extraction time per /extract/batch file; 0 disables the limit")
+	flag.Int64Var(&maxBodyBytes, "max-body-bytes", maxBodyBytes, "maximum request body size; larger requests get 413; 0 disables the limit")
+	flag.IntVar(&batchConcurrency, "batch-concurrency", batchConcurrency, "number of /extract/batch files extracted concurrently")
+	flag.Int64Var(&batchMaxOutputBytes, "batch-max-output-bytes", batchMaxOutputBytes, "total extracted text per /extract/batch response after which remaining files are skipped; 0 disables the limit")
+	flag.DurationVar(&resultTTL, "result-ttl", 0, "keep successful /extract responses this long, retrievable with GET /results/{id}; 0 disables it")
+	flagFormats := flag.String("enabled-formats", "", "comma-separated formats that may be extracted, such as docx,xlsx,txt; empty enables all")
+	flagEncodings := flag.String("encoding-candidates", "", "comma-separated code pages tried for non-UTF text in order of preference, such as koi8-u,windows-1251,cp866; empty uses the defaults")
+	flagRedact := flag.String("redact-pattern", "", "regular expression whose matches are replaced with [REDACTED] in all extracted text")
 	flag.Parse()
+	for _, f := range strings.Split(*flagFormats, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			baseOptions.EnabledFormats = append(baseOptions.EnabledFormats, f)
+		}
+	}
+	for _, e := range strings.Split(*flagEncodings, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			baseOptions.EncodingCandidates = append(baseOptions.EncodingCandidates, e)
+		}
+	}
+	if err := baseOptions.Validate(); err != nil {
+		log.Fatalf("invalid options: %v", err)
+	}
+	if *flagRedact != "" {
+		re, err := regexp.Compile(*flagRedact)
+		if err != nil {
+			log.Fatalf("invalid -redact-pattern: %v", err)
+		}
+		baseOptions.TextHook = redactHook(re)
+	}
+	if resultTTL > 0 {
+		resultStore = store.NewMemoryStore()
+	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", handleHealth)
+	mux.HandleFunc("/ready", handleReady)
+	mux.HandleFunc("/version", handleVersion)
 	mux.HandleFunc("/extract", handleExtract)
 	mux.HandleFunc("/extract/batch", handleExtractBatch)
+	mux.HandleFunc("/detect-encoding", handleDetectEncoding)
+	mux.HandleFunc("/verify", handleVerify)
+	mux.HandleFunc("/results/", handleResult)
 
 	port := strings.TrimSpace(*flagPort)
 	if port == "" {
@@ -157,6 +651,8 @@ func main() {
 	}
 	addr := ":" + port
 
+	go runStartupChecks()
+
 	log.Printf("listening on %s", addr)
 	if err := http.ListenAndServe(addr, mux); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"docparser/internal/extract"
+	"docparser/internal/store"
+)
+
+// postJSON sends body to handler as a POST request and decodes the JSON response into out.
+func postJSON(t *testing.T, handler http.HandlerFunc, path, body string, out any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s: invalid response %q: %v", path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestExtractRedactsTables(t *testing.T) {
+	saved := baseOptions
+	defer func() { baseOptions = saved }()
+	baseOptions.TextHook = redactHook(regexp.MustCompile(`\d{4}`))
+
+	html := `<table><tr><td>card</td><td>4111</td></tr></table>`
+	body := `{"filename":"t.html","content_base64":"` + base64.StdEncoding.EncodeToString([]byte(html)) + `","tables":true}`
+	var resp extractResponse
+	if code := postJSON(t, handleExtract, "/extract", body, &resp); code != http.StatusOK || !resp.Success {
+		t.Fatalf("status %d, response %+v", code, resp)
+	}
+	raw, _ := json.Marshal(resp)
+	if strings.Contains(string(raw), "4111") {
+		t.Errorf("response leaks the number: %s", raw)
+	}
+	if len(resp.Tables) != 1 || resp.Tables[0][0][1] != "[REDACTED]" {
+		t.Errorf("tables = %q", resp.Tables)
+	}
+}
+
+// recordingStore is a ResultStore that remembers its calls and can fail Put.
+type recordingStore struct {
+	entries map[string][]byte
+	ttls    []time.Duration
+	putErr  error
+}
+
+func (s *recordingStore) Put(id string, data []byte, ttl time.Duration) error {
+	if s.putErr != nil {
+		return s.putErr
+	}
+	s.entries[id] = data
+	s.ttls = append(s.ttls, ttl)
+	return nil
+}
+
+func (s *recordingStore) Get(id string) ([]byte, bool, error) {
+	data, ok := s.entries[id]
+	return data, ok, nil
+}
+
+func getResult(t *testing.T, id string, out any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handleResult(rec, httptest.NewRequest(http.MethodGet, "/results/"+id, nil))
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestExtractStoresResult(t *testing.T) {
+	body := `{"filename":"a.txt","content_base64":"` + base64.StdEncoding.EncodeToString([]byte("stored text")) + `"}`
+
+	resultStore = nil
+	var resp extractResponse
+	if postJSON(t, handleExtract, "/extract", body, &resp); resp.ResultID != "" {
+		t.Errorf("result_id %q without a store", resp.ResultID)
+	}
+
+	s := &recordingStore{entries: make(map[string][]byte)}
+	resultStore, resultTTL = s, time.Hour
+	defer func() { resultStore, resultTTL = nil, 0 }()
+	resp = extractResponse{}
+	if code := postJSON(t, handleExtract, "/extract", body, &resp); code != http.StatusOK || resp.ResultID == "" {
+		t.Fatalf("status %d, response %+v; want a result_id", code, resp)
+	}
+	if len(s.ttls) != 1 || s.ttls[0] != time.Hour {
+		t.Errorf("Put ttls = %v, want [1h]", s.ttls)
+	}
+	var stored extractResponse
+	if code := getResult(t, resp.ResultID, &stored); code != http.StatusOK || stored.Text != "stored text" || stored.ResultID != resp.ResultID {
+		t.Errorf("GET /results/%s: status %d, %+v", resp.ResultID, code, stored)
+	}
+	if code := getResult(t, "unknown", nil); code != http.StatusNotFound {
+		t.Errorf("GET /results/unknown: status %d, want 404", code)
+	}
+
+	// a failed extraction is not stored
+	failed := `{"filename":"a.pdf","content_base64":"` + base64.StdEncoding.EncodeToString([]byte("not a pdf")) + `"}`
+	resp = extractResponse{}
+	if postJSON(t, handleExtract, "/extract", failed, &resp); resp.Success || resp.ResultID != "" {
+		t.Errorf("failed extraction: %+v", resp)
+	}
+
+	s.putErr = errors.New("store is down")
+	resp = extractResponse{}
+	if code := postJSON(t, handleExtract, "/extract", body, &resp); code != http.StatusOK || !resp.Success || resp.ResultID != "" {
+		t.Errorf("failing store: status %d, %+v; want the text without a result_id", code, resp)
+	}
+}
+
+func TestExtractResultExpires(t *testing.T) {
+	resultStore, resultTTL = store.NewMemoryStore(), time.Nanosecond
+	defer func() { resultStore, resultTTL = nil, 0 }()
+	body := `{"filename":"a.txt","content_base64":"` + base64.StdEncoding.EncodeToString([]byte("text")) + `"}`
+	var resp extractResponse
+	postJSON(t, handleExtract, "/extract", body, &resp)
+	time.Sleep(time.Millisecond)
+	if code := getResult(t, resp.ResultID, nil); code != http.StatusNotFound {
+		t.Errorf("GET /results/%s after the ttl: status %d, want 404", resp.ResultID, code)
+	}
+}
+
+func TestExtractPattern(t *testing.T) {
+	text := "Invoice INV-2024-001, total 10\nInvoice INV-2024-002, total 20\n"
+	content := base64.StdEncoding.EncodeToString([]byte(text))
+
+	body := `{"filename":"a.txt","content_base64":"` + content + `","extract_pattern":"INV-(\\d{4})-(\\d{3})"}`
+	var resp extractResponse
+	if code := postJSON(t, handleExtract, "/extract", body, &resp); code != http.StatusOK || !resp.Success {
+		t.Fatalf("status %d, response %+v", code, resp)
+	}
+	want := [][]string{{"INV-2024-001", "2024", "001"}, {"INV-2024-002", "2024", "002"}}
+	if resp.Text != "" || !reflect.DeepEqual(resp.Matches, want) {
+		t.Errorf("text %q, matches %q; want no text and %q", resp.Text, resp.Matches, want)
+	}
+
+	body = `{"filename":"a.txt","content_base64":"` + content + `","extract_pattern":"("}`
+	resp = extractResponse{}
+	if code := postJSON(t, handleExtract, "/extract", body, &resp); code != http.StatusBadRequest || !strings.HasPrefix(resp.Text, "invalid extract_pattern") {
+		t.Errorf("invalid pattern: status %d, response %+v", code, resp)
+	}
+
+	batch := `{"files":[{"filename":"a.txt","content_base64":"` + content + `"}],"extract_pattern":"total (\\d+)"}`
+	var batchResp batchResponse
+	if code := postJSON(t, handleExtractBatch, "/extract/batch", batch, &batchResp); code != http.StatusOK || len(batchResp.Results) != 1 {
+		t.Fatalf("batch: status %d, response %+v", code, batchResp)
+	}
+	want = [][]string{{"total 10", "10"}, {"total 20", "20"}}
+	if got := batchResp.Results[0]; got.Text != "" || !reflect.DeepEqual(got.Matches, want) {
+		t.Errorf("batch: text %q, matches %q; want %q", got.Text, got.Matches, want)
+	}
+}
+
+func TestDetectEncodingEndpoint(t *testing.T) {
+	cp1251, _ := charmap.Windows1251.NewEncoder().String("Привет, мир! Это проверка кодировки.")
+	body := `{"content_base64":"` + base64.StdEncoding.EncodeToString([]byte(cp1251)) + `"}`
+	var resp detectEncodingResponse
+	if code := postJSON(t, handleDetectEncoding, "/detect-encoding", body, &resp); code != http.StatusOK || resp.Encoding != "windows-1251" || resp.Confidence <= 0 {
+		t.Errorf("status %d, response %+v; want windows-1251", code, resp)
+	}
+	for _, body := range []string{`{}`, `{"content_base64":"!!"}`, `not json`} {
+		if code := postJSON(t, handleDetectEncoding, "/detect-encoding", body, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", body, code)
+		}
+	}
+}
+
+func TestExtractFormatOverride(t *testing.T) {
+	content := base64.StdEncoding.EncodeToString([]byte(`{\rtf1\ansi Hello\par World}`))
+	body := `{"filename":"notes.txt","format":"rtf","content_base64":"` + content + `"}`
+	var resp extractResponse
+	if code := postJSON(t, handleExtract, "/extract", body, &resp); code != http.StatusOK || !resp.Success || resp.Text != "Hello\nWorld" || resp.Format != "rtf" {
+		t.Errorf("status %d, response %+v", code, resp)
+	}
+	body = `{"filename":"notes.txt","format":"bogus","content_base64":"` + content + `"}`
+	resp = extractResponse{}
+	if postJSON(t, handleExtract, "/extract", body, &resp); resp.Success {
+		t.Errorf("unknown format: response %+v", resp)
+	}
+}
+
+func TestExtractDebugTimings(t *testing.T) {
+	content := base64.StdEncoding.EncodeToString([]byte("hello"))
+	body := `{"filename":"a.txt","content_base64":"` + content + `"}`
+	var resp extractResponse
+	if postJSON(t, handleExtract, "/extract", body, &resp); resp.TimingsMs != nil {
+		t.Errorf("timings without debug: %v", resp.TimingsMs)
+	}
+	resp = extractResponse{}
+	if code := postJSON(t, handleExtract, "/extract?debug=true", body, &resp); code != http.StatusOK || !resp.Success {
+		t.Fatalf("status %d, response %+v", code, resp)
+	}
+	for _, phase := range []string{extract.PhaseDetect, extract.PhaseParse, extract.PhaseNormalize, extract.PhaseTotal} {
+		if _, ok := resp.TimingsMs[phase]; !ok {
+			t.Errorf("no %s phase in %v", phase, resp.TimingsMs)
+		}
+	}
+
+	batch := `{"files":[{"filename":"a.txt","content_base64":"` + content + `"}]}`
+	var batchResp batchResponse
+	if code := postJSON(t, handleExtractBatch, "/extract/batch?debug=1", batch, &batchResp); code != http.StatusOK || len(batchResp.Results) != 1 {
+		t.Fatalf("batch: status %d, response %+v", code, batchResp)
+	}
+	if _, ok := batchResp.Results[0].TimingsMs[extract.PhaseTotal]; !ok {
+		t.Errorf("batch: no total in %v", batchResp.Results[0].TimingsMs)
+	}
+}
+
+func TestExtractWordFrequencies(t *testing.T) {
+	content := base64.StdEncoding.EncodeToString([]byte("Кот и пёс, кот и мышь"))
+	for _, tc := range []struct {
+		extra string
+		want  []extract.WordCount
+	}{
+		{``, nil},
+		{`,"word_frequencies":2`, []extract.WordCount{{Word: "кот", Count: 2}, {Word: "мышь", Count: 1}}},
+		{`,"word_frequencies":1,"stopwords":["кот"]`, []extract.WordCount{{Word: "и", Count: 2}}},
+	} {
+		body := `{"filename":"a.txt","content_base64":"` + content + `"` + tc.extra + `}`
+		var resp extractResponse
+		if code := postJSON(t, handleExtract, "/extract", body, &resp); code != http.StatusOK || !reflect.DeepEqual(resp.WordFrequencies, tc.want) {
+			t.Errorf("%s: status %d, frequencies %v; want %v", tc.extra, code, resp.WordFrequencies, tc.want)
+		}
+	}
+}
+
+func TestExtractBatchOutputLimit(t *testing.T) {
+	savedLimit, savedConcurrency := batchMaxOutputBytes, batchConcurrency
+	defer func() { batchMaxOutputBytes, batchConcurrency = savedLimit, savedConcurrency }()
+	batchMaxOutputBytes = 10
+
+	file := func(name, text string) string {
+		return `{"filename":"` + name + `","content_base64":"` + base64.StdEncoding.EncodeToString([]byte(text)) + `"}`
+	}
+	// the failed item adds nothing; the second text reaches the limit, so the
+	// items after it are skipped
+	body := `{"files":[` + file("a.txt", "aaaa") + `,` + file("bad.docx", "not a zip") + `,` + file("b.txt", "bbbbbb") + `,` +
+		file("c.txt", "cc") + `,` + file("d.txt", "") + `]}`
+	for _, concurrency := range []int{1, 4} {
+		batchConcurrency = concurrency
+		var resp batchResponse
+		if code := postJSON(t, handleExtractBatch, "/extract/batch", body, &resp); code != http.StatusOK || len(resp.Results) != 5 {
+			t.Fatalf("concurrency %d: status %d, response %+v", concurrency, code, resp)
+		}
+		for i, want := range []struct {
+			success bool
+			status  string
+		}{{true, ""}, {false, ""}, {true, ""}, {false, statusSkippedSizeLimit}, {false, statusSkippedSizeLimit}} {
+			if got := resp.Results[i]; got.Success != want.success || got.Status != want.status {
+				t.Errorf("concurrency %d, item %d: got %+v, want success %v, status %q", concurrency, i, got, want.success, want.status)
+			}
+		}
+		if got := resp.Results[3]; got.Filename != "c.txt" || !strings.Contains(got.Text, "10 bytes") {
+			t.Errorf("concurrency %d: skipped item %+v", concurrency, got)
+		}
+	}
+
+	batchMaxOutputBytes = 0
+	var resp batchResponse
+	postJSON(t, handleExtractBatch, "/extract/batch", body, &resp)
+	for i, item := range resp.Results {
+		if item.Status == statusSkippedSizeLimit {
+			t.Errorf("without a limit: item %d skipped", i)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	pdf := base64.StdEncoding.EncodeToString([]byte("%PDF-1.7\n"))
+	for _, tc := range []struct {
+		body string
+		code int
+		want verifyResponse
+	}{
+		{`{"filename":"a.pdf","content_base64":"` + pdf + `"}`, http.StatusOK, verifyResponse{Filename: "a.pdf", Extension: "pdf", DetectedFormat: "pdf"}},
+		{`{"filename":"a.docx","content_base64":"` + pdf + `"}`, http.StatusOK, verifyResponse{Filename: "a.docx", Extension: "docx", DetectedFormat: "pdf", Mismatch: true}},
+		{`{"content_base64":"` + pdf + `"}`, http.StatusBadRequest, verifyResponse{}},
+		{`{"filename":"a.pdf","content_base64":"%%%"}`, http.StatusBadRequest, verifyResponse{}},
+	} {
+		var resp verifyResponse
+		if code := postJSON(t, handleVerify, "/verify", tc.body, &resp); code != tc.code || resp != tc.want {
+			t.Errorf("%s: status %d, response %+v; want %d, %+v", tc.body, code, resp, tc.code, tc.want)
+		}
+	}
+}
+
+func TestExtractBatchFormatAndDuration(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake tool is a shell script")
+	}
+	// a CHM unpacker that takes a while, so the duration is measurable
+	tool := filepath.Join(t.TempDir(), "extract_chmLib")
+	script := "#!/bin/sh\nsleep 0.05\nmkdir -p \"$2\"\nprintf '<p>Topic</p>' > \"$2/a.htm\"\n"
+	if err := os.WriteFile(tool, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	saved := baseOptions
+	defer func() { baseOptions = saved }()
+	baseOptions.CHMTool = tool
+
+	file := func(name string, data []byte) string {
+		return `{"filename":"` + name + `","content_base64":"` + base64.StdEncoding.EncodeToString(data) + `"}`
+	}
+	body := `{"files":[` + file("a.txt", []byte("plain")) + `,` + file("b.rtf", []byte(`{\rtf1 Hi}`)) + `,` +
+		file("c.chm", []byte("ITSF")) + `,` + file("d.docx", []byte("not a zip")) + `]}`
+	rec := httptest.NewRecorder()
+	handleExtractBatch(rec, httptest.NewRequest(http.MethodPost, "/extract/batch", strings.NewReader(body)))
+	var raw struct {
+		Results []map[string]json.RawMessage `json:"results"`
+	}
+	var resp batchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil || json.Unmarshal(rec.Body.Bytes(), &resp) != nil || len(resp.Results) != 4 {
+		t.Fatalf("status %d, response %s", rec.Code, rec.Body)
+	}
+	for i, want := range []struct {
+		format  string
+		success bool
+	}{{"txt", true}, {"rtf", true}, {"chm", true}, {"docx", false}} {
+		got := resp.Results[i]
+		if got.Format != want.format || got.Success != want.success {
+			t.Errorf("item %d: got %+v, want format %q, success %v", i, got, want.format, want.success)
+		}
+		if _, ok := raw.Results[i]["duration_ms"]; !ok || got.DurationMs < 0 {
+			t.Errorf("item %d: duration_ms %d, present %v", i, got.DurationMs, ok)
+		}
+	}
+	if got := resp.Results[2].DurationMs; got < 50 {
+		t.Errorf("slow item: duration_ms %d, want at least 50", got)
+	}
+}
+
+func TestExtractChunks(t *testing.T) {
+	content := base64.StdEncoding.EncodeToString([]byte("First paragraph here.\n\nSecond paragraph here.\n"))
+	body := `{"filename":"a.txt","content_base64":"` + content + `","chunk_size":30}`
+	var resp extractResponse
+	want := []string{"First paragraph here.", "Second paragraph here."}
+	if code := postJSON(t, handleExtract, "/extract", body, &resp); code != http.StatusOK || resp.Text != "" || !reflect.DeepEqual(resp.Chunks, want) {
+		t.Errorf("status %d, text %q, chunks %q; want %q", code, resp.Text, resp.Chunks, want)
+	}
+	body = `{"filename":"a.txt","content_base64":"` + content + `","chunk_size":10,"chunk_overlap":10}`
+	resp = extractResponse{}
+	if code := postJSON(t, handleExtract, "/extract", body, &resp); code != http.StatusBadRequest || resp.Success {
+		t.Errorf("overlap as large as the size: status %d, response %+v", code, resp)
+	}
+}
+
+func TestExtractMultipart(t *testing.T) {
+	saved := maxBodyBytes
+	defer func() { maxBodyBytes = saved }()
+	maxBodyBytes = 1 << 20
+
+	form := func(file []byte, fields ...string) (string, []byte) {
+		var b bytes.Buffer
+		mw := multipart.NewWriter(&b)
+		if file != nil {
+			fw, err := mw.CreateFormFile("file", "notes.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			fw.Write(file)
+		}
+		for i := 0; i+1 < len(fields); i += 2 {
+			mw.WriteField(fields[i], fields[i+1])
+		}
+		mw.Close()
+		return mw.FormDataContentType(), b.Bytes()
+	}
+	large := bytes.Repeat([]byte("x"), 2<<20)
+	for _, tc := range []struct {
+		name string
+		file []byte
+		cut  int // bytes dropped from the end of the body
+		code int
+		text string
+	}{
+		{"upload", []byte("hello"), 0, http.StatusOK, "hello"},
+		{"over the body limit", large, 0, http.StatusRequestEntityTooLarge, ""},
+		{"no file", nil, 0, http.StatusBadRequest, "invalid form: file is required"},
+		{"truncated", []byte("hello"), 10, http.StatusBadRequest, "invalid form: "},
+	} {
+		contentType, body := form(tc.file, "options", `{"best_effort":true}`)
+		req := httptest.NewRequest(http.MethodPost, "/extract", bytes.NewReader(body[:len(body)-tc.cut]))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		handleExtract(rec, req)
+		var resp extractResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: invalid response %q: %v", tc.name, rec.Body.String(), err)
+		}
+		if rec.Code != tc.code || !strings.HasPrefix(resp.Text, tc.text) {
+			t.Errorf("%s: status %d, text %q; want %d, %q", tc.name, rec.Code, resp.Text, tc.code, tc.text)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"docparser/internal/extract"
+)
+
+// baseOptions holds the server-wide settings every request starts from; fields
+// tagged json:"-" (such as tool paths) can only be set here.
+var baseOptions = extract.DefaultOptions()
+
+// parseOptions builds extraction options for a request. It starts from baseOptions,
+// applies the JSON "options" object from the body (raw may be empty) and
+// then query parameters named after the same JSON keys, so ?best_effort=true works
+// for every endpoint. Unknown keys in the JSON object and malformed values are errors.
+func parseOptions(r *http.Request, raw json.RawMessage) (extract.Options, error) {
+	opts := baseOptions
+	// decoding into a shared backing array would change baseOptions itself
+	opts.StripPatterns = slices.Clone(opts.StripPatterns)
+	opts.EncodingCandidates = slices.Clone(opts.EncodingCandidates)
+	opts.EnabledFormats = slices.Clone(opts.EnabledFormats)
+	if len(bytes.TrimSpace(raw)) > 0 && !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&opts); err != nil {
+			return opts, err
+		}
+	}
+
+	query := r.URL.Query()
+	v := reflect.ValueOf(&opts).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if key == "" || key == "-" || !query.Has(key) {
+			continue
+		}
+		if err := setOption(v.Field(i), query[key]); err != nil {
+			return opts, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return opts, opts.Validate()
+}
+
+// setOption assigns query parameter values to an option field.
+func setOption(f reflect.Value, values []string) error {
+	s := values[len(values)-1]
+	switch f.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Slice:
+		if f.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported option type %s", f.Type())
+		}
+		// repeated parameters and comma-separated lists are both accepted
+		var items []string
+		for _, v := range values {
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+		f.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported option type %s", f.Type())
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	"docparser/internal/extract"
+)
+
+func TestParseOptionsKeepsBaseOptions(t *testing.T) {
+	saved := baseOptions
+	defer func() { baseOptions = saved }()
+	baseOptions.EncodingCandidates = []string{"koi8-u", "windows-1251", "cp866"}
+	baseOptions.StripPatterns = []string{"a", "b"}
+
+	r := httptest.NewRequest("POST", "/extract", nil)
+	opts, err := parseOptions(r, json.RawMessage(`{"encoding_candidates":["cp866"],"strip_patterns":["x"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(opts.EncodingCandidates, []string{"cp866"}) || !slices.Equal(opts.StripPatterns, []string{"x"}) {
+		t.Errorf("request options = %v, %v", opts.EncodingCandidates, opts.StripPatterns)
+	}
+	if !slices.Equal(baseOptions.EncodingCandidates, []string{"koi8-u", "windows-1251", "cp866"}) || !slices.Equal(baseOptions.StripPatterns, []string{"a", "b"}) {
+		t.Errorf("base options changed to %v, %v", baseOptions.EncodingCandidates, baseOptions.StripPatterns)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	for _, tc := range []struct {
+		name, query, body string
+		check             func(extract.Options) bool
+	}{
+		{"empty", "", "", func(o extract.Options) bool { return reflect.DeepEqual(o, baseOptions) }},
+		{"null body", "", "null", func(o extract.Options) bool { return reflect.DeepEqual(o, baseOptions) }},
+		{"body", "", `{"best_effort":true,"expand_tabs":4}`, func(o extract.Options) bool { return o.BestEffort && o.ExpandTabs == 4 }},
+		{"query", "?best_effort=true&max_consecutive_blank_lines=2", "", func(o extract.Options) bool {
+			return o.BestEffort && o.MaxConsecutiveBlankLines == 2
+		}},
+		{"query wins", "?expand_tabs=8", `{"expand_tabs":4,"list_markers":true}`, func(o extract.Options) bool {
+			return o.ExpandTabs == 8 && o.ListMarkers
+		}},
+		{"query list", "?strip_patterns=a,b&strip_patterns=c", "", func(o extract.Options) bool {
+			return slices.Equal(o.StripPatterns, []string{"a", "b", "c"})
+		}},
+		{"operator-only field ignored in the query", "?TesseractPath=/bin/sh", "", func(o extract.Options) bool { return o.TesseractPath == "" }},
+	} {
+		r := httptest.NewRequest("POST", "/extract"+tc.query, nil)
+		opts, err := parseOptions(r, json.RawMessage(tc.body))
+		if err != nil || !tc.check(opts) {
+			t.Errorf("%s: got %+v, %v", tc.name, opts, err)
+		}
+	}
+
+	for _, tc := range []struct{ name, query, body string }{
+		{"unknown field", "", `{"no_such_option":true}`},
+		{"operator-only field", "", `{"TesseractPath":"/bin/sh"}`},
+		{"wrong type", "", `{"best_effort":"yes"}`},
+		{"bad query value", "?best_effort=maybe", ""},
+		{"invalid value", "?expand_tabs=-1", ""},
+		{"invalid combination", "", `{"pdf_image_ocr":true}`},
+	} {
+		r := httptest.NewRequest("POST", "/extract"+tc.query, nil)
+		if _, err := parseOptions(r, json.RawMessage(tc.body)); err == nil {
+			t.Errorf("%s: no error", tc.name)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// readyTools are the external programs whose availability /ready reports. None
+// of them fails the probe: as in /health, a missing pdftotext is shown to the
+// operator, but PDFs are still read by the built-in parser.
+var readyTools = []string{"pdftotext"}
+
+// startupDone is set once runStartupChecks has finished.
+var startupDone atomic.Bool
+
+type readyResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// runStartupChecks probes the external tools once so /ready, and /version, can
+// answer from the cached results.
+func runStartupChecks() {
+	probeToolVersions()
+	startupDone.Store(true)
+}
+
+// handleReady is the readiness probe: unlike /health, which only reports that
+// the process is up, it returns 503 until the startup checks have completed.
+// The tools are probed once per process, so the checks describe them as found
+// at startup.
+func handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !startupDone.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, readyResponse{Status: "starting"})
+		return
+	}
+	resp := readyResponse{Status: "ready", Checks: make(map[string]string, len(readyTools))}
+	tools := probeToolVersions()
+	for _, name := range readyTools {
+		if v := tools[name]; v.Error != "" {
+			resp.Checks[name] = v.Error
+		} else {
+			resp.Checks[name] = "ok"
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
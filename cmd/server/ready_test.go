@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadyWithoutPdftotext(t *testing.T) {
+	get := func() (int, readyResponse) {
+		rec := httptest.NewRecorder()
+		handleReady(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		var resp readyResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+		}
+		return rec.Code, resp
+	}
+
+	startupDone.Store(false)
+	if code, resp := get(); code != http.StatusServiceUnavailable || resp.Status != "starting" {
+		t.Errorf("before the startup checks: %d %+v, want 503 starting", code, resp)
+	}
+
+	// the probe runs once per process; this test takes it over
+	toolVersionsOnce.Do(func() {
+		toolVersions = map[string]toolVersion{"pdftotext": {Error: "not found in PATH"}}
+	})
+	if _, ok := toolVersions["pdfimages"]; ok {
+		t.Skip("tool versions were probed by an earlier test")
+	}
+	startupDone.Store(true)
+	defer startupDone.Store(false)
+	code, resp := get()
+	if code != http.StatusOK || resp.Status != "ready" || resp.Checks["pdftotext"] != "not found in PATH" {
+		t.Errorf("without pdftotext: %d %+v, want 200 ready with the check reported", code, resp)
+	}
+}
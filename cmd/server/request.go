@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxPresizeBytes bounds the buffer allocated up front from the request's
+// Content-Length, which the client controls; beyond it the buffer grows as
+// data arrives.
+const maxPresizeBytes = 64 << 20
+
+// base64Error reports content_base64 that is not valid base64, as opposed to a
+// malformed JSON body.
+type base64Error struct{ err error }
+
+func (e base64Error) Error() string { return e.err.Error() }
+
+// decodeExtractRequest reads an /extract body. The content_base64 string is
+// decoded as it streams in, into req.data, so a large upload is held in memory
+// once as raw bytes rather than also as a JSON string and its base64 text.
+// The other fields are decoded as usual. size, the body length if known,
+// presizes the buffer for the decoded bytes.
+func decodeExtractRequest(r io.Reader, size int64) (req extractRequest, err error) {
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+	if tok, err := dec.Token(); err != nil {
+		return req, err
+	} else if tok != json.Delim('{') {
+		return req, errors.New("request body must be a JSON object")
+	}
+	fields := make(map[string]json.RawMessage)
+	seen := false
+	for {
+		if !dec.More() {
+			if _, err := dec.Token(); err != nil {
+				return req, err
+			}
+			break
+		}
+		tok, err := dec.Token()
+		if err != nil {
+			return req, err
+		}
+		key, _ := tok.(string)
+		if key != "content_base64" {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return req, err
+			}
+			fields[key] = raw
+			continue
+		}
+		if seen {
+			return req, errors.New("duplicate content_base64")
+		}
+		seen = true
+		// the decoder has read ahead; continue by hand from where it stopped
+		rest := bufio.NewReader(io.MultiReader(dec.Buffered(), br))
+		if req.data, err = readBase64Value(rest, size); err != nil {
+			return req, err
+		}
+		c, err := nextNonSpace(rest)
+		if err != nil {
+			return req, err
+		}
+		if c == '}' {
+			break
+		}
+		if c != ',' {
+			return req, fmt.Errorf("invalid character %q after content_base64", c)
+		}
+		// the remaining members form an object of their own
+		br = rest
+		dec = json.NewDecoder(io.MultiReader(strings.NewReader("{"), br))
+		if _, err := dec.Token(); err != nil {
+			return req, err
+		}
+	}
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return req, err
+	}
+	return req, json.Unmarshal(raw, &req)
+}
+
+// readBase64Value reads ": <string>" from r and returns the string decoded as
+// base64. A null value yields no data.
+func readBase64Value(r *bufio.Reader, size int64) ([]byte, error) {
+	c, err := nextNonSpace(r)
+	if err != nil {
+		return nil, err
+	}
+	if c != ':' {
+		return nil, fmt.Errorf("invalid character %q after object key", c)
+	}
+	if c, err = nextNonSpace(r); err != nil {
+		return nil, err
+	}
+	if c == 'n' {
+		var null [3]byte
+		if _, err := io.ReadFull(r, null[:]); err != nil || string(null[:]) != "ull" {
+			return nil, errors.New("content_base64 must be a string")
+		}
+		return nil, nil
+	}
+	if c != '"' {
+		return nil, errors.New("content_base64 must be a string")
+	}
+	s := &jsonStringReader{r: r}
+	var data bytes.Buffer
+	if size > 0 {
+		data.Grow(base64.StdEncoding.DecodedLen(int(min(size, maxPresizeBytes))))
+	}
+	if _, err := io.Copy(&data, newBase64Decoder(s)); err != nil {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, base64Error{err}
+	}
+	if !s.done {
+		// trailing text after padding
+		return nil, base64Error{errors.New("illegal data after padding")}
+	}
+	return data.Bytes(), nil
+}
+
+// newBase64Decoder decodes base64 from r in any of the variants clients send:
+// the standard or the URL-safe alphabet, with or without padding. Spaces,
+// tabs and line breaks are ignored.
+func newBase64Decoder(r io.Reader) io.Reader {
+	return base64.NewDecoder(base64.StdEncoding, &base64Normalizer{r: r})
+}
+
+// decodeBase64 decodes s as newBase64Decoder does.
+func decodeBase64(s string) ([]byte, error) {
+	return io.ReadAll(newBase64Decoder(strings.NewReader(s)))
+}
+
+// base64Normalizer rewrites base64 text into the padded standard form: the
+// URL-safe '-' and '_' become '+' and '/', spaces and tabs are dropped and
+// missing padding is added at the end. Line breaks are left to the decoder,
+// which skips them.
+type base64Normalizer struct {
+	r   io.Reader
+	n   int    // base64 characters read, padding included
+	pad string // padding still to be returned after the input ends
+	eof bool
+}
+
+func (b *base64Normalizer) Read(p []byte) (int, error) {
+	for !b.eof {
+		n, err := b.r.Read(p)
+		out := p[:0]
+		for _, c := range p[:n] {
+			switch c {
+			case ' ', '\t':
+				continue
+			case '-':
+				c = '+'
+			case '_':
+				c = '/'
+			}
+			if c != '\r' && c != '\n' {
+				b.n++
+			}
+			out = append(out, c)
+		}
+		if err == io.EOF {
+			b.eof = true
+			switch b.n % 4 {
+			case 2:
+				b.pad = "=="
+			case 3:
+				b.pad = "="
+			}
+		} else if err != nil {
+			return len(out), err
+		}
+		if len(out) > 0 {
+			return len(out), nil
+		}
+	}
+	if b.pad == "" {
+		return 0, io.EOF
+	}
+	n := copy(p, b.pad)
+	b.pad = b.pad[n:]
+	return n, nil
+}
+
+// jsonStringReader reads the contents of a JSON string up to its closing
+// quote. Only the escapes that can occur in base64 text are accepted:
+// \/ and the line breaks \r and \n, which the base64 decoder skips.
+type jsonStringReader struct {
+	r    *bufio.Reader
+	done bool  // the closing quote has been read
+	err  error // a JSON syntax error, as opposed to a base64 one
+}
+
+func (s *jsonStringReader) Read(p []byte) (int, error) {
+	if s.done {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if s.r.Buffered() == 0 {
+		if _, err := s.r.Peek(1); err != nil {
+			return 0, s.fail(err)
+		}
+	}
+	// copy the plain characters at hand, stopping at a quote or escape
+	chunk, _ := s.r.Peek(min(len(p), s.r.Buffered()))
+	n := 0
+	for n < len(chunk) && chunk[n] != '"' && chunk[n] != '\\' && chunk[n] >= 0x20 {
+		n++
+	}
+	if n > 0 {
+		copy(p, chunk[:n])
+		_, _ = s.r.Discard(n)
+		return n, nil
+	}
+	c, _ := s.r.ReadByte()
+	switch {
+	case c == '"':
+		s.done = true
+		return 0, io.EOF
+	case c < 0x20:
+		return 0, s.fail(errors.New("invalid control character in string"))
+	}
+	e, err := s.r.ReadByte()
+	if err != nil {
+		return 0, s.fail(err)
+	}
+	switch e {
+	case '/':
+		p[0] = '/'
+	case 'n':
+		p[0] = '\n'
+	case 'r':
+		p[0] = '\r'
+	default:
+		return 0, s.fail(fmt.Errorf("unsupported escape \\%c in content_base64", e))
+	}
+	return 1, nil
+}
+
+// fail records a JSON syntax error; the end of input inside the string is
+// unexpected.
+func (s *jsonStringReader) fail(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	s.err = err
+	return err
+}
+
+// nextNonSpace returns the next byte of r that is not JSON whitespace.
+func nextNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		c, err := r.ReadByte()
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			return c, nil
+		}
+	}
+}
+
+// multipartMemory is how much of a multipart/form-data body is held in
+// memory; larger uploads are spooled to temporary files while parsing.
+const multipartMemory = 32 << 20
+
+// isMultipart reports whether r carries a multipart/form-data body.
+func isMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// formError reports a malformed multipart/form-data body, as opposed to a
+// read error.
+type formError struct{ err error }
+
+func (e formError) Error() string { return e.err.Error() }
+
+// decodeMultipartRequest reads an /extract upload: the file in the "file"
+// part, and the other request fields as form values of the same names.
+// filename defaults to the name of the uploaded file, options holds the
+// options object as JSON and stopwords may be repeated.
+func decodeMultipartRequest(r *http.Request) (req extractRequest, err error) {
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		if bodyTooLarge(err) {
+			return req, err
+		}
+		return req, formError{err}
+	}
+	file, header, err := r.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) {
+		return req, formError{errors.New("file is required")}
+	}
+	if err != nil {
+		return req, formError{err}
+	}
+	defer file.Close()
+	if req.data, err = io.ReadAll(file); err != nil {
+		return req, err
+	}
+	if len(req.data) == 0 {
+		return req, formError{errors.New("file is empty")}
+	}
+	form := r.MultipartForm.Value
+	value := func(key string) string {
+		if v := form[key]; len(v) > 0 {
+			return strings.TrimSpace(v[0])
+		}
+		return ""
+	}
+	number := func(key string) (int, error) {
+		if value(key) == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value(key))
+		if err != nil {
+			return 0, formError{fmt.Errorf("%s: %w", key, err)}
+		}
+		return n, nil
+	}
+	req.Filename = value("filename")
+	if req.Filename == "" {
+		req.Filename = header.Filename
+	}
+	req.Format = value("format")
+	req.Encoding = value("encoding")
+	req.ExtractPattern = value("extract_pattern")
+	req.Stopwords = form["stopwords"]
+	if req.WordFrequencies, err = number("word_frequencies"); err != nil {
+		return req, err
+	}
+	if req.ChunkSize, err = number("chunk_size"); err != nil {
+		return req, err
+	}
+	if req.ChunkOverlap, err = number("chunk_overlap"); err != nil {
+		return req, err
+	}
+	if v := value("tables"); v != "" {
+		if req.Tables, err = strconv.ParseBool(v); err != nil {
+			return req, formError{fmt.Errorf("tables: %w", err)}
+		}
+	}
+	if v := value("options"); v != "" {
+		req.Options = json.RawMessage(v)
+	}
+	return req, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+type toolVersion struct {
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type versionResponse struct {
+	Module      string                 `json:"module"`
+	Version     string                 `json:"version"`
+	GoVersion   string                 `json:"go_version"`
+	VCSRevision string                 `json:"vcs_revision,omitempty"`
+	VCSTime     string                 `json:"vcs_time,omitempty"`
+	VCSModified bool                   `json:"vcs_modified,omitempty"`
+	Tools       map[string]toolVersion `json:"tools"`
+}
+
+// versionTools are the external programs whose versions /version reports,
+// with the arguments that make them print it.
+var versionTools = map[string][]string{
+	"pdftotext": {"-v"},
+	"pdfimages": {"-v"},
+	"pdftoppm":  {"-v"},
+	"tesseract": {"--version"},
+}
+
+var (
+	toolVersionsOnce sync.Once
+	toolVersions     map[string]toolVersion
+)
+
+// probeToolVersions runs each tool once per process; the installed tools do not
+// change while the server is running.
+func probeToolVersions() map[string]toolVersion {
+	toolVersionsOnce.Do(func() {
+		toolVersions = make(map[string]toolVersion, len(versionTools))
+		for name, args := range versionTools {
+			toolVersions[name] = probeToolVersion(name, args...)
+		}
+	})
+	return toolVersions
+}
+
+func probeToolVersion(name string, args ...string) toolVersion {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return toolVersion{Error: "not found in PATH"}
+	}
+	// pdftotext -v prints to stderr and exits non-zero on some Poppler versions,
+	// so the output is used whenever there is any
+	out, err := exec.Command(path, args...).CombinedOutput()
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	if line == "" {
+		if err == nil {
+			return toolVersion{Error: "no version output"}
+		}
+		return toolVersion{Error: err.Error()}
+	}
+	return toolVersion{Version: strings.TrimSpace(line)}
+}
+
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	resp := versionResponse{
+		Version:   "(unknown)",
+		GoVersion: runtime.Version(),
+		Tools:     probeToolVersions(),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		resp.Module = info.Main.Path
+		resp.Version = info.Main.Version
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				resp.VCSRevision = s.Value
+			case "vcs.time":
+				resp.VCSTime = s.Value
+			case "vcs.modified":
+				resp.VCSModified = s.Value == "true"
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
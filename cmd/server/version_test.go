@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestProbeToolVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake tool is a shell script")
+	}
+	dir := t.TempDir()
+	write := func(name, script string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	for _, tc := range []struct {
+		name, path string
+		want       toolVersion
+	}{
+		// pdftotext -v prints to stderr and may exit with an error
+		{"stderr and failure", write("stderr", "echo 'pdftotext version 24.02.0' >&2\necho 'Copyright' >&2\nexit 99\n"), toolVersion{Version: "pdftotext version 24.02.0"}},
+		{"no output", write("silent", "exit 0\n"), toolVersion{Error: "no version output"}},
+		{"missing", filepath.Join(dir, "missing"), toolVersion{Error: "not found in PATH"}},
+	} {
+		if got := probeToolVersion(tc.path, "-v"); got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleVersion(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	var resp versionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+	}
+	if rec.Code != http.StatusOK || resp.GoVersion != runtime.Version() || resp.Version == "" {
+		t.Errorf("status %d, response %+v", rec.Code, resp)
+	}
+	if !reflect.DeepEqual(resp.Tools, probeToolVersions()) {
+		t.Errorf("tools = %+v, want the probed versions %+v", resp.Tools, probeToolVersions())
+	}
+
+	rec = httptest.NewRecorder()
+	handleVersion(rec, httptest.NewRequest(http.MethodPost, "/version", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status %d, want 405", rec.Code)
+	}
+}
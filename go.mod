@@ -4,4 +4,7 @@ go 1.23.0
 
 toolchain go1.24.4
 
-require golang.org/x/text v0.28.0
+require (
+	golang.org/x/net v0.43.0
+	golang.org/x/text v0.28.0
+)
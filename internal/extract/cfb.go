@@ -0,0 +1,228 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode/utf16"
+)
+
+// cfbMagic starts Compound File Binary (OLE2) containers: legacy Office
+// files and encrypted OOXML packages.
+var cfbMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+const (
+	cfbFreeSect   = 0xFFFFFFFF
+	cfbEndOfChain = 0xFFFFFFFE
+)
+
+var errCFBCorrupt = errors.New("cfb: corrupt compound file")
+
+type cfbEntry struct {
+	name  string
+	typ   byte // 1 storage, 2 stream, 5 root
+	start uint32
+	size  uint64
+}
+
+// cfbFile reads streams from a Compound File Binary container.
+type cfbFile struct {
+	data       []byte
+	sectorSize int
+	miniSize   int
+	miniCutoff uint64
+	fat        []uint32
+	miniFAT    []uint32
+	miniStream []byte
+	entries    []cfbEntry
+}
+
+func openCFB(data []byte) (*cfbFile, error) {
+	if len(data) < 512 || !bytes.HasPrefix(data, cfbMagic) {
+		return nil, errors.New("cfb: not a compound file")
+	}
+	le := binary.LittleEndian
+	c := &cfbFile{
+		data:       data,
+		sectorSize: 1 << le.Uint16(data[0x1E:]),
+		miniSize:   1 << le.Uint16(data[0x20:]),
+		miniCutoff: uint64(le.Uint32(data[0x38:])),
+	}
+	if c.sectorSize != 512 && c.sectorSize != 4096 || c.miniSize != 64 {
+		return nil, errCFBCorrupt
+	}
+
+	// the DIFAT lists the FAT sectors: 109 entries in the header, the rest in
+	// a chain of DIFAT sectors whose last entry links to the next one
+	var difat []uint32
+	for i := 0; i < 109; i++ {
+		difat = append(difat, le.Uint32(data[0x4C+4*i:]))
+	}
+	// a chain longer than the file has sectors, or one that comes back to a
+	// sector it has read, is corrupt rather than merely long
+	next, count := le.Uint32(data[0x44:]), le.Uint32(data[0x48:])
+	seen := make(map[uint32]bool)
+	for i := uint32(0); i < count && next != cfbEndOfChain && next != cfbFreeSect; i++ {
+		sec := c.sector(next)
+		if sec == nil || seen[next] || len(seen) >= c.sectors() {
+			return nil, errCFBCorrupt
+		}
+		seen[next] = true
+		n := len(sec)/4 - 1
+		for j := 0; j < n; j++ {
+			difat = append(difat, le.Uint32(sec[4*j:]))
+		}
+		next = le.Uint32(sec[4*n:])
+	}
+	for _, s := range difat {
+		if s == cfbFreeSect || s == cfbEndOfChain {
+			continue
+		}
+		sec := c.sector(s)
+		if sec == nil {
+			return nil, errCFBCorrupt
+		}
+		for j := 0; j+4 <= len(sec); j += 4 {
+			c.fat = append(c.fat, le.Uint32(sec[j:]))
+		}
+	}
+
+	if len(c.fat) == 0 {
+		return nil, errCFBCorrupt
+	}
+
+	dir, err := c.chain(le.Uint32(data[0x30:]), 0)
+	if err != nil {
+		return nil, err
+	}
+	for off := 0; off+128 <= len(dir); off += 128 {
+		e := dir[off : off+128]
+		nameLen := int(le.Uint16(e[64:]))
+		if nameLen > 64 {
+			nameLen = 64
+		}
+		u := make([]uint16, 0, 32)
+		for i := 0; i+1 < nameLen; i += 2 {
+			if v := le.Uint16(e[i:]); v != 0 {
+				u = append(u, v)
+			}
+		}
+		c.entries = append(c.entries, cfbEntry{
+			name:  string(utf16.Decode(u)),
+			typ:   e[66],
+			start: le.Uint32(e[116:]),
+			size:  le.Uint64(e[120:]),
+		})
+	}
+	if len(c.entries) == 0 || c.entries[0].typ != 5 {
+		return nil, errCFBCorrupt
+	}
+	if c.sectorSize == 512 {
+		// version 3 files only define the low 32 bits of the size
+		for i := range c.entries {
+			c.entries[i].size &= 0xFFFFFFFF
+		}
+	}
+
+	root := c.entries[0]
+	if c.miniStream, err = c.chain(root.start, root.size); err != nil {
+		return nil, err
+	}
+	miniFAT, err := c.chain(le.Uint32(data[0x3C:]), 0)
+	if err != nil {
+		return nil, err
+	}
+	for j := 0; j+4 <= len(miniFAT); j += 4 {
+		c.miniFAT = append(c.miniFAT, le.Uint32(miniFAT[j:]))
+	}
+	return c, nil
+}
+
+// sector returns sector n; the header occupies the space of sector -1.
+func (c *cfbFile) sector(n uint32) []byte {
+	off := (int64(n) + 1) * int64(c.sectorSize)
+	if off+int64(c.sectorSize) > int64(len(c.data)) {
+		return nil
+	}
+	return c.data[off : off+int64(c.sectorSize)]
+}
+
+// sectors returns the number of whole sectors after the header.
+func (c *cfbFile) sectors() int {
+	return len(c.data)/c.sectorSize - 1
+}
+
+// chain concatenates the sectors of a FAT chain, truncated to size when it is
+// not zero. A chain that loops is corrupt.
+func (c *cfbFile) chain(start uint32, size uint64) ([]byte, error) {
+	var out []byte
+	seen := make(map[uint32]bool)
+	for n := start; n != cfbEndOfChain && n != cfbFreeSect; {
+		if seen[n] || len(seen) >= c.sectors() || int(n) >= len(c.fat) {
+			return nil, errCFBCorrupt
+		}
+		seen[n] = true
+		sec := c.sector(n)
+		if sec == nil {
+			return nil, errCFBCorrupt
+		}
+		out = append(out, sec...)
+		n = c.fat[n]
+	}
+	if size > 0 && uint64(len(out)) > size {
+		out = out[:size]
+	}
+	return out, nil
+}
+
+// miniChain reads a stream stored in the mini stream. A chain that loops is
+// corrupt.
+func (c *cfbFile) miniChain(start uint32, size uint64) ([]byte, error) {
+	var out []byte
+	seen := make(map[uint32]bool)
+	for n := start; n != cfbEndOfChain && n != cfbFreeSect; {
+		if seen[n] || int(n) >= len(c.miniFAT) {
+			return nil, errCFBCorrupt
+		}
+		seen[n] = true
+		off := int(n) * c.miniSize
+		if off+c.miniSize > len(c.miniStream) {
+			return nil, errCFBCorrupt
+		}
+		out = append(out, c.miniStream[off:off+c.miniSize]...)
+		n = c.miniFAT[n]
+	}
+	if uint64(len(out)) > size {
+		out = out[:size]
+	}
+	return out, nil
+}
+
+// stream returns the content of the first stream with the given name,
+// compared case-insensitively as the format requires.
+func (c *cfbFile) stream(name string) ([]byte, error) {
+	for _, e := range c.entries {
+		if e.typ != 2 || !strings.EqualFold(e.name, name) {
+			continue
+		}
+		if e.size == 0 {
+			return nil, nil
+		}
+		if e.size < c.miniCutoff {
+			return c.miniChain(e.start, e.size)
+		}
+		return c.chain(e.start, e.size)
+	}
+	return nil, errors.New("cfb: stream " + name + " not found")
+}
+
+// hasStream reports whether the container has a stream with the given name.
+func (c *cfbFile) hasStream(name string) bool {
+	for _, e := range c.entries {
+		if e.typ == 2 && strings.EqualFold(e.name, name) {
+			return true
+		}
+	}
+	return false
+}
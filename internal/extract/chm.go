@@ -0,0 +1,142 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// chmTools are tried in order when Options.CHMTool is empty.
+var chmTools = []string{"7z", "7za", "extract_chmLib"}
+
+var reCHMTopic = regexp.MustCompile(`(?i)<param\s+name="Local"\s+value="([^"]+)"`)
+
+// extractCHM unpacks a compiled HTML help file with an external tool and converts its
+// topics to text, following the table of contents (.hhc) order where available.
+func extractCHM(ctx context.Context, data []byte, opts Options) (string, error) {
+	// the topics are HTML pages, and the allowlist applies to them too
+	if err := opts.checkFormat("html"); err != nil {
+		return "", err
+	}
+	tool, err := findCHMTool(opts.CHMTool)
+	if err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp("", "docparser-chm-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "input.chm")
+	if err := os.WriteFile(src, data, 0o600); err != nil {
+		return "", err
+	}
+	out := filepath.Join(dir, "out")
+	var cmd *exec.Cmd
+	if strings.Contains(filepath.Base(tool), "extract_chmLib") {
+		cmd = exec.CommandContext(ctx, tool, src, out)
+	} else {
+		cmd = exec.CommandContext(ctx, tool, "x", "-y", "-o"+out, src)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stop := phaseTimer(ctx, PhaseSubprocess)
+	err = cmd.Run()
+	stop()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w: %s", filepath.Base(tool), err, strings.TrimSpace(stderr.String()))
+	}
+
+	topics, err := chmTopics(out)
+	if err != nil {
+		return "", err
+	}
+	var parts []string
+	for _, topic := range topics {
+		data, err := os.ReadFile(topic)
+		if err != nil {
+			continue
+		}
+		// help files are mostly written in legacy code pages
+		text, err := extractHTML(data)
+		if err == nil && text != "" {
+			parts = append(parts, text)
+		}
+	}
+	if len(parts) == 0 {
+		return "", errors.New("no html topics found in chm")
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+func findCHMTool(configured string) (string, error) {
+	if configured != "" {
+		return exec.LookPath(configured)
+	}
+	for _, name := range chmTools {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.New("chm extraction requires one of: " + strings.Join(chmTools, ", "))
+}
+
+// chmTopics lists the unpacked HTML topics: first in table of contents order,
+// then any remaining pages sorted by path.
+func chmTopics(root string) ([]string, error) {
+	var pages, tocs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".htm", ".html":
+			pages = append(pages, path)
+		case ".hhc":
+			tocs = append(tocs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(pages)
+
+	// CHM paths are case-insensitive
+	byName := make(map[string]string, len(pages))
+	for _, p := range pages {
+		rel, _ := filepath.Rel(root, p)
+		byName[strings.ToLower(filepath.ToSlash(rel))] = p
+	}
+	seen := make(map[string]bool)
+	var ordered []string
+	for _, toc := range tocs {
+		raw, err := os.ReadFile(toc)
+		if err != nil {
+			continue
+		}
+		for _, m := range reCHMTopic.FindAllSubmatch(raw, -1) {
+			local, _, _ := strings.Cut(string(m[1]), "#")
+			p, ok := byName[strings.ToLower(strings.TrimPrefix(local, "/"))]
+			if ok && !seen[p] {
+				seen[p] = true
+				ordered = append(ordered, p)
+			}
+		}
+	}
+	for _, p := range pages {
+		if !seen[p] {
+			ordered = append(ordered, p)
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,79 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// fakeCHMTool writes a script that unpacks any input, like extract_chmLib,
+// into a table of contents listing b.htm before a.htm and three topics.
+func fakeCHMTool(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake tool is a shell script")
+	}
+	tool := filepath.Join(t.TempDir(), "extract_chmLib")
+	script := `#!/bin/sh
+mkdir -p "$2/sub"
+printf '<object><param name="Local" value="B.htm#top"></object><object><param name="Local" value="/a.htm"></object>' > "$2/toc.hhc"
+printf '<html><body><p>Topic A</p></body></html>' > "$2/a.htm"
+printf '<html><body><p>Topic B</p></body></html>' > "$2/b.htm"
+printf '<html><body><p>Appendix</p></body></html>' > "$2/sub/z.html"
+`
+	if err := os.WriteFile(tool, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return tool
+}
+
+func TestExtractCHM(t *testing.T) {
+	opts := DefaultOptions()
+	opts.CHMTool = fakeCHMTool(t)
+	got, err := ExtractTextWithOptions("help.chm", []byte("ITSF"), opts)
+	if want := "Topic B\n\nTopic A\n\nAppendix"; err != nil || got != want {
+		t.Errorf("got %q, %v; want %q", got, err, want)
+	}
+
+	opts.CHMTool = filepath.Join(t.TempDir(), "missing-tool")
+	if _, err := ExtractTextWithOptions("help.chm", []byte("ITSF"), opts); err == nil {
+		t.Error("a missing tool gave no error")
+	}
+}
+
+func TestExtractCHMCodePage(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake tool is a shell script")
+	}
+	encode := func(s string) []byte {
+		b, err := charmap.Windows1251.NewEncoder().Bytes([]byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	// one topic declares its charset, the other relies on detection
+	topics := t.TempDir()
+	for name, data := range map[string][]byte{
+		"toc.hhc": []byte(`<object><param name="Local" value="a.htm"></object><object><param name="Local" value="b.htm"></object>`),
+		"a.htm":   encode(`<html><head><meta charset="windows-1251"></head><body><p>Привет</p></body></html>`),
+		"b.htm":   encode(`<html><body><p>Съешь же ещё этих мягких французских булок, да выпей чаю.</p></body></html>`),
+	} {
+		if err := os.WriteFile(filepath.Join(topics, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tool := filepath.Join(t.TempDir(), "extract_chmLib")
+	if err := os.WriteFile(tool, []byte("#!/bin/sh\nmkdir -p \"$2\" && cp \""+topics+"\"/* \"$2\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.CHMTool = tool
+	got, err := ExtractTextWithOptions("help.chm", []byte("ITSF"), opts)
+	if want := "Привет\n\nСъешь же ещё этих мягких французских булок, да выпей чаю."; err != nil || got != want {
+		t.Errorf("got %q, %v; want %q", got, err, want)
+	}
+}
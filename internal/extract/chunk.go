@@ -0,0 +1,96 @@
+package extract
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// ChunkText splits text into chunks of at most size runes for embedding
+// models, each repeating about the last overlap runes of the one before so
+// that context is not lost at the seams. A chunk ends at the last paragraph
+// break in the second half of its window if there is one, else at the last
+// line break, sentence end or space there, and only in the middle of a word
+// when the window has none. Overlaps start at a word boundary, so they may
+// be somewhat shorter or longer than overlap. Chunks are trimmed of
+// surrounding whitespace; blank ones are dropped.
+func ChunkText(text string, size, overlap int) ([]string, error) {
+	if size <= 0 {
+		return nil, errors.New("chunk size must be positive")
+	}
+	if overlap < 0 || overlap >= size {
+		return nil, errors.New("chunk overlap must be at least 0 and less than the chunk size")
+	}
+	r := []rune(text)
+	var chunks []string
+	for start := 0; start < len(r); {
+		end := len(r)
+		if start+size < len(r) {
+			end = chunkBreak(r, start+size/2, start+size)
+		}
+		if c := strings.TrimSpace(string(r[start:end])); c != "" {
+			chunks = append(chunks, c)
+		}
+		if end == len(r) {
+			break
+		}
+		next := max(end-overlap, start+1)
+		if overlap > 0 {
+			// begin the overlap with a whole word: the next one, or the one
+			// cut when no word starts before the end of the chunk
+			next = wordStart(r, next, end, start+1)
+		} else {
+			next = end
+		}
+		start = next
+	}
+	return chunks, nil
+}
+
+// wordStart returns the first word start in r[i:hi], or failing that the last
+// one in r[lo:i], or i if neither exists.
+func wordStart(r []rune, i, hi, lo int) int {
+	for j := i; j < hi; j++ {
+		if unicode.IsSpace(r[j-1]) && !unicode.IsSpace(r[j]) {
+			return j
+		}
+	}
+	for j := i - 1; j >= lo; j-- {
+		if unicode.IsSpace(r[j-1]) && !unicode.IsSpace(r[j]) {
+			return j
+		}
+	}
+	return i
+}
+
+// chunkBreak returns where to end a chunk within r[lo:hi]: just after the
+// best boundary found, or hi when there is none.
+func chunkBreak(r []rune, lo, hi int) int {
+	para, line, sentence, space := -1, -1, -1, -1
+	for i := hi - 1; i >= lo && i > 0; i-- {
+		switch c := r[i]; {
+		case c == '\n' && r[i-1] == '\n':
+			para = i + 1
+		case c == '\n':
+			if line < 0 {
+				line = i + 1
+			}
+		case unicode.IsSpace(c):
+			if sentence < 0 && strings.ContainsRune(".!?…", r[i-1]) {
+				sentence = i + 1
+			}
+			if space < 0 {
+				space = i + 1
+			}
+		}
+		if para >= 0 {
+			break
+		}
+	}
+	for _, b := range []int{para, line, sentence, space} {
+		if b > 0 {
+			return b
+		}
+	}
+	return hi
+}
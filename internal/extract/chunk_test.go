@@ -0,0 +1,64 @@
+package extract
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunkText(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		text          string
+		size, overlap int
+		want          []string
+	}{
+		{"fits", "short text", 20, 5, []string{"short text"}},
+		{"paragraph break preferred", "First para one.\n\nSecond para two. More words", 30, 0,
+			[]string{"First para one.", "Second para two. More words"}},
+		{"sentence end over space", "Раз два три. Четыре пять шесть", 20, 0,
+			[]string{"Раз два три.", "Четыре пять шесть"}},
+		{"overlap starts at a word", "alpha beta gamma delta epsilon", 16, 6,
+			[]string{"alpha beta", "beta gamma", "gamma delta", "delta epsilon"}},
+		{"no boundary", "abcdefghij", 4, 1, []string{"abcd", "defg", "ghij"}},
+		{"blank", " \n\n ", 4, 0, nil},
+	} {
+		got, err := ChunkText(tc.text, tc.size, tc.overlap)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+
+	// numbered words show the overlap and that nothing is lost between chunks
+	var words []string
+	for i := range 200 {
+		words = append(words, "слово"+strconv.Itoa(i))
+	}
+	chunks, err := ChunkText(strings.Join(words, " "), 100, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := -1
+	for i, c := range chunks {
+		if n := utf8.RuneCountInString(c); n > 100 {
+			t.Errorf("chunk %d has %d runes, want at most 100", i, n)
+		}
+		fields := strings.Fields(c)
+		first, _ := strconv.Atoi(strings.TrimPrefix(fields[0], "слово"))
+		if i > 0 && first > last {
+			t.Errorf("chunk %d starts at word %d, after the end of the previous chunk at %d", i, first, last)
+		}
+		last, _ = strconv.Atoi(strings.TrimPrefix(fields[len(fields)-1], "слово"))
+	}
+	if last != len(words)-1 {
+		t.Errorf("the last chunk ends at word %d", last)
+	}
+
+	for _, bad := range [][2]int{{0, 0}, {-1, 0}, {10, -1}, {10, 10}} {
+		if _, err := ChunkText("text", bad[0], bad[1]); err == nil {
+			t.Errorf("size %d, overlap %d accepted", bad[0], bad[1])
+		}
+	}
+}
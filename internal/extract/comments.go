@@ -0,0 +1,236 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Comment is a review comment attached to a location in a document.
+type Comment struct {
+	Author string `json:"author"`
+	// Location is "slide N" for presentations and "Sheet!A1" for workbooks.
+	Location string `json:"location"`
+	Text     string `json:"text"`
+	// Resolved reports a comment marked as resolved/done by a reviewer.
+	Resolved bool `json:"resolved,omitempty"`
+}
+
+// ExtractComments returns the review comments of a PPTX or XLSX file in document
+// order. Replies follow the comment they answer.
+func ExtractComments(filename string, data []byte) ([]Comment, error) {
+	return ExtractCommentsWithOptions(filename, data, DefaultOptions())
+}
+
+// ExtractCommentsWithOptions is like ExtractComments but fails with
+// ErrFormatDisabled for a format left out of Options.EnabledFormats.
+func ExtractCommentsWithOptions(filename string, data []byte, opts Options) ([]Comment, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := zipIndex(zr)
+	format := fileFormat(filename)
+	switch {
+	case format == "pptx" || (format != "xlsx" && files["ppt/presentation.xml"] != nil):
+		if err := opts.checkFormat("pptx"); err != nil {
+			return nil, err
+		}
+		return pptxComments(files)
+	case format == "xlsx" || files["xl/workbook.xml"] != nil:
+		if err := opts.checkFormat("xlsx"); err != nil {
+			return nil, err
+		}
+		return xlsxComments(files)
+	}
+	return nil, errors.New("comments are supported for pptx and xlsx only")
+}
+
+// formatComments renders comments as an appendix to extracted text, one per
+// line as "location (author): text"; no comments yield "".
+func formatComments(comments []Comment) string {
+	if len(comments) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n[Comments]\n")
+	for _, c := range comments {
+		b.WriteString(c.Location)
+		if c.Author != "" {
+			b.WriteString(" (" + c.Author + ")")
+		}
+		b.WriteString(": " + strings.ReplaceAll(c.Text, "\n", " ") + "\n")
+	}
+	return b.String()
+}
+
+// ooxmlTextBody is a DrawingML text body (a:p/a:r/a:t).
+type ooxmlTextBody struct {
+	Paras []struct {
+		Runs []string `xml:"r>t"`
+	} `xml:"p"`
+}
+
+func (tb ooxmlTextBody) String() string {
+	var paras []string
+	for _, p := range tb.Paras {
+		paras = append(paras, strings.Join(p.Runs, ""))
+	}
+	return strings.TrimSpace(strings.Join(paras, "\n"))
+}
+
+// pptxComment covers both legacy (p:cm with p:text) and modern (p188:cm with
+// p188:txBody) PowerPoint comments.
+type pptxComment struct {
+	AuthorID string        `xml:"authorId,attr"`
+	Status   string        `xml:"status,attr"`
+	Text     string        `xml:"text"`
+	Body     ooxmlTextBody `xml:"txBody"`
+	Replies  []pptxComment `xml:"replyLst>reply"`
+}
+
+func pptxComments(files map[string]*zip.File) ([]Comment, error) {
+	authors := make(map[string]string)
+	for _, part := range []string{"ppt/commentAuthors.xml", "ppt/authors.xml"} {
+		var list struct {
+			Legacy []struct {
+				ID   string `xml:"id,attr"`
+				Name string `xml:"name,attr"`
+			} `xml:"cmAuthor"`
+			Modern []struct {
+				ID   string `xml:"id,attr"`
+				Name string `xml:"name,attr"`
+			} `xml:"author"`
+		}
+		if unmarshalZipXML(files, part, &list) != nil {
+			continue
+		}
+		for _, a := range list.Legacy {
+			authors[a.ID] = a.Name
+		}
+		for _, a := range list.Modern {
+			authors[a.ID] = a.Name
+		}
+	}
+
+	var out []Comment
+	var add func(loc string, c pptxComment, resolved bool)
+	add = func(loc string, c pptxComment, resolved bool) {
+		text := strings.TrimSpace(c.Text)
+		if text == "" {
+			text = c.Body.String()
+		}
+		resolved = resolved || c.Status == "resolved"
+		out = append(out, Comment{Author: authors[c.AuthorID], Location: loc, Text: text, Resolved: resolved})
+		for _, r := range c.Replies {
+			add(loc, r, resolved)
+		}
+	}
+	for _, slide := range pptxSlides(files) {
+		loc := "slide " + strconv.Itoa(slide.Num)
+		for _, rel := range sortedRels(readRels(files, slide.Part)) {
+			if !strings.HasSuffix(rel.Type, "/comments") {
+				continue
+			}
+			var list struct {
+				Comments []pptxComment `xml:"cm"`
+			}
+			if err := unmarshalZipXML(files, relTarget(slide.Part, rel.Target), &list); err != nil {
+				return nil, err
+			}
+			for _, c := range list.Comments {
+				add(loc, c, false)
+			}
+		}
+	}
+	return out, nil
+}
+
+// xlsxRichText is rich text in a workbook (comments, shared and inline
+// strings): either a plain t or formatted runs.
+type xlsxRichText struct {
+	T    string   `xml:"t"`
+	Runs []string `xml:"r>t"`
+}
+
+func (rt xlsxRichText) String() string {
+	return strings.TrimSpace(rt.T + strings.Join(rt.Runs, ""))
+}
+
+func xlsxComments(files map[string]*zip.File) ([]Comment, error) {
+	sheets, err := xlsxSheets(files)
+	if err != nil {
+		return nil, err
+	}
+	var persons struct {
+		Persons []struct {
+			ID   string `xml:"id,attr"`
+			Name string `xml:"displayName,attr"`
+		} `xml:"person"`
+	}
+	_ = unmarshalZipXML(files, "xl/persons/person.xml", &persons)
+	personNames := make(map[string]string)
+	for _, p := range persons.Persons {
+		personNames[p.ID] = p.Name
+	}
+
+	var out []Comment
+	for _, sheet := range sheets {
+		var legacy, threaded string
+		for _, rel := range sortedRels(readRels(files, sheet.Part)) {
+			switch {
+			case strings.HasSuffix(rel.Type, "/threadedComment"):
+				threaded = relTarget(sheet.Part, rel.Target)
+			case strings.HasSuffix(rel.Type, "/comments"):
+				legacy = relTarget(sheet.Part, rel.Target)
+			}
+		}
+		// threaded comments are mirrored into the legacy part for old readers;
+		// prefer them so each comment is reported once
+		if threaded != "" {
+			var list struct {
+				Comments []struct {
+					Ref      string `xml:"ref,attr"`
+					PersonID string `xml:"personId,attr"`
+					Done     string `xml:"done,attr"`
+					Text     string `xml:"text"`
+				} `xml:"threadedComment"`
+			}
+			if err := unmarshalZipXML(files, threaded, &list); err != nil {
+				return nil, err
+			}
+			for _, c := range list.Comments {
+				out = append(out, Comment{
+					Author:   personNames[c.PersonID],
+					Location: sheet.Name + "!" + c.Ref,
+					Text:     strings.TrimSpace(c.Text),
+					Resolved: c.Done == "1" || c.Done == "true",
+				})
+			}
+			continue
+		}
+		if legacy != "" {
+			var list struct {
+				Authors  []string `xml:"authors>author"`
+				Comments []struct {
+					Ref      string       `xml:"ref,attr"`
+					AuthorID int          `xml:"authorId,attr"`
+					Text     xlsxRichText `xml:"text"`
+				} `xml:"commentList>comment"`
+			}
+			if err := unmarshalZipXML(files, legacy, &list); err != nil {
+				return nil, err
+			}
+			for _, c := range list.Comments {
+				author := ""
+				if c.AuthorID >= 0 && c.AuthorID < len(list.Authors) {
+					author = list.Authors[c.AuthorID]
+				}
+				out = append(out, Comment{Author: author, Location: sheet.Name + "!" + c.Ref, Text: c.Text.String()})
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,58 @@
+package extract
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractCommentsPPTX(t *testing.T) {
+	pptx := testZip(t,
+		"ppt/presentation.xml", `<p:presentation `+testPresentationNS+`/>`,
+		"ppt/slides/slide1.xml", testSlide("Title"),
+		"ppt/slides/_rels/slide1.xml.rels", `<Relationships `+testRelsNS+`><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments" Target="../comments/comment1.xml"/></Relationships>`,
+		"ppt/commentAuthors.xml", `<p:cmAuthorLst `+testPresentationNS+`><p:cmAuthor id="0" name="Anna"/><p:cmAuthor id="1" name="Boris"/></p:cmAuthorLst>`,
+		"ppt/comments/comment1.xml", `<p:cmLst `+testPresentationNS+`><p:cm authorId="0"><p:text>Check the figure</p:text></p:cm><p:cm authorId="1" status="resolved"><p:text>Typo</p:text><p:replyLst><p:reply authorId="0"><p:text>Fixed</p:text></p:reply></p:replyLst></p:cm></p:cmLst>`,
+	)
+	want := []Comment{
+		{Author: "Anna", Location: "slide 1", Text: "Check the figure"},
+		{Author: "Boris", Location: "slide 1", Text: "Typo", Resolved: true},
+		{Author: "Anna", Location: "slide 1", Text: "Fixed", Resolved: true},
+	}
+	got, err := ExtractComments("deck.pptx", pptx)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractComments = %+v, %v; want %+v", got, err, want)
+	}
+
+	opts := DefaultOptions()
+	opts.IncludeComments = true
+	text, err := ExtractTextWithOptions("deck.pptx", pptx, opts)
+	if err != nil || !strings.HasSuffix(text, "[Comments]\nslide 1 (Anna): Check the figure\nslide 1 (Boris): Typo\nslide 1 (Anna): Fixed\n") {
+		t.Errorf("text = %q, %v", text, err)
+	}
+}
+
+func TestExtractCommentsXLSX(t *testing.T) {
+	sheetRels := func(typ, target string) string {
+		return `<Relationships ` + testRelsNS + `><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/` + typ + `" Target="` + target + `"/></Relationships>`
+	}
+	xlsx := testXLSX(t, []string{`<row r="1"><c r="A1" t="inlineStr"><is><t>total</t></is></c></row>`, ``},
+		"xl/worksheets/_rels/sheet1.xml.rels", sheetRels("comments", "../comments1.xml"),
+		"xl/comments1.xml", `<comments xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><authors><author>Anna</author></authors><commentList><comment ref="A1" authorId="0"><text><r><t>Is this </t></r><r><t>right?</t></r></text></comment></commentList></comments>`,
+		"xl/worksheets/_rels/sheet2.xml.rels", sheetRels("threadedComment", "../threadedComments/threadedComment1.xml"),
+		"xl/threadedComments/threadedComment1.xml", `<ThreadedComments xmlns="http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments"><threadedComment ref="B2" personId="{1}" done="1"><text>Done here</text></threadedComment></ThreadedComments>`,
+		"xl/persons/person.xml", `<personList xmlns="http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments"><person displayName="Boris" id="{1}"/></personList>`,
+	)
+	want := []Comment{
+		{Author: "Anna", Location: "Sheet1!A1", Text: "Is this right?"},
+		{Author: "Boris", Location: "Sheet2!B2", Text: "Done here", Resolved: true},
+	}
+	got, err := ExtractComments("book.xlsx", xlsx)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractComments = %+v, %v; want %+v", got, err, want)
+	}
+
+	if _, err := ExtractComments("a.docx", testDOCX(t, ``)); err == nil {
+		t.Error("ExtractComments accepted a docx")
+	}
+}
@@ -0,0 +1,95 @@
+package extract
+
+import (
+	"encoding/csv"
+	"strings"
+)
+
+// csvSampleLines is how many leading lines are examined to guess the delimiter.
+const csvSampleLines = 5
+
+// csvDelimiters are the delimiters tried, in order of preference on a tie.
+var csvDelimiters = []rune{',', '\t', ';'}
+
+// extractCSV renders delimited data as one line per record with fields
+// separated by tabs; newlines inside quoted fields become spaces so a record
+// stays on its line. A delim of 0 is detected from the first lines. The text
+// is decoded like TXT first, and returned as such if it does not parse as CSV.
+func extractCSV(data []byte, delim rune, opts Options) (string, error) {
+	text, err := extractTXT(data, opts)
+	if err != nil {
+		return "", err
+	}
+	text = strings.TrimPrefix(text, "\uFEFF")
+	if delim == 0 {
+		delim = detectCSVDelimiter(text)
+	}
+	r := csv.NewReader(strings.NewReader(text))
+	r.Comma = delim
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return text, nil
+	}
+	var b strings.Builder
+	for _, rec := range records {
+		for len(rec) > 0 && strings.TrimSpace(rec[len(rec)-1]) == "" {
+			rec = rec[:len(rec)-1]
+		}
+		if len(rec) == 0 {
+			continue
+		}
+		for i, field := range rec {
+			if i > 0 {
+				b.WriteByte('\t')
+			}
+			b.WriteString(strings.Join(strings.Fields(field), " "))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// detectCSVDelimiter picks the delimiter that occurs outside quotes the same
+// number of times on every sampled line, preferring the most frequent; when
+// none is consistent, the most frequent overall wins. Comma is the default.
+func detectCSVDelimiter(text string) rune {
+	lines := strings.SplitN(text, "\n", csvSampleLines+1)
+	if len(lines) > csvSampleLines {
+		lines = lines[:csvSampleLines]
+	}
+	best, bestConsistent, bestCount := ',', false, 0
+	for _, d := range csvDelimiters {
+		per, total, consistent := -1, 0, true
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			n := countUnquoted(line, d)
+			if per >= 0 && n != per {
+				consistent = false
+			}
+			per = n
+			total += n
+		}
+		consistent = consistent && total > 0
+		if (consistent && !bestConsistent) || (consistent == bestConsistent && total > bestCount) {
+			best, bestConsistent, bestCount = d, consistent, total
+		}
+	}
+	return best
+}
+
+// countUnquoted counts r in line outside double-quoted sections.
+func countUnquoted(line string, r rune) int {
+	n, quoted := 0, false
+	for _, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == r && !quoted:
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,108 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// xmlTextAttrs are attributes whose values are human-readable text rather than
+// identifiers or settings.
+var xmlTextAttrs = map[string]bool{
+	"title": true, "alt": true, "label": true, "caption": true,
+	"description": true, "summary": true, "text": true,
+}
+
+// extractJSON returns the string values of a JSON document (or a stream of
+// documents, such as JSON Lines) one per line in document order. Object keys,
+// numbers, booleans and nulls are skipped.
+func extractJSON(data []byte) (string, error) {
+	type container struct {
+		object  bool
+		wantKey bool // in an object, the next string is a key
+	}
+	dec := json.NewDecoder(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+	dec.UseNumber()
+	var stack []container
+	var lines []string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF && len(stack) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", errors.New("invalid json: " + err.Error())
+		}
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if n := len(stack); n > 0 && stack[n-1].object {
+			if stack[n-1].wantKey {
+				stack[n-1].wantKey = false
+				continue
+			}
+			stack[n-1].wantKey = true
+		}
+		switch v := tok.(type) {
+		case json.Delim:
+			stack = append(stack, container{object: v == '{', wantKey: v == '{'})
+		case string:
+			if s := strings.Join(strings.Fields(v), " "); s != "" {
+				lines = append(lines, s)
+			}
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// extractXML returns the text nodes of an XML document and the values of
+// xmlTextAttrs attributes, one per line in document order; element and other
+// attribute names are skipped. The declared encoding is honored. A bare DOCX
+// document.xml is handed to the DOCX walker.
+func extractXML(ctx context.Context, data []byte, opts Options) (string, error) {
+	if bytes.Contains(data[:min(len(data), 4096)], []byte("<w:document")) {
+		return extractDOCX(ctx, data, opts)
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.CharsetReader = charset.NewReaderLabel
+	var lines []string
+	add := func(s string) {
+		if s = strings.Join(strings.Fields(s), " "); s != "" {
+			lines = append(lines, s)
+		}
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(lines) == 0 {
+				return "", errors.New("invalid xml: " + err.Error())
+			}
+			// keep what was read before the damage
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			for _, a := range t.Attr {
+				if xmlTextAttrs[strings.ToLower(a.Name.Local)] {
+					add(a.Value)
+				}
+			}
+		case xml.CharData:
+			add(string(t))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
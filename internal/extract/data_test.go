@@ -0,0 +1,29 @@
+package extract
+
+import "testing"
+
+func TestExtractDataFiles(t *testing.T) {
+	for _, tc := range []struct {
+		name, filename, data, want string
+		ok                         bool
+	}{
+		{"nested json", "a.json", `{"title": "Report", "count": 3, "tags": ["alpha", "  beta\n gamma "], "author": {"name": "Иван", "active": true, "note": null}, "empty": ""}`,
+			"Report\nalpha\nbeta gamma\nИван", true},
+		{"json lines", "a.json", "\ufeff{\"a\": \"one\"}\n{\"a\": \"two\"}\n", "one\ntwo", true},
+		{"keys are skipped", "a.json", `{"key": {"inner": "value"}}`, "value", true},
+		{"truncated json", "a.json", `{"a": ["x"`, "", false},
+		{"xml", "a.xml", `<?xml version="1.0"?><catalog><book id="b1" title="Go"><author>Rob</author><summary>  A  book </summary></book><img alt="cover" src="c.png"/></catalog>`,
+			"Go\nRob\nA book\ncover", true},
+		{"xml encoding", "a.xml", "<?xml version=\"1.0\" encoding=\"windows-1251\"?><p>\xcf\xf0\xe8\xe2\xe5\xf2</p>", "Привет", true},
+		{"damaged xml keeps the text before", "a.xml", `<a><b>kept</b><c>`, "kept", true},
+		{"invalid xml", "a.xml", `<a`, "", false},
+	} {
+		got, err := ExtractText(tc.filename, []byte(tc.data))
+		if tc.ok && (err != nil || got != tc.want) {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("%s: got %q, want an error", tc.name, got)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package extract
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultMaxRecursionDepth is the nesting of sub-documents (compressed
+// payloads, imported DOCX chunks) allowed when Options.MaxRecursionDepth is
+// zero.
+const DefaultMaxRecursionDepth = 3
+
+// ErrMaxDepthExceeded is returned for input whose sub-documents nest deeper
+// than Options.MaxRecursionDepth.
+var ErrMaxDepthExceeded = errors.New("maximum nesting depth of embedded documents exceeded")
+
+// depthKey carries the nesting depth of the document being extracted; the
+// top-level input is at depth 0.
+type depthKey struct{}
+
+// enterNested returns the context for extracting a sub-document of the one
+// ctx belongs to, failing with ErrMaxDepthExceeded past the limit.
+func enterNested(ctx context.Context, opts Options) (context.Context, error) {
+	depth, _ := ctx.Value(depthKey{}).(int)
+	if depth >= opts.maxRecursionDepth() {
+		return ctx, ErrMaxDepthExceeded
+	}
+	return context.WithValue(ctx, depthKey{}, depth+1), nil
+}
@@ -0,0 +1,43 @@
+package extract
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMaxRecursionDepth(t *testing.T) {
+	nested := func(levels int) []byte {
+		data := []byte("deep text")
+		for range levels {
+			data = gzipBytes(t, data)
+		}
+		return data
+	}
+	for _, tc := range []struct {
+		limit, levels int
+		ok            bool
+	}{
+		{0, DefaultMaxRecursionDepth, true},
+		{0, DefaultMaxRecursionDepth + 1, false},
+		{1, 1, true},
+		{1, 2, false},
+		{10, 10, true},
+	} {
+		opts := DefaultOptions()
+		opts.MaxRecursionDepth = tc.limit
+		for _, name := range []string{"a.txt.gz", "a"} {
+			got, err := ExtractTextWithOptions(name, nested(tc.levels), opts)
+			if tc.ok && (err != nil || got != "deep text") {
+				t.Errorf("limit %d, %d levels, %s: got %q, %v", tc.limit, tc.levels, name, got, err)
+			}
+			if !tc.ok && !errors.Is(err, ErrMaxDepthExceeded) {
+				t.Errorf("limit %d, %d levels, %s: err = %v, want ErrMaxDepthExceeded", tc.limit, tc.levels, name, err)
+			}
+		}
+	}
+	opts := DefaultOptions()
+	opts.MaxRecursionDepth = -1
+	if _, err := ExtractTextWithOptions("a.txt", []byte("x"), opts); err == nil {
+		t.Error("negative depth accepted")
+	}
+}
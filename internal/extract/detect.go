@@ -0,0 +1,142 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// signatureFormats are the formats DetectFormat recognizes; files of other
+// formats (plain text, markup) have no signature to check.
+var signatureFormats = map[string]bool{
+	"pdf": true, "rtf": true, "gz": true, "chm": true, "zip": true,
+	"docx": true, "xlsx": true, "pptx": true, "odt": true, "epub": true,
+	"numbers": true, "doc": true, "xls": true, "ppt": true,
+}
+
+// extensionAliases maps alternative extensions to the format name
+// DetectFormat reports or the extractor is known by. Macro-enabled Office
+// files are the same packages with a vbaProject.bin part, which is ignored
+// for text.
+var extensionAliases = map[string]string{
+	"docm": "docx", "dotx": "docx", "xlsm": "xlsx", "pptm": "pptx",
+	"htm": "html", "xhtml": "html", "markdown": "md",
+	"tgz": "gz",
+}
+
+// fileFormat returns the lower-cased extension of filename without the dot,
+// with aliases resolved: report.docm is "docx".
+func fileFormat(filename string) string {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	if alias, ok := extensionAliases[ext]; ok {
+		return alias
+	}
+	return ext
+}
+
+// ooxmlFormat corrects an Office Open XML format named by the extension to
+// the one the package contents show, so that a workbook saved as .docx or a
+// .pptm renamed to .xlsx reaches the right extractor.
+func ooxmlFormat(format string, data []byte) string {
+	switch format {
+	case "docx", "xlsx", "pptx":
+	default:
+		return format
+	}
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		return format
+	}
+	switch detected := zipFormat(data); detected {
+	case "docx", "xlsx", "pptx":
+		return detected
+	}
+	return format
+}
+
+// DetectFormat identifies data by its signature, looking inside zip and OLE2
+// containers to tell office formats apart. It returns a format name such as
+// "pdf", "docx" or "xlsx"; "encrypted-ooxml" for a password-protected DOCX,
+// XLSX or PPTX; "zip" or "ole2" for other containers; or "" when data has no
+// known signature, as with plain text.
+func DetectFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF")):
+		return "pdf"
+	case bytes.HasPrefix(data, []byte("{\\rtf")):
+		return "rtf"
+	case bytes.HasPrefix(data, gzipMagic):
+		return "gz"
+	case bytes.HasPrefix(data, []byte("ITSF")):
+		return "chm"
+	case bytes.HasPrefix(data, cfbMagic):
+		c, err := openCFB(data)
+		if err != nil {
+			return "ole2"
+		}
+		for _, f := range []struct{ stream, format string }{
+			{"EncryptedPackage", "encrypted-ooxml"},
+			{"WordDocument", "doc"}, {"Workbook", "xls"}, {"Book", "xls"}, {"PowerPoint Document", "ppt"},
+		} {
+			if c.hasStream(f.stream) {
+				return f.format
+			}
+		}
+		return "ole2"
+	case bytes.HasPrefix(data, []byte("PK")):
+		return zipFormat(data)
+	}
+	return ""
+}
+
+// zipFormat tells zip-based formats apart by their characteristic parts.
+func zipFormat(data []byte) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "zip"
+	}
+	files := zipIndex(zr)
+	switch {
+	case files["word/document.xml"] != nil:
+		return "docx"
+	case files["xl/workbook.xml"] != nil:
+		return "xlsx"
+	case files["ppt/presentation.xml"] != nil:
+		return "pptx"
+	case files["Index.zip"] != nil:
+		return "numbers"
+	}
+	for name := range files {
+		if strings.HasPrefix(name, "Index/") && strings.HasSuffix(name, ".iwa") {
+			return "numbers"
+		}
+	}
+	if f := files["mimetype"]; f != nil {
+		if mime, err := readZipFile(f); err == nil {
+			switch strings.TrimSpace(string(mime)) {
+			case "application/epub+zip":
+				return "epub"
+			case "application/vnd.oasis.opendocument.text":
+				return "odt"
+			}
+		}
+	}
+	return "zip"
+}
+
+// VerifyExtension compares the extension of filename with the format detected
+// from data. ext is the lower-cased extension without the dot. mismatch is
+// true when data has a signature of another format, or when the extension
+// names a format with a signature that data lacks.
+func VerifyExtension(filename string, data []byte) (ext, detected string, mismatch bool) {
+	ext = strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	detected = DetectFormat(data)
+	want := fileFormat(filename)
+	switch detected {
+	case "":
+		return ext, detected, signatureFormats[want]
+	case "encrypted-ooxml":
+		return ext, detected, want != "docx" && want != "xlsx" && want != "pptx"
+	}
+	return ext, detected, want != detected
+}
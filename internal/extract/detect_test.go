@@ -0,0 +1,61 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyExtension(t *testing.T) {
+	pdf := []byte("%PDF-1.7\n")
+	docx := testDOCX(t, `<w:p/>`)
+	encrypted := testEncryptedOOXML(t, docx, "pass", 128)
+	for _, tc := range []struct {
+		filename string
+		data     []byte
+		ext      string
+		detected string
+		mismatch bool
+	}{
+		{"report.pdf", pdf, "pdf", "pdf", false},
+		{"Report.DOCX", docx, "docx", "docx", false},
+		{"report.docx", pdf, "docx", "pdf", true},
+		{"report.pdf", docx, "pdf", "docx", true},
+		{"scan.pdf", []byte("just text"), "pdf", "", true},
+		{"notes.txt", []byte("just text"), "txt", "", false},
+		{"notes.txt", pdf, "txt", "pdf", true},
+		{"secret.xlsx", encrypted, "xlsx", "encrypted-ooxml", false},
+		{"secret.txt", encrypted, "txt", "encrypted-ooxml", true},
+	} {
+		ext, detected, mismatch := VerifyExtension(tc.filename, tc.data)
+		if ext != tc.ext || detected != tc.detected || mismatch != tc.mismatch {
+			t.Errorf("%s with %s content: got %q, %q, %v; want %q, %q, %v",
+				tc.filename, tc.detected, ext, detected, mismatch, tc.ext, tc.detected, tc.mismatch)
+		}
+	}
+}
+
+func TestMacroEnabledOffice(t *testing.T) {
+	docm := testDOCX(t, `<w:p><w:r><w:t>word macros</w:t></w:r></w:p>`, "word/vbaProject.bin", "\xd0\xcf\x11\xe0")
+	xlsm := testXLSX(t, []string{`<row r="1"><c r="A1" t="inlineStr"><is><t>sheet macros</t></is></c></row>`}, "xl/vbaProject.bin", "\xd0\xcf\x11\xe0")
+	pptm := testZip(t, "ppt/presentation.xml", `<p:presentation `+testPresentationNS+`/>`,
+		"ppt/slides/slide1.xml", testSlide("slide macros"), "ppt/vbaProject.bin", "\xd0\xcf\x11\xe0")
+	for _, tc := range []struct {
+		filename string
+		data     []byte
+		want     string
+	}{
+		{"a.docm", docm, "word macros"},
+		{"a.xlsm", xlsm, "sheet macros"},
+		{"a.pptm", pptm, "slide macros"},
+		{"A.DOCM", docm, "word macros"},
+		// the package contents win over a wrong macro-enabled extension
+		{"a.xlsm", pptm, "slide macros"},
+		{"a.pptm", docm, "word macros"},
+		{"a.docm", xlsm, "sheet macros"},
+	} {
+		got, err := ExtractText(tc.filename, tc.data)
+		if err != nil || !strings.Contains(got, tc.want) {
+			t.Errorf("%s: got %q, %v; want %q", tc.filename, got, err, tc.want)
+		}
+	}
+}
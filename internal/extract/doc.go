@@ -0,0 +1,333 @@
+package extract
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// docFibIdent is wIdent of the File Information Block of Word 97-2003 files.
+const docFibIdent = 0xA5EC
+
+// docClxIndex and docPapxIndex are the positions of fcClx/lcbClx and
+// fcPlcfBtePapx/lcbPlcfBtePapx among the FibRgFcLcb pairs.
+const (
+	docClxIndex  = 33
+	docPapxIndex = 13
+)
+
+// sprmPFTtp and sprmPFInnerTtp mark the paragraph that ends a table row, of
+// an outer and of a nested table.
+const (
+	docSprmPFTtp      = 0x2417
+	docSprmPFInnerTtp = 0x244C
+)
+
+var (
+	errDOCCorrupt   = errors.New("doc: corrupt WordDocument stream")
+	errDOCEncrypted = errors.New("doc: encrypted documents are not supported")
+)
+
+// docCyrillicLangs are language ids whose 8-bit text pieces use Windows-1251
+// rather than Windows-1252.
+var docCyrillicLangs = map[uint16]bool{
+	0x0419: true, // Russian
+	0x0422: true, // Ukrainian
+	0x0423: true, // Belarusian
+	0x0402: true, // Bulgarian
+	0x0C1A: true, // Serbian (Cyrillic)
+	0x042F: true, // Macedonian
+	0x043F: true, // Kazakh
+}
+
+// extractDOC reads the main document text of a binary Word 97-2003 file:
+// the piece table (CLX) of the WordDocument stream maps character positions
+// to 8-bit or UTF-16 runs in the stream. Field codes are dropped in favor of
+// their results; paragraph and cell marks become newlines and tabs, and the
+// mark ending a table row a newline.
+func extractDOC(data []byte) (string, error) {
+	c, err := openCFB(data)
+	if err != nil {
+		return "", err
+	}
+	wd, err := c.stream("WordDocument")
+	if err != nil {
+		return "", err
+	}
+	le := binary.LittleEndian
+	if len(wd) < 0x22 || le.Uint16(wd) != docFibIdent {
+		return "", errDOCCorrupt
+	}
+	flags := le.Uint16(wd[0x0A:])
+	if flags&0x0100 != 0 {
+		return "", errDOCEncrypted
+	}
+	ansi := charmap.Windows1252
+	if docCyrillicLangs[le.Uint16(wd[0x06:])] {
+		ansi = charmap.Windows1251
+	}
+
+	// FibBase is followed by length-prefixed arrays of 16-bit, 32-bit and
+	// fc/lcb pair values
+	pos := 32
+	if pos+2 > len(wd) {
+		return "", errDOCCorrupt
+	}
+	pos += 2 + 2*int(le.Uint16(wd[pos:]))
+	if pos+2 > len(wd) {
+		return "", errDOCCorrupt
+	}
+	cslw := int(le.Uint16(wd[pos:]))
+	rgLw := pos + 2
+	if cslw < 4 || rgLw+4*cslw+2 > len(wd) {
+		return "", errDOCCorrupt
+	}
+	ccpText := int(le.Uint32(wd[rgLw+12:]))
+	pos = rgLw + 4*cslw
+	cbRgFcLcb := int(le.Uint16(wd[pos:]))
+	rgFcLcb := pos + 2
+	if cbRgFcLcb <= docClxIndex || rgFcLcb+8*(docClxIndex+1) > len(wd) {
+		return "", errDOCCorrupt
+	}
+	fcClx := int(le.Uint32(wd[rgFcLcb+8*docClxIndex:]))
+	lcbClx := int(le.Uint32(wd[rgFcLcb+8*docClxIndex+4:]))
+
+	tableName := "0Table"
+	if flags&0x0200 != 0 {
+		tableName = "1Table"
+	}
+	table, err := c.stream(tableName)
+	if err != nil {
+		return "", err
+	}
+	if lcbClx == 0 || fcClx < 0 || fcClx+lcbClx > len(table) {
+		return "", errDOCCorrupt
+	}
+	// without readable paragraph properties every mark is taken for a cell
+	var rowEnds map[uint32]bool
+	fcPapx := int(le.Uint32(wd[rgFcLcb+8*docPapxIndex:]))
+	lcbPapx := int(le.Uint32(wd[rgFcLcb+8*docPapxIndex+4:]))
+	if fcPapx >= 0 && lcbPapx > 0 && fcPapx+lcbPapx <= len(table) {
+		rowEnds = docRowEnds(wd, table[fcPapx:fcPapx+lcbPapx])
+	}
+	raw, rows, err := docPieces(wd, table[fcClx:fcClx+lcbClx], ccpText, ansi, rowEnds)
+	if err != nil {
+		return "", err
+	}
+	return docCleanText(raw, rows), nil
+}
+
+// docRowEnds reads the paragraph properties (PAPX) pages that plcf, a
+// PlcBtePapx, points to and returns the file offsets just past the paragraphs
+// that end a table row.
+func docRowEnds(wd, plcf []byte) map[uint32]bool {
+	le := binary.LittleEndian
+	const page = 512
+	ends := make(map[uint32]bool)
+	// n+1 file offsets, then n 4-byte page numbers
+	n := (len(plcf) - 4) / 8
+	for k := 0; k < n; k++ {
+		pn := int(le.Uint32(plcf[4*(n+1)+4*k:]) & 0x3FFFFF)
+		if (pn+1)*page > len(wd) {
+			continue
+		}
+		fkp := wd[pn*page : (pn+1)*page]
+		// crun paragraph end offsets after the first start, then 13-byte
+		// BxPap entries whose first byte is the word offset of the PAPX
+		crun := int(fkp[page-1])
+		if 4*(crun+1)+13*crun > page-1 {
+			continue
+		}
+		for i := 0; i < crun; i++ {
+			off := 2 * int(fkp[4*(crun+1)+13*i])
+			if off == 0 || off >= page-1 {
+				continue
+			}
+			size := 2*int(fkp[off]) - 1
+			grpprl := fkp[off+1:]
+			if fkp[off] == 0 {
+				size, grpprl = 2*int(fkp[off+1]), fkp[off+2:]
+			}
+			if size < 2 || size > len(grpprl) {
+				continue
+			}
+			// the style index, then the sprms
+			if docTableRowEnd(grpprl[2:size]) {
+				ends[le.Uint32(fkp[4*(i+1):])] = true
+			}
+		}
+	}
+	return ends
+}
+
+// docTableRowEnd reports whether the sprms in grpprl set sprmPFTtp or
+// sprmPFInnerTtp.
+func docTableRowEnd(grpprl []byte) bool {
+	le := binary.LittleEndian
+	for i := 0; i+2 <= len(grpprl); {
+		sprm := le.Uint16(grpprl[i:])
+		i += 2
+		var size int
+		switch sprm >> 13 {
+		case 0, 1:
+			size = 1
+		case 2, 4, 5:
+			size = 2
+		case 3:
+			size = 4
+		case 7:
+			size = 3
+		default:
+			// variable: a size byte, or two for sprmTDefTable
+			if i >= len(grpprl) {
+				return false
+			}
+			size = 1 + int(grpprl[i])
+			if sprm == 0xD608 && i+1 < len(grpprl) {
+				size = 1 + int(le.Uint16(grpprl[i:]))
+			}
+		}
+		if i+size > len(grpprl) {
+			return false
+		}
+		if (sprm == docSprmPFTtp || sprm == docSprmPFInnerTtp) && grpprl[i] != 0 {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// docPieces assembles the first ccp characters from the piece table in clx.
+// It also returns the character positions whose characters end a paragraph
+// at one of the file offsets in rowEnds.
+func docPieces(wd, clx []byte, ccp int, ansi *charmap.Charmap, rowEnds map[uint32]bool) (string, map[int]bool, error) {
+	le := binary.LittleEndian
+	// skip Prc entries (property modifiers) up to the Pcdt
+	i := 0
+	for i < len(clx) && clx[i] == 0x01 {
+		if i+3 > len(clx) {
+			return "", nil, errDOCCorrupt
+		}
+		// cbGrpprl is signed in the spec but never negative
+		i += 3 + int(le.Uint16(clx[i+1:]))
+	}
+	if i+5 > len(clx) || clx[i] != 0x02 {
+		return "", nil, errDOCCorrupt
+	}
+	plc := clx[i+5:]
+	if lcb := int(le.Uint32(clx[i+1:])); lcb < len(plc) {
+		plc = plc[:lcb]
+	}
+	// PlcPcd: n+1 character positions, then n 8-byte piece descriptors
+	n := (len(plc) - 4) / 12
+	if n <= 0 {
+		return "", nil, errDOCCorrupt
+	}
+	var b strings.Builder
+	rows := make(map[int]bool)
+	// characters written so far, the position of the next one
+	pos := 0
+	for k := 0; k < n && ccp > 0; k++ {
+		start := int(le.Uint32(plc[4*k:]))
+		end := int(le.Uint32(plc[4*(k+1):]))
+		if end <= start {
+			continue
+		}
+		count := min(end-start, ccp)
+		ccp -= count
+		pos += count
+		first := pos - count
+		fc := le.Uint32(plc[4*(n+1)+8*k+2:])
+		if fc&0x40000000 != 0 {
+			// compressed: one 8-bit character per position
+			off := int(fc&0x3FFFFFFF) / 2
+			if off+count > len(wd) {
+				return "", nil, errDOCCorrupt
+			}
+			for j := 0; j < count; j++ {
+				if rowEnds[uint32(off+j+1)] {
+					rows[first+j] = true
+				}
+			}
+			s, err := ansi.NewDecoder().Bytes(wd[off : off+count])
+			if err != nil {
+				return "", nil, err
+			}
+			b.Write(s)
+			continue
+		}
+		off := int(fc)
+		if off+2*count > len(wd) {
+			return "", nil, errDOCCorrupt
+		}
+		for j := 0; j < count; j++ {
+			if rowEnds[uint32(off+2*j+2)] {
+				rows[first+j] = true
+			}
+		}
+		u := make([]uint16, count)
+		for j := range u {
+			u[j] = le.Uint16(wd[off+2*j:])
+		}
+		b.WriteString(string(utf16.Decode(u)))
+	}
+	return b.String(), rows, nil
+}
+
+// docCleanText maps Word's control characters to plain text. Between a field
+// begin (0x13) and its separator (0x14) lies the field code, which is dropped;
+// the result up to the field end (0x15) is kept. rowEnds holds the character
+// positions of the marks that end table rows rather than cells.
+func docCleanText(s string, rowEnds map[int]bool) string {
+	var b strings.Builder
+	// for each open field, whether its code part is still being read
+	var fields []bool
+	// the character position of r, which counts UTF-16 code units
+	cp := -1
+	for _, r := range s {
+		cp++
+		if r > 0xFFFF {
+			cp++
+		}
+		switch r {
+		case 0x13:
+			fields = append(fields, true)
+			continue
+		case 0x14:
+			if len(fields) > 0 {
+				fields[len(fields)-1] = false
+			}
+			continue
+		case 0x15:
+			if len(fields) > 0 {
+				fields = fields[:len(fields)-1]
+			}
+			continue
+		}
+		if len(fields) > 0 && fields[len(fields)-1] {
+			continue
+		}
+		if r == 0x07 && rowEnds[cp] {
+			b.WriteByte('\n')
+			continue
+		}
+		switch r {
+		case '\r', 0x0B, 0x0C:
+			b.WriteByte('\n')
+		case 0x07:
+			// cell end marks
+			b.WriteByte('\t')
+		case 0x1E:
+			b.WriteByte('-')
+		case 0x1F, 0x01, 0x02, 0x05, 0x08:
+			// optional hyphen, pictures, note and comment references
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package extract
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestDOCPiecesNegativeGrpprl(t *testing.T) {
+	for _, cb := range []uint16{0xFFFC, 0xFFFD, 0xFFFF} {
+		clx := []byte{0x01, byte(cb), byte(cb >> 8), 0x02, 0, 0, 0, 0}
+		if _, _, err := docPieces(nil, clx, 10, charmap.Windows1252, nil); !errors.Is(err, errDOCCorrupt) {
+			t.Errorf("cbGrpprl %#x: err = %v, want errDOCCorrupt", cb, err)
+		}
+	}
+}
+
+func TestOpenCFBMiniSectorShift(t *testing.T) {
+	data := make([]byte, 1024)
+	copy(data, cfbMagic)
+	binary.LittleEndian.PutUint16(data[0x1E:], 9)
+	for _, shift := range []uint16{0, 5, 7, 63} {
+		binary.LittleEndian.PutUint16(data[0x20:], shift)
+		if _, err := openCFB(data); !errors.Is(err, errCFBCorrupt) {
+			t.Errorf("mini sector shift %d: err = %v, want errCFBCorrupt", shift, err)
+		}
+	}
+}
+
+func TestOpenCFBCycles(t *testing.T) {
+	le := binary.LittleEndian
+	const fat = 512 // offset of sector 0, the FAT
+	for _, tc := range []struct {
+		name  string
+		patch func(data []byte)
+	}{
+		{"self-linked DIFAT sector", func(data []byte) {
+			le.PutUint32(data[0x44:], 0)
+			le.PutUint32(data[0x48:], 0xFFFFFFFF)
+			le.PutUint32(data[fat+508:], 0)
+		}},
+		{"directory chain loop", func(data []byte) { le.PutUint32(data[fat+4:], 1) }},
+		{"stream chain loop", func(data []byte) { le.PutUint32(data[fat+12:], 3) }},
+	} {
+		data := testCFB(t, "Data", "x", "Other", "y")
+		tc.patch(data)
+		c, err := openCFB(data)
+		if err == nil {
+			_, err = c.stream("Other")
+		}
+		if !errors.Is(err, errCFBCorrupt) {
+			t.Errorf("%s: err = %v, want errCFBCorrupt", tc.name, err)
+		}
+		if DetectFormat(data) == "doc" {
+			t.Errorf("%s: detected as doc", tc.name)
+		}
+	}
+}
+
+// testDOC builds a Word 97-2003 file whose text is one 8-bit piece. Every
+// '\n' in text becomes a cell mark whose paragraph ends a table row, which
+// the paragraph properties tell with sprmPFTtp.
+func testDOC(t *testing.T, text string) []byte {
+	t.Helper()
+	le := binary.LittleEndian
+	const page = 512
+	// sector 0 holds the FIB, sector 1 the text and sector 2 the PAPX page
+	wd := make([]byte, 3*page)
+	le.PutUint16(wd, docFibIdent)
+	le.PutUint16(wd[34:], 4) // no 16-bit values, four 32-bit ones
+	le.PutUint32(wd[48:], uint32(len(text)))
+	le.PutUint16(wd[52:], docClxIndex+1)
+	rgFcLcb := 54
+	copy(wd[page:], strings.ReplaceAll(text, "\n", "\x07"))
+
+	// each paragraph ends after a paragraph or cell mark
+	fkp := wd[2*page:]
+	var ends []int
+	var rowEnds []bool
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c == '\r' || c == 0x07 || c == '\n' {
+			ends = append(ends, page+i+1)
+			rowEnds = append(rowEnds, c == '\n')
+		}
+	}
+	crun := len(ends)
+	le.PutUint32(fkp, page)
+	for i, end := range ends {
+		le.PutUint32(fkp[4*(i+1):], uint32(end))
+		if rowEnds[i] {
+			fkp[4*(crun+1)+13*i] = (page - 8) / 2
+		}
+	}
+	// the PAPX: cb, then istd 0 and sprmPFTtp 1
+	copy(fkp[page-8:], []byte{3, 0, 0, 0x17, 0x24, 1})
+	fkp[page-1] = byte(crun)
+
+	// the CLX, one piece of 8-bit text, then the PlcBtePapx
+	table := []byte{0x02}
+	table = le.AppendUint32(table, 16)
+	table = le.AppendUint32(table, 0)
+	table = le.AppendUint32(table, uint32(len(text)))
+	table = le.AppendUint16(table, 0)
+	table = le.AppendUint32(table, 2*page|0x40000000)
+	table = le.AppendUint16(table, 0)
+	le.PutUint32(wd[rgFcLcb+8*docClxIndex+4:], uint32(len(table)))
+	le.PutUint32(wd[rgFcLcb+8*docPapxIndex:], uint32(len(table)))
+	le.PutUint32(wd[rgFcLcb+8*docPapxIndex+4:], 12)
+	table = le.AppendUint32(table, page)
+	table = le.AppendUint32(table, uint32(page+len(text)))
+	table = le.AppendUint32(table, 2)
+	return testCFB(t, "WordDocument", string(wd), "0Table", string(table))
+}
+
+func TestDOCTables(t *testing.T) {
+	for _, tc := range []struct {
+		name, text, want string
+	}{
+		{"paragraphs", "one\rtwo\r", "one\ntwo\n"},
+		{"table", "a\x07b\x07\nc\x07d\x07\nafter\r", "a\tb\t\nc\td\t\nafter\n"},
+		{"empty cells", "a\x07\x07c\x07\n\x07\x07\x07\n", "a\t\tc\t\n\t\t\t\n"},
+	} {
+		got, err := ExtractText("a.doc", testDOC(t, tc.text))
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,818 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// nsDrawingML is the DrawingML namespace, whose a:t elements hold shape text.
+const nsDrawingML = "http://schemas.openxmlformats.org/drawingml/2006/main"
+
+// docxDoc holds the package parts and per-document state used while walking a
+// DOCX body.
+type docxDoc struct {
+	ctx   context.Context // for imported sub-documents
+	opts  Options
+	files map[string]*zip.File // nil when the package could not be indexed
+	// part is the package part being walked; its relationships are loaded on
+	// first use
+	part string
+	rels map[string]opcRel
+
+	styles    *docxStyles    // nil when the package could not be indexed
+	numbering *docxNumbering // nil unless list markers are requested
+	// relationship ids of embedded pictures, in document order
+	imageRels []string
+	// set once a walked part has text, even text the options leave out, so
+	// that such a document is not mistaken for a scan
+	hasText bool
+	// header and footer references of the section properties, in document order
+	headerRefs, footerRefs []docxPartRef
+	sections               int
+	// document default w:lang, runs in other languages are annotated
+	defaultLang string
+
+	// nil unless notes are requested
+	footnotes, endnotes *docxNotes
+}
+
+func extractDOCX(ctx context.Context, data []byte, opts Options) (string, error) {
+	d := &docxDoc{ctx: ctx, opts: opts, part: "word/document.xml"}
+	if err := opts.TrackChanges.validate(); err != nil {
+		return "", err
+	}
+	if bytes.HasPrefix(data, cfbMagic) {
+		// a password-protected package is an OLE2 container around the zip
+		c, err := openCFB(data)
+		if err != nil {
+			return "", err
+		}
+		if !c.hasStream("EncryptedPackage") {
+			if c.hasStream("WordDocument") {
+				// a Word 97-2003 file saved with the wrong extension
+				return extractDOC(data)
+			}
+			return "", errors.New("not a docx package: legacy OLE2 document")
+		}
+		if data, err = decryptOOXML(data, opts.DOCXPassword); err != nil {
+			return "", err
+		}
+	}
+	if isBareDocumentXML(data) {
+		// the main part uploaded on its own instead of the whole package
+		return d.text(bytes.NewReader(data))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		if !opts.BestEffort {
+			return "", err
+		}
+		// the central directory may be damaged while the entry itself is intact
+		doc, scanErr := scanZipEntry(data, "word/document.xml", opts.maxDecompressedBytes())
+		if errors.Is(scanErr, ErrDecompressionLimit) {
+			return "", scanErr
+		}
+		if scanErr != nil {
+			return "", err
+		}
+		addWarning(ctx, "damaged_zip: read word/document.xml from its local header ("+err.Error()+")")
+		return d.text(bytes.NewReader(doc))
+	}
+	d.files = zipIndex(zr)
+	docFile, ok := d.files["word/document.xml"]
+	if !ok {
+		return "", errors.New("document.xml not found in docx")
+	}
+	d.styles = loadDocxStyles(d.files)
+	if opts.AnnotateLanguage {
+		d.defaultLang = d.styles.runProps("", "", docxRPr{}).lang
+	}
+	if opts.IncludeFootnotes {
+		if d.footnotes, err = d.loadNotes("word/footnotes.xml", "footnote"); err != nil {
+			return "", err
+		}
+		if d.endnotes, err = d.loadNotes("word/endnotes.xml", "endnote"); err != nil {
+			return "", err
+		}
+	}
+	if opts.ListMarkers {
+		if d.numbering, err = loadDocxNumbering(d.files); err != nil {
+			return "", err
+		}
+	}
+	rc, err := docFile.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	text, err := d.text(rc)
+	if err != nil {
+		return "", err
+	}
+	if opts.IncludeHeadersFooters {
+		headers, err := d.headerFooterText(d.headerRefs, "word/header")
+		if err != nil {
+			return "", err
+		}
+		footers, err := d.headerFooterText(d.footerRefs, "word/footer")
+		if err != nil {
+			return "", err
+		}
+		if headers != "" {
+			text = headers + "\n" + text
+		}
+		if footers != "" {
+			text += "\n" + footers
+		}
+	}
+	text += d.footnotes.render("Footnotes", strconv.Itoa) + d.endnotes.render("Endnotes", lowerRoman)
+	if isBlankText(text) && !d.hasText {
+		if images := d.images(); len(images) > 0 {
+			if !opts.OCR {
+				return "", ErrImageOnly
+			}
+			return ocrImages(ctx, images, opts)
+		}
+	}
+	return text, nil
+}
+
+// skipHasText skips the rest of the element whose start was just read, like
+// xml.Decoder.Skip, and reports whether it held any text other than
+// whitespace.
+func skipHasText(dec *xml.Decoder) (bool, error) {
+	hasText := false
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return false, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			hasText = hasText || len(bytes.TrimSpace(tok)) > 0
+		}
+	}
+	return hasText, nil
+}
+
+// isBareDocumentXML reports whether data is an unpackaged word/document.xml
+// rather than a zip.
+func isBareDocumentXML(data []byte) bool {
+	data = bytes.TrimLeft(bytes.TrimPrefix(data, utf8BOM), " \t\r\n")
+	return bytes.HasPrefix(data, []byte("<?xml")) || bytes.HasPrefix(data, []byte("<w:document"))
+}
+
+// relPart resolves a relationship of the walked part to a package part name.
+func (d *docxDoc) relPart(id string) (string, bool) {
+	if d.rels == nil {
+		d.rels = readRels(d.files, d.part)
+	}
+	rel, ok := d.rels[id]
+	if !ok || rel.TargetMode == "External" {
+		return "", false
+	}
+	return relTarget(d.part, rel.Target), true
+}
+
+// linkTarget returns the URL a w:hyperlink relationship points to, or "" for
+// targets within the package.
+func (d *docxDoc) linkTarget(id string) string {
+	if d.files == nil || id == "" {
+		return ""
+	}
+	if d.rels == nil {
+		d.rels = readRels(d.files, d.part)
+	}
+	rel := d.rels[id]
+	if rel.TargetMode != "External" {
+		return ""
+	}
+	return rel.Target
+}
+
+// altChunkText extracts the content a w:altChunk imports from another part of
+// the package, which Word stores as HTML, RTF, plain text or a nested DOCX.
+// Unreadable or unsupported chunks, and those in a format Options.EnabledFormats
+// leaves out, yield ""; only nesting past Options.MaxRecursionDepth is an error.
+func (d *docxDoc) altChunkText(id string) (string, error) {
+	name, ok := d.relPart(id)
+	if !ok {
+		return "", nil
+	}
+	f, ok := d.files[name]
+	if !ok {
+		return "", nil
+	}
+	ctx, err := enterNested(d.ctx, d.opts)
+	if err != nil {
+		return "", err
+	}
+	data, err := readZipFile(f)
+	if err != nil {
+		return "", nil
+	}
+	var format string
+	switch ext := strings.ToLower(path.Ext(name)); {
+	case ext == ".docx" || bytes.HasPrefix(data, []byte("PK")):
+		format = "docx"
+	case ext == ".rtf" || bytes.HasPrefix(data, []byte("{\\rtf")):
+		format = "rtf"
+	case ext == ".htm" || ext == ".html" || ext == ".xhtml":
+		format = "html"
+	case ext == ".txt":
+		format = "txt"
+	default:
+		return "", nil
+	}
+	text, _, err := extractFormat(ctx, format, data, d.opts)
+	if errors.Is(err, ErrMaxDepthExceeded) {
+		return "", err
+	}
+	if err != nil || strings.TrimSpace(text) == "" {
+		return "", nil
+	}
+	return strings.TrimRight(text, "\n") + "\n", nil
+}
+
+// images returns the pictures referenced from the document body, or every
+// part under word/media when the body references none.
+func (d *docxDoc) images() [][]byte {
+	var names []string
+	for _, id := range d.imageRels {
+		if name, ok := d.relPart(id); ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		for name := range d.files {
+			if strings.HasPrefix(name, "word/media/") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+	}
+	var images [][]byte
+	for _, name := range names {
+		f, ok := d.files[name]
+		if !ok {
+			continue
+		}
+		if data, err := readZipFile(f); err == nil {
+			images = append(images, data)
+		}
+	}
+	return images
+}
+
+// docxPartRef is a w:headerReference or w:footerReference of a section.
+type docxPartRef struct {
+	section int
+	kind    string // first, default or even
+	id      string
+}
+
+// docxPartKinds orders the header and footer variants of a section the way
+// they appear in print: the first page, then the odd and even pages.
+var docxPartKinds = map[string]int{"first": 0, "default": 1, "even": 2}
+
+// headerFooterText extracts the headers or footers a document references,
+// section by section, or every part named prefix*.xml when the sections
+// reference none. A part shared by several sections or variants, or one
+// repeating the text of another, is included once.
+func (d *docxDoc) headerFooterText(refs []docxPartRef, prefix string) (string, error) {
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].section != refs[j].section {
+			return refs[i].section < refs[j].section
+		}
+		return docxPartKinds[refs[i].kind] < docxPartKinds[refs[j].kind]
+	})
+	var names []string
+	for _, ref := range refs {
+		if name, ok := d.relPart(ref.id); ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		for name := range d.files {
+			if strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ".xml") {
+				names = append(names, name)
+			}
+		}
+		// header2.xml before header10.xml
+		sort.Slice(names, func(i, j int) bool {
+			if len(names[i]) != len(names[j]) {
+				return len(names[i]) < len(names[j])
+			}
+			return names[i] < names[j]
+		})
+	}
+	seen, seenText := make(map[string]bool), make(map[string]bool)
+	var b strings.Builder
+	for _, name := range names {
+		f, ok := d.files[name]
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		// a header is walked like the body; its own rels resolve its links
+		sub := &docxDoc{ctx: d.ctx, opts: d.opts, files: d.files, part: name, styles: d.styles, defaultLang: d.defaultLang}
+		text, err := sub.text(rc)
+		rc.Close()
+		if err != nil {
+			return "", err
+		}
+		text = strings.TrimSpace(text)
+		if text == "" || seenText[text] {
+			continue
+		}
+		seenText[text] = true
+		b.WriteString(text + "\n")
+	}
+	return b.String(), nil
+}
+
+// docxNotes holds the footnotes or endnotes of a document.
+type docxNotes struct {
+	texts map[string]string // note id -> text
+	// note ids in order of first reference; the rendered number of a note is
+	// its position here, whatever id Word stored
+	order []string
+	nums  map[string]int
+}
+
+// loadNotes reads the notes of a footnotes or endnotes part, skipping the
+// separator and continuation pseudo-notes. A missing part yields no notes.
+func (d *docxDoc) loadNotes(part, elem string) (*docxNotes, error) {
+	notes := &docxNotes{texts: make(map[string]string), nums: make(map[string]int)}
+	f, ok := d.files[part]
+	if !ok {
+		return notes, nil
+	}
+	raw, err := readZipFile(f)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Notes []struct {
+			XMLName xml.Name
+			ID      string `xml:"id,attr"`
+			Type    string `xml:"type,attr"`
+			Inner   []byte `xml:",innerxml"`
+		} `xml:",any"`
+	}
+	if err := xml.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	// note bodies are walked like the document body, without nested notes
+	sub := &docxDoc{ctx: d.ctx, opts: d.opts, files: d.files, part: part, styles: d.styles, defaultLang: d.defaultLang}
+	for _, n := range doc.Notes {
+		if n.XMLName.Local != elem || (n.Type != "" && n.Type != "normal") {
+			continue
+		}
+		text, err := sub.text(bytes.NewReader(n.Inner))
+		if err != nil {
+			return nil, err
+		}
+		notes.texts[n.ID] = strings.TrimSpace(text)
+	}
+	return notes, nil
+}
+
+// ref returns the inline marker for a reference to note id, numbering notes
+// 1..n in order of first reference and rendering the number with format.
+func (n *docxNotes) ref(id string, format func(int) string) string {
+	if _, ok := n.texts[id]; !ok {
+		return ""
+	}
+	num, ok := n.nums[id]
+	if !ok {
+		n.order = append(n.order, id)
+		num = len(n.order)
+		n.nums[id] = num
+	}
+	return "[" + format(num) + "]"
+}
+
+// render lists the referenced notes in their rendered number order under a
+// [heading] line.
+func (n *docxNotes) render(heading string, format func(int) string) string {
+	if n == nil || len(n.order) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n[" + heading + "]\n")
+	for i, id := range n.order {
+		b.WriteString("[" + format(i+1) + "] " + n.texts[id] + "\n")
+	}
+	return b.String()
+}
+
+// lowerRoman renders endnote numbers as Word does by default: i, ii, iii.
+func lowerRoman(n int) string { return strings.ToLower(romanNumeral(n)) }
+
+// text walks WordprocessingML from r and returns its text.
+func (d *docxDoc) text(r io.Reader) (string, error) {
+	dec := xml.NewDecoder(r)
+	var b bytes.Buffer
+	type element struct{ space, local string }
+	var stack []element
+	// output offsets where the currently open paragraphs start; text boxes nest paragraphs
+	var paraStarts []int
+	// list membership of the current paragraph, from its own properties or style
+	var (
+		pStyle   string
+		numPr    docxNumPr
+		hasNumPr bool
+	)
+	// style and direct properties of the current run
+	var (
+		runStyle string
+		runRPr   docxRPr
+	)
+	// set when the current paragraph had hidden runs left out
+	var hiddenSkipped bool
+	// language of the open [lang:..] marker, "" for the default
+	var openLang string
+	// switchLang opens or closes language markers before run content is written
+	switchLang := func(lang string) {
+		if !d.opts.AnnotateLanguage || lang == openLang {
+			return
+		}
+		if openLang != "" {
+			b.WriteString("[/lang]")
+		}
+		if lang != "" {
+			b.WriteString("[lang:" + lang + "]")
+		}
+		openLang = lang
+	}
+	// tracked change the open {+ or {- marker belongs to, "" when none is open
+	var openRev string
+	switchRev := func(rev string) {
+		if rev == openRev {
+			return
+		}
+		// language markers nest inside change markers
+		switchLang("")
+		switch openRev {
+		case "ins":
+			b.WriteString("+}")
+		case "del":
+			b.WriteString("-}")
+		}
+		switch rev {
+		case "ins":
+			b.WriteString("{+")
+		case "del":
+			b.WriteString("{-")
+		}
+		openRev = rev
+	}
+	// writeRun writes run content, applying the track changes mode to content
+	// inside w:ins/w:moveTo or w:del/w:moveFrom
+	writeRun := func(s string) {
+		if !d.opts.IncludeHiddenText && d.styles.runProps(pStyle, runStyle, runRPr).hidden {
+			hiddenSkipped = true
+			return
+		}
+		rev := ""
+		for i := len(stack) - 1; i >= 0 && rev == ""; i-- {
+			switch stack[i].local {
+			case "ins", "moveTo":
+				rev = "ins"
+			case "del", "moveFrom":
+				rev = "del"
+			}
+		}
+		switch d.opts.TrackChanges {
+		case TrackChangesOriginal:
+			if rev == "ins" {
+				return
+			}
+		case TrackChangesMarkup:
+			switchRev(rev)
+		case TrackChangesInsertions:
+			if rev != "ins" {
+				return
+			}
+		default:
+			if rev == "del" {
+				return
+			}
+		}
+		lang := ""
+		if d.opts.AnnotateLanguage {
+			lang = d.styles.runProps(pStyle, runStyle, runRPr).lang
+			if strings.EqualFold(lang, d.defaultLang) {
+				lang = ""
+			}
+		}
+		switchLang(lang)
+		b.WriteString(s)
+	}
+	// set by a paragraph-level w:sectPr: the paragraph is the last of its section
+	var sectionEnd bool
+	// open tables, and the column span of each open cell. Cells of a
+	// top-level table are separated by tabs and its rows end with a newline;
+	// within a cell, paragraphs and nested tables run on with spaces so that
+	// every row stays on one line.
+	var (
+		tableDepth int
+		cellSpans  []int
+	)
+	trimSpaces := func() { b.Truncate(len(bytes.TrimRight(b.Bytes(), " "))) }
+	// the target of the open w:hyperlink and where its text starts, for
+	// Options.IncludeLinkURLs
+	var (
+		linkURL   string
+		linkStart int
+	)
+	// parent reports whether the enclosing elements, innermost first, are names
+	parent := func(names ...string) bool {
+		if len(stack) < len(names)+1 {
+			return false
+		}
+		for i, name := range names {
+			if stack[len(stack)-2-i].local != name {
+				return false
+			}
+		}
+		return true
+	}
+
+walk:
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, element{space: t.Name.Space, local: t.Name.Local})
+			// breaks and tabs only count inside a run: w:tab also appears in
+			// w:pPr/w:tabs as a tab stop definition, which is not content
+			inRun := len(stack) > 1 && stack[len(stack)-2].local == "r"
+			// mc:Fallback repeats the mc:Choice before it for older readers,
+			// typically a text box as VML next to its DrawingML original
+			skip := t.Name.Local == "Fallback" && parent("AlternateContent")
+			// field instructions such as MERGEFIELD Name; the field result
+			// after w:fldChar separate is the visible text
+			skip = skip || t.Name.Local == "instrText" || t.Name.Local == "delInstrText"
+			textbox := d.opts.ExcludeTextboxes && (t.Name.Local == "txbxContent" || (t.Name.Local == "t" && t.Name.Space == nsDrawingML))
+			if textbox {
+				// left out, but still text for the image-only check
+				hasText, err := skipHasText(dec)
+				if err != nil {
+					return "", err
+				}
+				d.hasText = d.hasText || hasText
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			if skip {
+				if err := dec.Skip(); err != nil {
+					return "", err
+				}
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			switch t.Name.Local {
+			case "p":
+				paraStarts = append(paraStarts, b.Len())
+				pStyle, numPr, hasNumPr = "", docxNumPr{}, false
+				hiddenSkipped = false
+			case "sectPr":
+				d.sections++
+				if parent("pPr", "p") {
+					sectionEnd = true
+				}
+			case "headerReference", "footerReference":
+				if parent("sectPr") {
+					ref := docxPartRef{section: d.sections, kind: xmlAttr(t, "type"), id: xmlAttr(t, "id")}
+					if t.Name.Local == "headerReference" {
+						d.headerRefs = append(d.headerRefs, ref)
+					} else {
+						d.footerRefs = append(d.footerRefs, ref)
+					}
+				}
+			case "tbl":
+				tableDepth++
+			case "tc":
+				cellSpans = append(cellSpans, 1)
+			case "gridSpan":
+				if parent("tcPr", "tc") && len(cellSpans) > 0 {
+					if n, err := strconv.Atoi(xmlAttr(t, "val")); err == nil && n > 1 {
+						cellSpans[len(cellSpans)-1] = n
+					}
+				}
+			case "r":
+				runStyle, runRPr = "", docxRPr{}
+			case "rStyle":
+				if parent("rPr", "r") {
+					runStyle = xmlAttr(t, "val")
+				}
+			case "lang":
+				if parent("rPr", "r") {
+					runRPr.Lang = &xmlVal{Val: xmlAttr(t, "val")}
+				}
+			case "vanish":
+				if parent("rPr", "r") {
+					runRPr.Vanish = &xmlVal{Val: xmlAttr(t, "val")}
+				}
+			case "pStyle":
+				if parent("pPr", "p") {
+					pStyle = xmlAttr(t, "val")
+				}
+			case "numId", "ilvl":
+				// numPr inside w:pPrChange describes the old formatting, so the
+				// full parent chain is checked
+				if parent("numPr", "pPr", "p") {
+					hasNumPr = true
+					if t.Name.Local == "numId" {
+						numPr.numID = xmlAttr(t, "val")
+					} else {
+						numPr.ilvl, _ = strconv.Atoi(xmlAttr(t, "val"))
+					}
+				}
+			case "hyperlink":
+				if d.opts.IncludeLinkURLs {
+					linkURL, linkStart = d.linkTarget(xmlAttr(t, "id")), b.Len()
+				}
+			case "bookmarkStart":
+				// _GoBack is Word's internal "last edit" bookmark, not an anchor
+				if name := xmlAttr(t, "name"); d.opts.AnnotateBookmarks && name != "" && name != "_GoBack" {
+					b.WriteString("[#" + name + "]")
+				}
+			case "blip", "imagedata":
+				// a:blip in DrawingML pictures, v:imagedata in legacy VML ones
+				id := xmlAttr(t, "embed")
+				if id == "" {
+					id = xmlAttr(t, "id")
+				}
+				if id != "" {
+					d.imageRels = append(d.imageRels, id)
+				}
+			case "altChunk":
+				if d.files != nil {
+					chunk, err := d.altChunkText(xmlAttr(t, "id"))
+					if err != nil {
+						return "", err
+					}
+					b.WriteString(chunk)
+				}
+			case "footnoteReference":
+				if d.footnotes != nil {
+					b.WriteString(d.footnotes.ref(xmlAttr(t, "id"), strconv.Itoa))
+				}
+			case "endnoteReference":
+				if d.endnotes != nil {
+					b.WriteString(d.endnotes.ref(xmlAttr(t, "id"), lowerRoman))
+				}
+			case "br":
+				if inRun && tableDepth > 0 {
+					writeRun(" ")
+				} else if inRun {
+					writeRun("\n")
+				}
+			case "tab":
+				if inRun {
+					writeRun("\t")
+				}
+			case "t", "delText":
+				// read text until end of this element; adjacent runs of one word are
+				// concatenated as-is, nothing is inserted between them. The text is
+				// kept verbatim whether or not xml:space="preserve" is set: not every
+				// generator sets it on runs whose spaces separate words.
+				var txt strings.Builder
+				for {
+					tok2, err2 := dec.Token()
+					if err2 == io.EOF {
+						break
+					}
+					if err2 != nil {
+						return "", err2
+					}
+					if char, ok := tok2.(xml.CharData); ok {
+						txt.WriteString(string(char))
+						continue
+					}
+					if end, ok := tok2.(xml.EndElement); ok && end.Name.Local == t.Name.Local {
+						break
+					}
+				}
+				if strings.TrimSpace(txt.String()) != "" {
+					d.hasText = true
+				}
+				writeRun(txt.String())
+				// the end element was consumed above
+				stack = stack[:len(stack)-1]
+			}
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			switch t.Name.Local {
+			case "pPr":
+				if d.numbering != nil && len(stack) > 0 && stack[len(stack)-1].local == "p" {
+					np := numPr
+					if styleNP, _ := d.styles.numPr(pStyle); !hasNumPr {
+						np = styleNP
+					} else if np.numID == "" {
+						// a paragraph may set only the level of its style's list
+						np.numID = styleNP.numID
+					}
+					b.WriteString(d.numbering.marker(np))
+				}
+			case "p":
+				// markers do not span paragraphs
+				switchRev("")
+				switchLang("")
+				start := 0
+				if len(paraStarts) > 0 {
+					start = paraStarts[len(paraStarts)-1]
+					paraStarts = paraStarts[:len(paraStarts)-1]
+				}
+				empty := len(bytes.TrimSpace(b.Bytes()[start:])) == 0
+				// a paragraph of hidden text disappears along with it, and so do
+				// paragraphs without insertions when only those are wanted
+				drop := hiddenSkipped || tableDepth > 0 || d.opts.TrackChanges == TrackChangesInsertions
+				if (d.opts.DropEmptyParagraphs || drop) && empty {
+					b.Truncate(start)
+				} else if tableDepth > 0 {
+					b.WriteByte(' ')
+				} else {
+					b.WriteByte('\n')
+					if d.opts.DistinguishBreaks {
+						b.WriteByte('\n')
+					}
+				}
+				if sectionEnd && d.opts.FirstUnitOnly {
+					break walk
+				}
+			case "hyperlink":
+				// a link whose text is its address is left alone
+				if text := string(bytes.TrimSpace(b.Bytes()[min(linkStart, b.Len()):])); linkURL != "" && text != "" && text != linkURL {
+					b.WriteString(" (" + linkURL + ")")
+				}
+				linkURL = ""
+			case "tc":
+				trimSpaces()
+				span := 1
+				if len(cellSpans) > 0 {
+					span = cellSpans[len(cellSpans)-1]
+					cellSpans = cellSpans[:len(cellSpans)-1]
+				}
+				if tableDepth == 1 {
+					b.WriteString(strings.Repeat("\t", span))
+				} else {
+					b.WriteByte(' ')
+				}
+			case "tr":
+				trimSpaces()
+				if tableDepth == 1 {
+					// the separator after the last cell
+					if bytes.HasSuffix(b.Bytes(), []byte("\t")) {
+						b.Truncate(b.Len() - 1)
+					}
+					b.WriteByte('\n')
+				} else {
+					b.WriteByte(' ')
+				}
+			case "tbl":
+				if tableDepth > 0 {
+					tableDepth--
+				}
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// xmlAttr returns the value of the attribute with the given local name.
+func xmlAttr(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,269 @@
+package extract
+
+import (
+	"archive/zip"
+	"strconv"
+	"strings"
+)
+
+const docxMaxLevels = 9
+
+// xmlVal captures a w:val attribute; a nil *xmlVal means the element is absent.
+type xmlVal struct {
+	Val string `xml:"val,attr"`
+}
+
+type docxLvlXML struct {
+	Ilvl    int     `xml:"ilvl,attr"`
+	Start   *xmlVal `xml:"start"`
+	NumFmt  *xmlVal `xml:"numFmt"`
+	LvlText *xmlVal `xml:"lvlText"`
+	Restart *xmlVal `xml:"lvlRestart"`
+	IsLgl   *xmlVal `xml:"isLgl"`
+}
+
+// docxLvl is one level of a list definition.
+type docxLvl struct {
+	start   int
+	format  string
+	text    string // e.g. "%1.%2."
+	restart int    // -1: after any higher level; 0: never; n: after levels 1..n
+	legal   bool   // render all levels as decimal numbers
+}
+
+// docxNumPr is a paragraph's list membership.
+type docxNumPr struct {
+	numID string
+	ilvl  int
+}
+
+// docxNumbering renders list markers from word/numbering.xml, tracking counters
+// the way Word does: instances sharing an abstract definition continue each
+// other's numbering unless a num overrides its start value.
+type docxNumbering struct {
+	abstract map[string]*[docxMaxLevels]docxLvl
+	// numId -> abstractNumId, and per-level overrides of that num
+	numAbstract map[string]string
+	numLevels   map[string]map[int]docxLvl
+	numStarts   map[string]map[int]int
+
+	counters map[string]*[docxMaxLevels]int
+	started  map[string]*[docxMaxLevels]bool
+}
+
+func loadDocxNumbering(files map[string]*zip.File) (*docxNumbering, error) {
+	n := &docxNumbering{
+		abstract:    make(map[string]*[docxMaxLevels]docxLvl),
+		numAbstract: make(map[string]string),
+		numLevels:   make(map[string]map[int]docxLvl),
+		numStarts:   make(map[string]map[int]int),
+		counters:    make(map[string]*[docxMaxLevels]int),
+		started:     make(map[string]*[docxMaxLevels]bool),
+	}
+	if _, ok := files["word/numbering.xml"]; !ok {
+		return n, nil
+	}
+	var doc struct {
+		Abstract []struct {
+			ID     string       `xml:"abstractNumId,attr"`
+			Levels []docxLvlXML `xml:"lvl"`
+		} `xml:"abstractNum"`
+		Nums []struct {
+			ID         string  `xml:"numId,attr"`
+			AbstractID *xmlVal `xml:"abstractNumId"`
+			Overrides  []struct {
+				Ilvl          int          `xml:"ilvl,attr"`
+				StartOverride *xmlVal      `xml:"startOverride"`
+				Levels        []docxLvlXML `xml:"lvl"`
+			} `xml:"lvlOverride"`
+		} `xml:"num"`
+	}
+	if err := unmarshalZipXML(files, "word/numbering.xml", &doc); err != nil {
+		return nil, err
+	}
+	for _, a := range doc.Abstract {
+		levels := new([docxMaxLevels]docxLvl)
+		for i := range levels {
+			levels[i] = docxLvl{start: 1, format: "decimal", restart: -1}
+		}
+		for _, l := range a.Levels {
+			if l.Ilvl >= 0 && l.Ilvl < docxMaxLevels {
+				levels[l.Ilvl] = l.lvl(levels[l.Ilvl])
+			}
+		}
+		n.abstract[a.ID] = levels
+	}
+	for _, num := range doc.Nums {
+		if num.AbstractID == nil {
+			continue
+		}
+		n.numAbstract[num.ID] = num.AbstractID.Val
+		base := n.abstract[num.AbstractID.Val]
+		for _, o := range num.Overrides {
+			if o.Ilvl < 0 || o.Ilvl >= docxMaxLevels {
+				continue
+			}
+			if o.StartOverride != nil {
+				if v, err := strconv.Atoi(o.StartOverride.Val); err == nil {
+					if n.numStarts[num.ID] == nil {
+						n.numStarts[num.ID] = make(map[int]int)
+					}
+					n.numStarts[num.ID][o.Ilvl] = v
+				}
+			}
+			for _, l := range o.Levels {
+				if base == nil {
+					break
+				}
+				if n.numLevels[num.ID] == nil {
+					n.numLevels[num.ID] = make(map[int]docxLvl)
+				}
+				n.numLevels[num.ID][o.Ilvl] = l.lvl(base[o.Ilvl])
+			}
+		}
+	}
+	return n, nil
+}
+
+// lvl applies the elements present in the XML on top of def.
+func (l docxLvlXML) lvl(def docxLvl) docxLvl {
+	if l.Start != nil {
+		if v, err := strconv.Atoi(l.Start.Val); err == nil {
+			def.start = v
+		}
+	}
+	if l.NumFmt != nil {
+		def.format = l.NumFmt.Val
+	}
+	if l.LvlText != nil {
+		def.text = l.LvlText.Val
+	}
+	if l.Restart != nil {
+		if v, err := strconv.Atoi(l.Restart.Val); err == nil {
+			def.restart = v
+		}
+	}
+	if l.IsLgl != nil {
+		def.legal = l.IsLgl.Val != "0" && l.IsLgl.Val != "false"
+	}
+	return def
+}
+
+// level returns the effective definition of ilvl for numID.
+func (n *docxNumbering) level(numID string, ilvl int) (docxLvl, bool) {
+	if l, ok := n.numLevels[numID][ilvl]; ok {
+		return l, true
+	}
+	levels, ok := n.abstract[n.numAbstract[numID]]
+	if !ok {
+		return docxLvl{}, false
+	}
+	return levels[ilvl], true
+}
+
+// marker advances the counters for a list paragraph and returns its marker,
+// e.g. "3. " or "- ", indented by level. numId 0 means "not a list item".
+func (n *docxNumbering) marker(np docxNumPr) string {
+	if np.numID == "" || np.numID == "0" || np.ilvl < 0 || np.ilvl >= docxMaxLevels {
+		return ""
+	}
+	lvl, ok := n.level(np.numID, np.ilvl)
+	if !ok {
+		return ""
+	}
+	// a num that overrides start values is a separate list instance
+	key := "abstract:" + n.numAbstract[np.numID]
+	if _, ok := n.numStarts[np.numID]; ok {
+		key = "num:" + np.numID
+	}
+	counters, started := n.counters[key], n.started[key]
+	if counters == nil {
+		counters, started = new([docxMaxLevels]int), new([docxMaxLevels]bool)
+		n.counters[key], n.started[key] = counters, started
+	}
+
+	if started[np.ilvl] {
+		counters[np.ilvl]++
+	} else {
+		counters[np.ilvl] = lvl.start
+		if v, ok := n.numStarts[np.numID][np.ilvl]; ok {
+			counters[np.ilvl] = v
+		}
+		started[np.ilvl] = true
+	}
+	// using this level restarts deeper levels, subject to their lvlRestart
+	for j := np.ilvl + 1; j < docxMaxLevels; j++ {
+		deeper, _ := n.level(np.numID, j)
+		if deeper.restart < 0 || (deeper.restart > 0 && np.ilvl < deeper.restart) {
+			started[j] = false
+		}
+	}
+
+	indent := strings.Repeat("  ", np.ilvl)
+	switch lvl.format {
+	case "none":
+		return indent
+	case "bullet":
+		return indent + "- "
+	}
+	text := lvl.text
+	if text == "" {
+		text = "%" + strconv.Itoa(np.ilvl+1) + "."
+	}
+	for i := docxMaxLevels - 1; i >= 0; i-- {
+		ph := "%" + strconv.Itoa(i+1)
+		if !strings.Contains(text, ph) {
+			continue
+		}
+		l, _ := n.level(np.numID, i)
+		format := l.format
+		if lvl.legal {
+			format = "decimal"
+		}
+		text = strings.ReplaceAll(text, ph, formatListNumber(counters[i], format))
+	}
+	return indent + text + " "
+}
+
+// formatListNumber renders n in a WordprocessingML number format.
+func formatListNumber(n int, format string) string {
+	switch format {
+	case "decimalZero":
+		if n < 10 {
+			return "0" + strconv.Itoa(n)
+		}
+	case "lowerLetter":
+		return listLetters(n, 'a')
+	case "upperLetter":
+		return listLetters(n, 'A')
+	case "lowerRoman":
+		return strings.ToLower(romanNumeral(n))
+	case "upperRoman":
+		return romanNumeral(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// listLetters renders n the way Word letters lists: a..z, then aa..zz, and so on.
+func listLetters(n int, base rune) string {
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	return strings.Repeat(string(base+rune((n-1)%26)), (n-1)/26+1)
+}
+
+func romanNumeral(n int) string {
+	if n <= 0 || n >= 4000 {
+		return strconv.Itoa(n)
+	}
+	vals := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	syms := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var b strings.Builder
+	for i, v := range vals {
+		for n >= v {
+			b.WriteString(syms[i])
+			n -= v
+		}
+	}
+	return b.String()
+}
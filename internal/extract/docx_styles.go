@@ -0,0 +1,192 @@
+package extract
+
+import (
+	"archive/zip"
+	"strconv"
+	"strings"
+)
+
+// docxMaxStyleDepth bounds basedOn chains, which may be cyclic in damaged files.
+const docxMaxStyleDepth = 20
+
+// docxRPr is the subset of run properties the extractor interprets. A nil field
+// is not set at that level and is inherited.
+type docxRPr struct {
+	Bold   *xmlVal `xml:"b"`
+	Italic *xmlVal `xml:"i"`
+	Vanish *xmlVal `xml:"vanish"`
+	Lang   *xmlVal `xml:"lang"`
+	Size   *xmlVal `xml:"sz"`
+}
+
+// docxRunProps are effective run properties after style inheritance.
+type docxRunProps struct {
+	bold, italic, hidden bool
+	lang                 string
+	size                 int // font size in half-points, 0 when no level sets it
+}
+
+type docxStyle struct {
+	Type       string  `xml:"type,attr"`
+	ID         string  `xml:"styleId,attr"`
+	Default    string  `xml:"default,attr"`
+	Name       *xmlVal `xml:"name"`
+	BasedOn    *xmlVal `xml:"basedOn"`
+	NumID      *xmlVal `xml:"pPr>numPr>numId"`
+	Ilvl       *xmlVal `xml:"pPr>numPr>ilvl"`
+	OutlineLvl *xmlVal `xml:"pPr>outlineLvl"`
+	RPr        docxRPr `xml:"rPr"`
+}
+
+// docxStyles resolves effective properties from word/styles.xml: document
+// defaults, then the paragraph style chain, then the run style chain, then
+// direct formatting, each level overriding what it sets. A nil *docxStyles
+// resolves everything to zero values.
+type docxStyles struct {
+	defaults    docxRPr
+	styles      map[string]docxStyle
+	defaultPara string // style of paragraphs without w:pStyle
+}
+
+// loadDocxStyles reads word/styles.xml; a missing or malformed part yields a
+// resolver with no styles.
+func loadDocxStyles(files map[string]*zip.File) *docxStyles {
+	s := &docxStyles{styles: make(map[string]docxStyle)}
+	var doc struct {
+		Defaults docxRPr     `xml:"docDefaults>rPrDefault>rPr"`
+		Styles   []docxStyle `xml:"style"`
+	}
+	if files == nil || unmarshalZipXML(files, "word/styles.xml", &doc) != nil {
+		return s
+	}
+	s.defaults = doc.Defaults
+	for _, st := range doc.Styles {
+		s.styles[st.ID] = st
+		// an absent w:default is off, unlike other on/off attributes
+		if st.Type == "paragraph" && st.Default != "" && xmlOn(st.Default) {
+			s.defaultPara = st.ID
+		}
+	}
+	return s
+}
+
+// chain returns id and its basedOn ancestors, nearest first.
+func (s *docxStyles) chain(id string) []docxStyle {
+	var chain []docxStyle
+	for i := 0; id != "" && i < docxMaxStyleDepth; i++ {
+		st, ok := s.styles[id]
+		if !ok {
+			break
+		}
+		chain = append(chain, st)
+		id = ""
+		if st.BasedOn != nil {
+			id = st.BasedOn.Val
+		}
+	}
+	return chain
+}
+
+// runProps returns the effective properties of a run with run style rStyle and
+// direct properties direct, in a paragraph with style pStyle.
+func (s *docxStyles) runProps(pStyle, rStyle string, direct docxRPr) docxRunProps {
+	var p docxRunProps
+	if s != nil {
+		p.apply(s.defaults)
+		if pStyle == "" {
+			pStyle = s.defaultPara
+		}
+		for _, id := range []string{pStyle, rStyle} {
+			chain := s.chain(id)
+			for i := len(chain) - 1; i >= 0; i-- {
+				p.apply(chain[i].RPr)
+			}
+		}
+	}
+	p.apply(direct)
+	return p
+}
+
+func (p *docxRunProps) apply(r docxRPr) {
+	if r.Bold != nil {
+		p.bold = xmlOn(r.Bold.Val)
+	}
+	if r.Italic != nil {
+		p.italic = xmlOn(r.Italic.Val)
+	}
+	if r.Vanish != nil {
+		p.hidden = xmlOn(r.Vanish.Val)
+	}
+	if r.Lang != nil && r.Lang.Val != "" {
+		p.lang = r.Lang.Val
+	}
+	if r.Size != nil {
+		if n, err := strconv.Atoi(r.Size.Val); err == nil && n > 0 {
+			p.size = n
+		}
+	}
+}
+
+// numPr returns the list membership a paragraph inherits from its style, such
+// as "List Number"; ok is false when no style in the chain sets one.
+func (s *docxStyles) numPr(pStyle string) (np docxNumPr, ok bool) {
+	if s == nil {
+		return docxNumPr{}, false
+	}
+	if pStyle == "" {
+		pStyle = s.defaultPara
+	}
+	for _, st := range s.chain(pStyle) {
+		if st.NumID != nil {
+			np.numID = st.NumID.Val
+			if st.Ilvl != nil {
+				np.ilvl, _ = strconv.Atoi(st.Ilvl.Val)
+			}
+			return np, true
+		}
+	}
+	return docxNumPr{}, false
+}
+
+// headingLevel returns the 1-based outline level a paragraph style gives, from
+// w:outlineLvl or a built-in "heading N" name along the chain; ok is false for
+// body text styles.
+func (s *docxStyles) headingLevel(pStyle string) (level int, ok bool) {
+	if s == nil {
+		return 0, false
+	}
+	if pStyle == "" {
+		pStyle = s.defaultPara
+	}
+	for _, st := range s.chain(pStyle) {
+		if st.OutlineLvl != nil {
+			return docxOutlineLevel(st.OutlineLvl.Val)
+		}
+		if st.Name != nil {
+			if num, ok := strings.CutPrefix(strings.ToLower(st.Name.Val), "heading "); ok {
+				if n, err := strconv.Atoi(num); err == nil && n >= 1 && n <= docxMaxLevels {
+					return n, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// docxOutlineLevel converts a 0-based w:outlineLvl value; 9 means body text.
+func docxOutlineLevel(val string) (int, bool) {
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 0 || n >= docxMaxLevels {
+		return 0, false
+	}
+	return n + 1, true
+}
+
+// xmlOn interprets an OOXML on/off value; an absent value means on.
+func xmlOn(val string) bool {
+	switch val {
+	case "0", "false", "off":
+		return false
+	}
+	return true
+}
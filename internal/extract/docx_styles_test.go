@@ -0,0 +1,46 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func TestDocxStylesRunProps(t *testing.T) {
+	styles := `<?xml version="1.0" encoding="UTF-8"?><w:styles ` + testWordNS + `>` +
+		`<w:docDefaults><w:rPrDefault><w:rPr><w:i/><w:lang w:val="en-US"/></w:rPr></w:rPrDefault></w:docDefaults>` +
+		`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:rPr><w:sz w:val="22"/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Strong"><w:basedOn w:val="Normal"/><w:rPr><w:b/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Quote"><w:basedOn w:val="Strong"/><w:rPr><w:i w:val="0"/></w:rPr></w:style>` +
+		`<w:style w:type="character" w:styleId="Plain"><w:rPr><w:b w:val="false"/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Loop"><w:basedOn w:val="Loop"/><w:rPr><w:b/></w:rPr></w:style>` +
+		`</w:styles>`
+	data := testDOCX(t, ``, "word/styles.xml", styles)
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := loadDocxStyles(zipIndex(zr))
+	on := &xmlVal{}
+	for _, tc := range []struct {
+		name           string
+		pStyle, rStyle string
+		direct         docxRPr
+		want           docxRunProps
+	}{
+		{"defaults", "", "", docxRPr{}, docxRunProps{italic: true, lang: "en-US", size: 22}},
+		{"inherited bold", "Quote", "", docxRPr{}, docxRunProps{bold: true, lang: "en-US", size: 22}},
+		{"run style over paragraph style", "Strong", "Plain", docxRPr{}, docxRunProps{italic: true, lang: "en-US", size: 22}},
+		{"direct over styles", "Strong", "Plain", docxRPr{Bold: on, Vanish: on}, docxRunProps{bold: true, italic: true, hidden: true, lang: "en-US", size: 22}},
+		{"cyclic basedOn", "Loop", "", docxRPr{}, docxRunProps{bold: true, italic: true, lang: "en-US"}},
+		{"unknown style", "Missing", "", docxRPr{}, docxRunProps{italic: true, lang: "en-US"}},
+	} {
+		if got := s.runProps(tc.pStyle, tc.rStyle, tc.direct); got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+	var none *docxStyles
+	if got := none.runProps("Strong", "", docxRPr{Bold: on}); got != (docxRunProps{bold: true}) {
+		t.Errorf("nil resolver: got %+v, want only the direct bold", got)
+	}
+}
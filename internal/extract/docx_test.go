@@ -0,0 +1,338 @@
+package extract
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestDOCXWordSplitAcrossRuns(t *testing.T) {
+	for _, tc := range []struct {
+		name, body, want string
+	}{
+		{"three runs", `<w:p><w:r><w:t>Extr</w:t></w:r><w:r><w:t>act</w:t></w:r><w:r><w:t>ion</w:t></w:r></w:p>`, "Extraction\n"},
+		{"formatting", `<w:p><w:r><w:t>Extr</w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>act</w:t></w:r><w:r><w:rPr><w:i/></w:rPr><w:t>ion</w:t></w:r></w:p>`, "Extraction\n"},
+		{"spellcheck marks", `<w:p><w:proofErr w:type="spellStart"/><w:r><w:t>Extr</w:t></w:r><w:bookmarkStart w:id="0" w:name="x"/><w:r><w:t>act</w:t></w:r><w:bookmarkEnd w:id="0"/><w:r><w:t>ion</w:t></w:r><w:proofErr w:type="spellEnd"/></w:p>`, "Extraction\n"},
+		{"spaces kept", `<w:p><w:r><w:t xml:space="preserve">one </w:t></w:r><w:r><w:t>tw</w:t></w:r><w:r><w:t xml:space="preserve">o three</w:t></w:r></w:p>`, "one two three\n"},
+		{"spaces without xml:space", `<w:p><w:r><w:t>Hello </w:t></w:r><w:r><w:t>world</w:t></w:r><w:r><w:t> again</w:t></w:r></w:p>`, "Hello world again\n"},
+	} {
+		got, err := ExtractText("a.docx", testDOCX(t, tc.body))
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestDropEmptyParagraphs(t *testing.T) {
+	docx := testDOCX(t, `<w:p><w:r><w:t>one</w:t></w:r></w:p><w:p/><w:p><w:r><w:t xml:space="preserve">   </w:t></w:r></w:p><w:p><w:r><w:br/></w:r></w:p><w:p><w:r><w:t>two</w:t></w:r></w:p>`)
+	odt := testODT(t, `<text:p>one</text:p><text:p/><text:p> </text:p><text:h>two</text:h>`)
+	for _, tc := range []struct {
+		name, filename string
+		data           []byte
+		drop           bool
+		want           string
+	}{
+		{"docx kept", "a.docx", docx, false, "one\n\n   \n\n\ntwo\n"},
+		{"docx dropped", "a.docx", docx, true, "one\ntwo\n"},
+		{"odt kept", "a.odt", odt, false, "one\n\n \ntwo\n"},
+		{"odt dropped", "a.odt", odt, true, "one\ntwo\n"},
+	} {
+		opts := DefaultOptions()
+		opts.DropEmptyParagraphs = tc.drop
+		got, err := ExtractTextWithOptions(tc.filename, tc.data, opts)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestDOCXAnnotateBookmarks(t *testing.T) {
+	docx := testDOCX(t, `<w:p><w:bookmarkStart w:id="0" w:name="intro"/><w:r><w:t>Intro</w:t></w:r><w:bookmarkEnd w:id="0"/></w:p>`+
+		`<w:p><w:r><w:t xml:space="preserve">Last </w:t></w:r><w:bookmarkStart w:id="1" w:name="_GoBack"/><w:r><w:t>edit</w:t></w:r><w:bookmarkEnd w:id="1"/></w:p>`)
+	for _, tc := range []struct {
+		annotate bool
+		want     string
+	}{
+		{false, "Intro\nLast edit\n"},
+		{true, "[#intro]Intro\nLast edit\n"},
+	} {
+		opts := DefaultOptions()
+		opts.AnnotateBookmarks = tc.annotate
+		got, err := ExtractTextWithOptions("a.docx", docx, opts)
+		if err != nil || got != tc.want {
+			t.Errorf("AnnotateBookmarks %v: got %q, %v; want %q", tc.annotate, got, err, tc.want)
+		}
+	}
+}
+
+func TestDOCXFootnotes(t *testing.T) {
+	notes := func(kind, body string) string {
+		return `<?xml version="1.0" encoding="UTF-8"?><w:` + kind + `s ` + testWordNS + `>` +
+			`<w:` + kind + ` w:type="separator" w:id="-1"><w:p><w:r><w:separator/></w:r></w:p></w:` + kind + `>` + body + `</w:` + kind + `s>`
+	}
+	docx := testDOCX(t, `<w:p><w:r><w:t>Claim</w:t></w:r><w:r><w:footnoteReference w:id="7"/></w:r><w:r><w:t xml:space="preserve"> and more</w:t></w:r><w:r><w:footnoteReference w:id="3"/></w:r></w:p>`+
+		`<w:p><w:r><w:t>End</w:t></w:r><w:r><w:endnoteReference w:id="2"/></w:r></w:p>`,
+		"word/footnotes.xml", notes("footnote", `<w:footnote w:id="3"><w:p><w:r><w:t>Second source.</w:t></w:r></w:p></w:footnote><w:footnote w:id="7"><w:p><w:r><w:t>First source.</w:t></w:r></w:p></w:footnote>`),
+		"word/endnotes.xml", notes("endnote", `<w:endnote w:id="2"><w:p><w:r><w:t>Closing note.</w:t></w:r></w:p></w:endnote>`),
+	)
+	for _, tc := range []struct {
+		include bool
+		want    string
+	}{
+		{false, "Claim and more\nEnd\n"},
+		{true, "Claim[1] and more[2]\nEnd[i]\n\n[Footnotes]\n[1] First source.\n[2] Second source.\n\n[Endnotes]\n[i] Closing note.\n"},
+	} {
+		opts := DefaultOptions()
+		opts.IncludeFootnotes = tc.include
+		got, err := ExtractTextWithOptions("a.docx", docx, opts)
+		if err != nil || got != tc.want {
+			t.Errorf("IncludeFootnotes %v: got %q, %v; want %q", tc.include, got, err, tc.want)
+		}
+	}
+}
+
+func TestDOCXListMarkers(t *testing.T) {
+	item := func(num, ilvl, text string) string {
+		return `<w:p><w:pPr><w:numPr><w:ilvl w:val="` + ilvl + `"/><w:numId w:val="` + num + `"/></w:numPr></w:pPr><w:r><w:t>` + text + `</w:t></w:r></w:p>`
+	}
+	numbering := `<?xml version="1.0" encoding="UTF-8"?><w:numbering ` + testWordNS + `>` +
+		`<w:abstractNum w:abstractNumId="10">` +
+		`<w:lvl w:ilvl="0"><w:start w:val="3"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1."/></w:lvl>` +
+		`<w:lvl w:ilvl="1"><w:start w:val="1"/><w:numFmt w:val="lowerLetter"/><w:lvlText w:val="%2)"/></w:lvl>` +
+		`</w:abstractNum>` +
+		`<w:abstractNum w:abstractNumId="20"><w:lvl w:ilvl="0"><w:numFmt w:val="bullet"/><w:lvlText w:val="•"/></w:lvl></w:abstractNum>` +
+		`<w:num w:numId="1"><w:abstractNumId w:val="10"/></w:num>` +
+		`<w:num w:numId="2"><w:abstractNumId w:val="10"/><w:lvlOverride w:ilvl="0"><w:startOverride w:val="1"/></w:lvlOverride></w:num>` +
+		`<w:num w:numId="3"><w:abstractNumId w:val="20"/></w:num>` +
+		`</w:numbering>`
+	docx := testDOCX(t, item("1", "0", "A")+item("1", "1", "a")+item("1", "1", "b")+item("1", "0", "B")+item("1", "1", "c")+
+		item("2", "0", "restarted")+item("2", "0", "next")+item("1", "0", "continued")+item("3", "0", "bullet")+item("0", "0", "plain"),
+		"word/numbering.xml", numbering)
+	opts := DefaultOptions()
+	opts.ListMarkers = true
+	got, err := ExtractTextWithOptions("a.docx", docx, opts)
+	want := "3. A\n  a) a\n  b) b\n4. B\n  a) c\n1. restarted\n2. next\n5. continued\n- bullet\nplain\n"
+	if err != nil || got != want {
+		t.Errorf("got %q, %v; want %q", got, err, want)
+	}
+	if got, _ := ExtractText("a.docx", docx); got != "A\na\nb\nB\nc\nrestarted\nnext\ncontinued\nbullet\nplain\n" {
+		t.Errorf("without ListMarkers: got %q", got)
+	}
+}
+
+func TestDOCXAnnotateLanguage(t *testing.T) {
+	styles := `<?xml version="1.0" encoding="UTF-8"?><w:styles ` + testWordNS + `><w:docDefaults><w:rPrDefault><w:rPr><w:lang w:val="en-US"/></w:rPr></w:rPrDefault></w:docDefaults></w:styles>`
+	ru := `<w:rPr><w:lang w:val="ru-RU"/></w:rPr>`
+	docx := testDOCX(t, `<w:p><w:r><w:t xml:space="preserve">Hello </w:t></w:r><w:r>`+ru+`<w:t>мир</w:t></w:r><w:r>`+ru+`<w:t>ок</w:t></w:r><w:r><w:t xml:space="preserve"> again</w:t></w:r></w:p>`+
+		`<w:p><w:r>`+ru+`<w:t>Пока</w:t></w:r></w:p>`,
+		"word/styles.xml", styles)
+	for _, tc := range []struct {
+		annotate bool
+		want     string
+	}{
+		{false, "Hello мирок again\nПока\n"},
+		{true, "Hello [lang:ru-RU]мирок[/lang] again\n[lang:ru-RU]Пока[/lang]\n"},
+	} {
+		opts := DefaultOptions()
+		opts.AnnotateLanguage = tc.annotate
+		got, err := ExtractTextWithOptions("a.docx", docx, opts)
+		if err != nil || got != tc.want {
+			t.Errorf("AnnotateLanguage %v: got %q, %v; want %q", tc.annotate, got, err, tc.want)
+		}
+	}
+}
+
+func TestTrackChanges(t *testing.T) {
+	docx := testDOCX(t, `<w:p><w:r><w:t xml:space="preserve">a </w:t></w:r><w:ins w:id="1"><w:r><w:t>new</w:t></w:r></w:ins>`+
+		`<w:r><w:t xml:space="preserve"> b </w:t></w:r><w:del w:id="2"><w:r><w:delText>old</w:delText></w:r></w:del><w:r><w:t xml:space="preserve"> c</w:t></w:r></w:p>`+
+		`<w:p><w:r><w:t>plain</w:t></w:r></w:p>`)
+	odt := testODT(t, `<text:tracked-changes>`+
+		`<text:changed-region text:id="c1"><text:insertion><office:change-info/></text:insertion></text:changed-region>`+
+		`<text:changed-region text:id="c2"><text:deletion><office:change-info/><text:p>old</text:p></text:deletion></text:changed-region>`+
+		`</text:tracked-changes>`+
+		`<text:p>a <text:change-start text:change-id="c1"/>new<text:change-end text:change-id="c1"/> b <text:change text:change-id="c2"/> c</text:p>`+
+		`<text:p>plain</text:p>`)
+	for _, tc := range []struct {
+		mode TrackChangesMode
+		want string
+	}{
+		{"", "a new b  c\nplain\n"},
+		{TrackChangesClean, "a new b  c\nplain\n"},
+		{TrackChangesOriginal, "a  b old c\nplain\n"},
+		{TrackChangesMarkup, "a {+new+} b {-old-} c\nplain\n"},
+		{TrackChangesInsertions, "new\n"},
+	} {
+		opts := DefaultOptions()
+		opts.TrackChanges = tc.mode
+		for name, data := range map[string][]byte{"a.docx": docx, "a.odt": odt} {
+			got, err := ExtractTextWithOptions(name, data, opts)
+			if err != nil || got != tc.want {
+				t.Errorf("%s %q: got %q, %v; want %q", name, tc.mode, got, err, tc.want)
+			}
+		}
+	}
+	opts := DefaultOptions()
+	opts.TrackChanges = "accept"
+	if _, err := ExtractTextWithOptions("a.docx", docx, opts); err == nil {
+		t.Error("unknown track changes mode accepted")
+	}
+}
+
+func TestDOCXAltChunk(t *testing.T) {
+	rels := `<?xml version="1.0" encoding="UTF-8"?><Relationships ` + testRelsNS + `>` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/aFChunk" Target="chunk.html"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/aFChunk" Target="/word/imported.rtf"/>` +
+		`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/aFChunk" Target="https://example.com/x.html" TargetMode="External"/>` +
+		`</Relationships>`
+	para := func(s string) string { return `<w:p><w:r><w:t>` + s + `</w:t></w:r></w:p>` }
+	docx := testDOCX(t, para("before")+`<w:altChunk r:id="rId1"/>`+para("middle")+`<w:altChunk r:id="rId2"/><w:altChunk r:id="rId3"/><w:altChunk r:id="rId9"/>`+para("after"),
+		"word/_rels/document.xml.rels", rels,
+		"word/chunk.html", `<html><body><p>Imported <b>HTML</b></p></body></html>`,
+		"word/imported.rtf", `{\rtf1\ansi Imported RTF\par}`)
+	got, err := ExtractText("a.docx", docx)
+	if want := "before\nImported HTML\nmiddle\nImported RTF\nafter\n"; err != nil || got != want {
+		t.Errorf("got %q, %v; want %q", got, err, want)
+	}
+
+	// an HTML chunk in a legacy code page is decoded by its charset
+	cp1251, err := charmap.Windows1251.NewEncoder().String(`<html><head><meta charset="windows-1251"></head><body><p>Привет</p></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docx = testDOCX(t, `<w:altChunk r:id="rId1"/>`, "word/_rels/document.xml.rels", rels, "word/chunk.html", cp1251)
+	if got, err := ExtractText("a.docx", docx); err != nil || got != "Привет\n" {
+		t.Errorf("windows-1251 chunk: got %q, %v; want %q", got, err, "Привет\n")
+	}
+
+	// a DOCX chunk importing itself nests until the depth limit
+	self := `<Relationships ` + testRelsNS + `><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/aFChunk" Target="self.docx"/></Relationships>`
+	inner := testDOCX(t, para("inner"))
+	nested := testDOCX(t, `<w:altChunk r:id="rId1"/>`, "word/_rels/document.xml.rels", self, "word/self.docx", string(testDOCX(t, `<w:altChunk r:id="rId1"/>`, "word/_rels/document.xml.rels", self, "word/self.docx", string(inner))))
+	if got, err := ExtractText("a.docx", nested); err != nil || got != "inner\n" {
+		t.Errorf("nested: got %q, %v; want %q", got, err, "inner\n")
+	}
+	opts := DefaultOptions()
+	opts.MaxRecursionDepth = 1
+	if _, err := ExtractTextWithOptions("a.docx", nested, opts); !errors.Is(err, ErrMaxDepthExceeded) {
+		t.Errorf("nested past the limit: err = %v, want ErrMaxDepthExceeded", err)
+	}
+}
+
+func TestDOCXBareDocumentXML(t *testing.T) {
+	doc := `<w:document ` + testWordNS + `><w:body><w:p><w:r><w:t>Hello</w:t></w:r></w:p><w:p><w:r><w:t>World</w:t></w:r></w:p></w:body></w:document>`
+	for _, tc := range []struct {
+		name string
+		data string
+	}{
+		{"declaration", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" + doc},
+		{"root element", doc},
+		{"bom and whitespace", "\ufeff\r\n " + doc},
+	} {
+		got, err := ExtractText("a.docx", []byte(tc.data))
+		if err != nil || got != "Hello\nWorld\n" {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, got, err, "Hello\nWorld\n")
+		}
+	}
+	if _, err := ExtractText("a.docx", []byte("plain text")); err == nil {
+		t.Error("plain text read as a docx")
+	}
+}
+
+func TestDOCXDistinguishBreaks(t *testing.T) {
+	docx := testDOCX(t, `<w:p><w:r><w:t>line one</w:t><w:br/><w:t>line two</w:t></w:r></w:p><w:p><w:r><w:t>next</w:t></w:r></w:p>`+
+		`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>a</w:t><w:br/><w:t>b</w:t></w:r></w:p></w:tc></w:tr></w:tbl>`)
+	for _, tc := range []struct {
+		distinguish bool
+		want        string
+	}{
+		{false, "line one\nline two\nnext\na b\n"},
+		{true, "line one\nline two\n\nnext\n\na b\n"},
+	} {
+		opts := DefaultOptions()
+		opts.DistinguishBreaks = tc.distinguish
+		got, err := ExtractTextWithOptions("a.docx", docx, opts)
+		if err != nil || got != tc.want {
+			t.Errorf("DistinguishBreaks %v: got %q, %v; want %q", tc.distinguish, got, err, tc.want)
+		}
+	}
+}
+
+func TestDOCXHiddenText(t *testing.T) {
+	styles := `<?xml version="1.0" encoding="UTF-8"?><w:styles ` + testWordNS + `><w:style w:type="character" w:styleId="Secret"><w:rPr><w:vanish/></w:rPr></w:style></w:styles>`
+	docx := testDOCX(t, `<w:p><w:r><w:t xml:space="preserve">Visible </w:t></w:r><w:r><w:rPr><w:vanish/></w:rPr><w:t xml:space="preserve">hidden </w:t></w:r><w:r><w:t>text</w:t></w:r></w:p>`+
+		`<w:p><w:r><w:rPr><w:rStyle w:val="Secret"/></w:rPr><w:t>styled hidden</w:t></w:r></w:p>`+
+		`<w:p><w:r><w:rPr><w:rStyle w:val="Secret"/><w:vanish w:val="0"/></w:rPr><w:t>shown again</w:t></w:r></w:p>`+
+		`<w:p/>`,
+		"word/styles.xml", styles)
+	for _, tc := range []struct {
+		include bool
+		want    string
+	}{
+		// a paragraph of nothing but hidden text goes with it; empty ones stay
+		{false, "Visible text\nshown again\n\n"},
+		{true, "Visible hidden text\nstyled hidden\nshown again\n\n"},
+	} {
+		opts := DefaultOptions()
+		opts.IncludeHiddenText = tc.include
+		got, err := ExtractTextWithOptions("a.docx", docx, opts)
+		if err != nil || got != tc.want {
+			t.Errorf("IncludeHiddenText %v: got %q, %v; want %q", tc.include, got, err, tc.want)
+		}
+	}
+}
+
+func TestDOCXTextboxes(t *testing.T) {
+	box := `<w:txbxContent><w:p><w:r><w:t>Boxed text</w:t></w:r></w:p></w:txbxContent>`
+	docx := testDOCX(t, `<w:p><w:r><mc:AlternateContent xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006">`+
+		`<mc:Choice Requires="wps"><w:drawing><wps:txbx xmlns:wps="http://schemas.microsoft.com/office/word/2010/wordprocessingShape">`+box+`</wps:txbx></w:drawing></mc:Choice>`+
+		`<mc:Fallback><w:pict><v:textbox xmlns:v="urn:schemas-microsoft-com:vml">`+box+`</v:textbox></w:pict></mc:Fallback>`+
+		`</mc:AlternateContent></w:r></w:p>`+
+		`<w:p><w:r><w:drawing><a:graphic xmlns:a="`+nsDrawingML+`"><a:t>Shape text</a:t></a:graphic></w:drawing></w:r></w:p>`)
+	for _, tc := range []struct {
+		exclude bool
+		want    string
+	}{
+		// the VML fallback repeats the text box and is read once
+		{false, "Boxed text\n\nShape text\n"},
+		{true, ""},
+	} {
+		opts := DefaultOptions()
+		opts.ExcludeTextboxes = tc.exclude
+		got, err := ExtractTextWithOptions("a.docx", docx, opts)
+		if err != nil || got != tc.want {
+			t.Errorf("ExcludeTextboxes %v: got %q, %v; want %q", tc.exclude, got, err, tc.want)
+		}
+	}
+}
+
+func TestDOCXImageOnly(t *testing.T) {
+	const image = "\x89PNG"
+	scan := `<w:p><w:r><w:drawing/></w:r></w:p>`
+	header := `<?xml version="1.0" encoding="UTF-8"?><w:hdr ` + testWordNS + `><w:p><w:r><w:t>Letterhead</w:t></w:r></w:p></w:hdr>`
+	box := `<w:p><w:r><w:drawing><wps:txbx xmlns:wps="http://schemas.microsoft.com/office/word/2010/wordprocessingShape"><w:txbxContent><w:p><w:r><w:t>Boxed text</w:t></w:r></w:p></w:txbxContent></wps:txbx></w:drawing></w:r></w:p>`
+	for _, tc := range []struct {
+		name  string
+		docx  []byte
+		setup func(*Options)
+		want  string
+		err   error
+	}{
+		{"scan", testDOCX(t, scan, "word/media/image1.png", image), nil, "", ErrImageOnly},
+		{"scan with a text header", testDOCX(t, scan, "word/header1.xml", header, "word/media/image1.png", image),
+			func(o *Options) { o.IncludeHeadersFooters = true }, "Letterhead\n\n\n", nil},
+		{"text only in an excluded text box", testDOCX(t, box, "word/media/image1.png", image),
+			func(o *Options) { o.ExcludeTextboxes = true }, "", nil},
+		{"only hidden text", testDOCX(t, `<w:p><w:r><w:rPr><w:vanish/></w:rPr><w:t>hidden</w:t></w:r></w:p>`, "word/media/image1.png", image), nil, "", nil},
+		{"only a deletion", testDOCX(t, `<w:p><w:del w:id="1"><w:r><w:delText>old</w:delText></w:r></w:del></w:p>`, "word/media/image1.png", image), nil, "", nil},
+	} {
+		opts := DefaultOptions()
+		if tc.setup != nil {
+			tc.setup(&opts)
+		}
+		got, err := ExtractTextWithOptions("a.docx", tc.docx, opts)
+		if got != tc.want || !errors.Is(err, tc.err) {
+			t.Errorf("%s: got %q, %v; want %q, %v", tc.name, got, err, tc.want, tc.err)
+		}
+	}
+}
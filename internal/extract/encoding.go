@@ -0,0 +1,143 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// utf8BOM is the byte order mark some editors put before UTF-8 text.
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// DetectEncoding reports the encoding extractTXT would use for data without decoding
+// it: "utf-8", "utf-16le", "utf-16be" or one of the Cyrillic and Western European
+// code pages, "iso-8859-1" when nothing fits better. Confidence is in [0, 1]; for single-byte code pages it is the
+// heuristic score relative to the best score achievable for the input.
+func DetectEncoding(data []byte) (name string, confidence float64) {
+	return DetectEncodingWithOptions(data, DefaultOptions())
+}
+
+// DetectEncodingWithOptions is DetectEncoding choosing among the code pages
+// of opts.EncodingCandidates.
+func DetectEncodingWithOptions(data []byte, opts Options) (name string, confidence float64) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return "utf-16le", 1
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return "utf-16be", 1
+	case utf8.Valid(data):
+		return "utf-8", 1
+	}
+	_, name, score, ok := decodeBest(data, opts.encodingCandidates())
+	if !ok {
+		return "iso-8859-1", 0
+	}
+	// every high byte decodes to one rune worth at most 3 points as a letter,
+	// every printable ASCII byte is worth 1 point
+	maxScore := 0
+	for _, c := range data {
+		switch {
+		case c >= 0x80:
+			maxScore += 3
+		case c >= 0x20 && c <= 0x7E:
+			maxScore++
+		}
+	}
+	if maxScore == 0 || score <= 0 {
+		return name, 0
+	}
+	return name, min(float64(score)/float64(maxScore), 1)
+}
+
+// DetectAndDecode decodes data the way extractTXT does, choosing the encoding
+// as DetectEncoding reports it, and returns the text with the encoding name.
+// Confidence is the raw heuristic score of the text: 1 point per printable
+// ASCII character and up to 3 per letter beyond ASCII, with penalties for
+// replacement and control characters and for words mixing scripts. Higher is
+// better; the score grows with the input, so compare it against the length
+// of data.
+func DetectAndDecode(data []byte) (text string, encoding string, confidence int) {
+	return detectAndDecode(data, legacyCandidates)
+}
+
+func detectAndDecode(data []byte, candidates []encodingCandidate) (text string, encoding string, confidence int) {
+	// a byte order mark is not text; UTF-16 files written by concatenation
+	// may even carry a second one after the first
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		text, encoding = strings.TrimPrefix(decodeUTF16(data[2:], false), "\uFEFF"), "utf-16le"
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		text, encoding = strings.TrimPrefix(decodeUTF16(data[2:], true), "\uFEFF"), "utf-16be"
+	case utf8.Valid(data):
+		text, encoding = string(bytes.TrimPrefix(data, utf8BOM)), "utf-8"
+	default:
+		var ok bool
+		if text, encoding, confidence, ok = decodeBest(data, candidates); ok {
+			return text, encoding, confidence
+		}
+		// every byte is a character in ISO-8859-1
+		runes := make([]rune, len(data))
+		for i, c := range data {
+			runes[i] = rune(c)
+		}
+		text, encoding = string(runes), "iso-8859-1"
+	}
+	return text, encoding, scoreText(text)
+}
+
+// decodeUTF16 decodes UTF-16 without a byte order mark; a trailing odd byte
+// is dropped.
+func decodeUTF16(data []byte, bigEndian bool) string {
+	u := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		if bigEndian {
+			u = append(u, uint16(data[i])<<8|uint16(data[i+1]))
+		} else {
+			u = append(u, uint16(data[i])|uint16(data[i+1])<<8)
+		}
+	}
+	return string(utf16.Decode(u))
+}
+
+// textEncoding returns the encoding with the given name for Options.Encoding:
+// the names DetectEncoding reports first, then WHATWG labels. It returns nil
+// for unknown names.
+func textEncoding(name string) encoding.Encoding {
+	switch name = strings.ToLower(strings.TrimSpace(name)); name {
+	case "utf-8":
+		return unicode.UTF8
+	case "utf-16le":
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case "utf-16be":
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	}
+	if cp := codePage(name); cp != nil {
+		return cp
+	}
+	enc, _ := charset.Lookup(name)
+	return enc
+}
+
+// extractTXTWithEncoding decodes data as the named encoding, without
+// detection, and normalizes line ends as extractTXT does. A byte order mark
+// is dropped; bytes invalid in the encoding become U+FFFD.
+func extractTXTWithEncoding(data []byte, name string) (string, error) {
+	enc := textEncoding(name)
+	if enc == nil {
+		return "", errors.New("unknown encoding: " + name)
+	}
+	b, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", err
+	}
+	s := strings.TrimPrefix(string(b), "\uFEFF")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return s, nil
+}
@@ -0,0 +1,92 @@
+package extract
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	const russian = "Съешь же ещё этих мягких французских булок, да выпей чаю."
+	encode := func(cm *charmap.Charmap, s string) []byte {
+		b, err := cm.NewEncoder().Bytes([]byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want string
+		min  float64
+	}{
+		{"utf-8", []byte(russian), "utf-8", 1},
+		{"utf-16le", []byte{0xFF, 0xFE, 'h', 0}, "utf-16le", 1},
+		{"utf-16be", []byte{0xFE, 0xFF, 0, 'h'}, "utf-16be", 1},
+		{"windows-1251", encode(charmap.Windows1251, russian), "windows-1251", 0.8},
+		{"cp866", encode(charmap.CodePage866, russian), "cp866", 0.8},
+		{"windows-1252", encode(charmap.Windows1252, "Déjà vu, garçon — naïve café"), "windows-1252", 0.5},
+	} {
+		name, confidence := DetectEncoding(tc.data)
+		if name != tc.want || confidence < tc.min || confidence > 1 {
+			t.Errorf("%s: DetectEncoding = %q, %.2f; want %q, at least %.2f", tc.name, name, confidence, tc.want, tc.min)
+		}
+	}
+
+	// Ukrainian і and ї are missing from koi8-r but not from koi8-u
+	opts := DefaultOptions()
+	opts.EncodingCandidates = []string{"koi8-r", "koi8-u"}
+	if name, _ := DetectEncodingWithOptions(encode(charmap.KOI8U, "Київ і Їжак"), opts); name != "koi8-u" {
+		t.Errorf("koi8-u among candidates: DetectEncodingWithOptions = %q", name)
+	}
+}
+
+func TestCROnlyLineEnds(t *testing.T) {
+	// short lines, where a penalty for each CR would outweigh the letters
+	lines := []string{"Съешь", "же", "ещё", "этих", "мягких", "французских", "булок,", "да", "выпей", "чаю.", "я", "ты", "он"}
+	cp1251, err := charmap.Windows1251.NewEncoder().Bytes([]byte(strings.Join(lines, "\r") + "\r"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, confidence := DetectEncoding(cp1251); name != "windows-1251" || confidence < 0.8 {
+		t.Errorf("DetectEncoding = %q, %.2f; want windows-1251", name, confidence)
+	}
+	want := strings.Join(lines, "\n") + "\n"
+	if got, err := ExtractText("mac.txt", cp1251); err != nil || got != want {
+		t.Errorf("got %q, %v; want %q", got, err, want)
+	}
+}
+
+func TestStripBOM(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		data     []byte
+		encoding string
+		want     string
+	}{
+		{"utf-8", []byte("\xEF\xBB\xBFПривет\n"), "", "Привет\n"},
+		{"utf-8 forced", []byte("\xEF\xBB\xBFПривет\n"), "utf-8", "Привет\n"},
+		{"bare bom", []byte("\xEF\xBB\xBF"), "", ""},
+		{"inner bom kept", []byte("a\xEF\xBB\xBFb"), "", "a\ufeffb"},
+		{"doubled utf-16le", []byte{0xFF, 0xFE, 0xFF, 0xFE, 'h', 0, 'i', 0}, "", "hi"},
+		{"doubled utf-16be", []byte{0xFE, 0xFF, 0xFE, 0xFF, 0, 'h', 0, 'i'}, "", "hi"},
+	} {
+		opts := DefaultOptions()
+		opts.Encoding = tc.encoding
+		if got, err := ExtractTextWithOptions("a.txt", tc.data, opts); err != nil || got != tc.want {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+		// streamed a byte at a time, so the BOM arrives split
+		var b bytes.Buffer
+		if err := ExtractTextReader("a.txt", iotest.OneByteReader(bytes.NewReader(tc.data)), &b, opts); err != nil || b.String() != tc.want {
+			t.Errorf("%s streamed: got %q, %v; want %q", tc.name, b.String(), err, tc.want)
+		}
+	}
+	if text, _, _ := DetectAndDecode([]byte("\xEF\xBB\xBFhi")); text != "hi" {
+		t.Errorf("DetectAndDecode = %q, want %q", text, "hi")
+	}
+}
@@ -0,0 +1,103 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// extractEPUB reads the content documents of an EPUB in spine order. The
+// package (OPF) file is found through META-INF/container.xml; manifest hrefs
+// are resolved against its directory. Each document is converted like HTML
+// and chapters are separated by a blank line.
+func extractEPUB(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	files := zipIndex(zr)
+	opfPath, err := epubPackagePath(files)
+	if err != nil {
+		return "", err
+	}
+	var pkg struct {
+		Items []struct {
+			ID        string `xml:"id,attr"`
+			Href      string `xml:"href,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"manifest>item"`
+		Spine []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}
+	if err := unmarshalZipXML(files, opfPath, &pkg); err != nil {
+		return "", err
+	}
+	hrefs := make(map[string]string, len(pkg.Items))
+	for _, it := range pkg.Items {
+		if strings.Contains(it.MediaType, "html") {
+			hrefs[it.ID] = it.Href
+		}
+	}
+
+	var chapters []string
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		f := files[epubPath(opfPath, href)]
+		if f == nil {
+			continue
+		}
+		raw, err := readZipFile(f)
+		if err != nil {
+			return "", err
+		}
+		text, err := extractHTML(raw)
+		if err != nil {
+			return "", err
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			chapters = append(chapters, text)
+		}
+	}
+	if len(chapters) == 0 {
+		return "", nil
+	}
+	return strings.Join(chapters, "\n\n") + "\n", nil
+}
+
+// epubPackagePath finds the package (OPF) file through META-INF/container.xml.
+func epubPackagePath(files map[string]*zip.File) (string, error) {
+	var container struct {
+		Rootfiles []struct {
+			FullPath  string `xml:"full-path,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := unmarshalZipXML(files, "META-INF/container.xml", &container); err != nil {
+		return "", err
+	}
+	for _, rf := range container.Rootfiles {
+		if rf.MediaType == "" || rf.MediaType == "application/oebps-package+xml" {
+			return rf.FullPath, nil
+		}
+	}
+	return "", errors.New("epub: no package file in container.xml")
+}
+
+// epubPath resolves a manifest href, which is a URL relative to the package
+// file, to an archive path.
+func epubPath(opfPath, href string) string {
+	if i := strings.IndexByte(href, '#'); i >= 0 {
+		href = href[:i]
+	}
+	if p, err := url.PathUnescape(href); err == nil {
+		href = p
+	}
+	return relTarget(opfPath, path.Clean(href))
+}
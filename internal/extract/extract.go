@@ -1,176 +1,517 @@
 package extract
 
 import (
-	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/hex"
-	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"unicode"
 	"unicode/utf16"
 	"unicode/utf8"
 
+	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/charmap"
 	"golang.org/x/text/transform"
 )
 
 // ExtractText detects file type by extension and extracts plain text.
 func ExtractText(filename string, data []byte) (string, error) {
-	ext := strings.ToLower(filepath.Ext(filename))
-	switch ext {
-	case ".pdf":
-		return extractPDF(data)
-	case ".docx":
-		return extractDOCX(data)
-	case ".rtf":
-		return extractRTF(data)
-	case ".txt", "":
-		return extractTXT(data)
-	default:
-		// Try best-effort: docx are zips, pdf start with %PDF, rtf starts with {\rtf
-		if bytes.HasPrefix(data, []byte("%PDF")) {
-			return extractPDF(data)
-		}
-		if bytes.HasPrefix(data, []byte("PK")) {
-			return extractDOCX(data)
-		}
-		if bytes.HasPrefix(data, []byte("{\\rtf")) {
-			return extractRTF(data)
-		}
-		return "", errors.New("unsupported file type: " + ext)
-	}
+	return ExtractTextWithOptions(filename, data, DefaultOptions())
 }
 
-func extractPDF(data []byte) (string, error) {
-	cmd := exec.Command("pdftotext", "-layout", "-", "-")
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
+// ExtractTextContext is like ExtractText but gives up when ctx is done,
+// returning ctx.Err(). External tools such as pdftotext are killed.
+func ExtractTextContext(ctx context.Context, filename string, data []byte) (string, error) {
+	return extractText(ctx, filename, data, DefaultOptions())
+}
+
+// ExtractAs extracts data as the named format ("pdf", "docx", "rtf", "txt", ...),
+// skipping extension and signature detection. Unknown format names are an error.
+func ExtractAs(format string, data []byte) (string, error) {
+	opts := DefaultOptions()
+	opts.Format = format
+	return ExtractTextWithOptions("", data, opts)
+}
+
+// ExtractTextWithOptions is like ExtractText but applies opts.
+func ExtractTextWithOptions(filename string, data []byte, opts Options) (string, error) {
+	return extractText(context.Background(), filename, data, opts)
+}
+
+// extractText runs an extraction that can be abandoned through ctx. External
+// tools are killed on cancellation; in-process parsers cannot be interrupted,
+// so their result is discarded when it arrives after ctx is done.
+func extractText(ctx context.Context, filename string, data []byte, opts Options) (string, error) {
+	if err := opts.Validate(); err != nil {
 		return "", err
 	}
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", err
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
 	}
-	if err := cmd.Start(); err != nil {
-		return "", err
+	if ctx.Done() == nil {
+		return runExtraction(ctx, filename, data, opts)
 	}
-	if _, err := stdin.Write(data); err != nil {
-		_ = stdin.Close()
-		_ = cmd.Process.Kill()
+	if err := ctx.Err(); err != nil {
 		return "", err
 	}
-	_ = stdin.Close()
-	out, err := io.ReadAll(stdout)
-	if err != nil {
-		_ = cmd.Wait()
-		return "", err
+	type result struct {
+		text string
+		err  error
 	}
-	if err := cmd.Wait(); err != nil {
-		return "", err
+	done := make(chan result, 1)
+	go func() {
+		text, err := runExtraction(ctx, filename, data, opts)
+		done <- result{text, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			// a killed tool reports its own error; the cancellation is the cause
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "", r.err
+		}
+		return r.text, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
 	}
-	return string(out), nil
 }
 
-func extractDOCX(data []byte) (string, error) {
-	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+// runExtraction extracts and normalizes the text. A panic in a parser or in
+// Options.TextHook becomes an error: a malformed file must not take down the
+// process, and the extraction may run on a goroutine of its own.
+func runExtraction(ctx context.Context, filename string, data []byte, opts Options) (text string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			format := opts.Format
+			if format == "" {
+				format = fileFormat(filename)
+			}
+			text, err = "", fmt.Errorf("extract %s: internal error: %v", format, r)
+		}
+	}()
+	text, err = extractByType(ctx, filename, data, opts)
 	if err != nil {
 		return "", err
 	}
-	var docFile *zip.File
-	for _, f := range zr.File {
-		if f.Name == "word/document.xml" {
-			docFile = f
-			break
+	return normalize(ctx, text, opts)
+}
+
+// normalize is postProcess timed as PhaseNormalize.
+func normalize(ctx context.Context, text string, opts Options) (string, error) {
+	defer phaseTimer(ctx, PhaseNormalize)()
+	text, err := postProcess(text, opts)
+	if err != nil || opts.TextHook == nil {
+		return text, err
+	}
+	return opts.TextHook(text)
+}
+
+// extractByType dispatches on the file extension, falling back to magic bytes.
+// Options.Format, when set, overrides both.
+func extractByType(ctx context.Context, filename string, data []byte, opts Options) (string, error) {
+	if opts.Format != "" {
+		format := strings.TrimPrefix(strings.ToLower(opts.Format), ".")
+		if text, ok, err := extractFormat(ctx, format, data, opts); ok {
+			return text, err
+		}
+		return "", errors.New("unknown format: " + opts.Format)
+	}
+	stopDetect := phaseTimer(ctx, PhaseDetect)
+	ext := strings.ToLower(filepath.Ext(filename))
+	compressed := ext == ".gz" || bytes.HasPrefix(data, gzipMagic)
+	stopDetect()
+	if compressed {
+		stopDecompress := phaseTimer(ctx, PhaseDecompress)
+		inner, err := gunzip(data, opts.maxDecompressedBytes())
+		stopDecompress()
+		if err != nil {
+			return "", err
+		}
+		if ctx, err = enterNested(ctx, opts); err != nil {
+			return "", err
+		}
+		if ext != ".gz" {
+			return extractByType(ctx, filename, inner, opts)
+		}
+		// report.pdf.gz is a pdf; a bare name.gz is identified by content
+		filename = strings.TrimSuffix(filename, filepath.Ext(filename))
+		if filepath.Ext(filename) == "" {
+			if text, ok, err := extractByMagic(ctx, inner, opts); ok {
+				return text, err
+			}
+		}
+		return extractByType(ctx, filename, inner, opts)
+	}
+	format := ooxmlFormat(fileFormat(filename), data)
+	if format == "" {
+		// without an extension, a signature decides; anything else is text
+		if text, ok, err := extractByMagic(ctx, data, opts); ok {
+			return text, err
 		}
+		format = "txt"
 	}
-	if docFile == nil {
-		return "", errors.New("document.xml not found in docx")
+	if text, ok, err := extractFormat(ctx, format, data, opts); ok {
+		return text, err
 	}
-	rc, err := docFile.Open()
-	if err != nil {
-		return "", err
+	if text, ok, err := extractByMagic(ctx, data, opts); ok {
+		return text, err
 	}
-	defer rc.Close()
+	return "", errors.New("unsupported file type: " + ext)
+}
 
-	dec := xml.NewDecoder(rc)
-	var b strings.Builder
-	type element struct{ space, local string }
-	var stack []element
+// extractFormat runs the extractor for a format name, which is the file
+// extension without the dot; ok is false for unknown names.
+func extractFormat(ctx context.Context, format string, data []byte, opts Options) (text string, ok bool, err error) {
+	defer phaseTimer(ctx, PhaseParse)()
+	if alias, ok := extensionAliases[format]; ok {
+		format = alias
+	}
+	var run func() (string, error)
+	switch format {
+	case "pdf":
+		run = func() (string, error) { return extractPDF(ctx, data, opts) }
+	case "docx":
+		run = func() (string, error) { return extractDOCX(ctx, data, opts) }
+	case "xlsx":
+		run = func() (string, error) { return extractXLSX(data, opts) }
+	case "pptx":
+		run = func() (string, error) { return extractPPTX(ctx, data, opts) }
+	case "odt":
+		run = func() (string, error) { return extractODT(data, opts) }
+	case "epub":
+		run = func() (string, error) { return extractEPUB(data) }
+	case "doc":
+		run = func() (string, error) { return extractDOC(data) }
+	case "rtf":
+		run = func() (string, error) { return extractRTF(data) }
+	case "rst":
+		run = func() (string, error) { return extractRST(data) }
+	case "md":
+		run = func() (string, error) { return extractMarkdown(data, opts.IncludeLinkURLs) }
+	case "tex":
+		run = func() (string, error) { return extractTeX(data, opts.KeepTeXMath) }
+	case "numbers":
+		run = func() (string, error) { return extractNumbers(ctx, data, opts) }
+	case "chm":
+		run = func() (string, error) { return extractCHM(ctx, data, opts) }
+	case "html":
+		run = func() (string, error) { return extractHTML(data) }
+	case "csv":
+		run = func() (string, error) { return extractCSV(data, 0, opts) }
+	case "tsv":
+		run = func() (string, error) { return extractCSV(data, '\t', opts) }
+	case "json":
+		run = func() (string, error) { return extractJSON(data) }
+	case "xml":
+		run = func() (string, error) { return extractXML(ctx, data, opts) }
+	case "txt":
+		run = func() (string, error) { return extractTXT(data, opts) }
+	default:
+		return "", false, nil
+	}
+	if err := opts.checkFormat(format); err != nil {
+		return "", true, err
+	}
+	text, err = run()
+	recordFormat(ctx, format)
+	if err == nil {
+		switch format {
+		case "txt", "csv", "tsv":
+			encoding := strings.ToLower(strings.TrimSpace(opts.Encoding))
+			if encoding == "" {
+				encoding, _ = DetectEncodingWithOptions(data, opts)
+			}
+			recordInfo(ctx, func(info *extractInfo) { info.encoding = encoding })
+		case "pptx":
+			slides := strings.Count(text, "\f") + 1
+			recordInfo(ctx, func(info *extractInfo) { info.pages = slides })
+		}
+	}
+	return text, true, err
+}
+
+// extractByMagic picks an extractor from the leading bytes; ok is false when no
+// signature matches.
+func extractByMagic(ctx context.Context, data []byte, opts Options) (text string, ok bool, err error) {
+	stopDetect := phaseTimer(ctx, PhaseDetect)
+	format := magicFormat(data)
+	stopDetect()
+	if format == "" {
+		return "", false, nil
+	}
+	return extractFormat(ctx, format, data, opts)
+}
+
+// magicFormat names the format the leading bytes identify, or returns "".
+func magicFormat(data []byte) string {
+	// Try best-effort: docx are zips, pdf start with %PDF, rtf starts with {\rtf
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF")):
+		return "pdf"
+	case bytes.HasPrefix(data, cfbMagic):
+		if DetectFormat(data) == "doc" {
+			return "doc"
+		}
+	case bytes.HasPrefix(data, []byte("PK")):
+		switch format := zipFormat(data); format {
+		case "odt", "xlsx", "pptx", "epub":
+			return format
+		}
+		return "docx"
+	case bytes.HasPrefix(data, []byte("{\\rtf")):
+		return "rtf"
+	}
+	return ""
+}
+
+// ErrTruncatedPDF is returned under Options.Strict for a PDF that does not end
+// with startxref and %%EOF, which usually means the upload was cut off.
+var ErrTruncatedPDF = errors.New("truncated pdf: no startxref and %%EOF at the end of the file")
+
+// WarningTruncatedPDF prefixes the warning given instead of ErrTruncatedPDF.
+const WarningTruncatedPDF = "truncated_pdf"
+
+// pdfTailBytes is how far from the end of a PDF %%EOF is looked for; readers
+// tolerate some trailing garbage after it.
+const pdfTailBytes = 1024
 
-	for {
-		tok, err := dec.Token()
-		if err == io.EOF {
-			break
+// pdfTruncated reports whether data lacks the startxref and %%EOF that end
+// every complete PDF.
+func pdfTruncated(data []byte) bool {
+	tail := data[max(len(data)-pdfTailBytes, 0):]
+	eof := bytes.LastIndex(tail, []byte("%%EOF"))
+	return eof < 0 || !bytes.Contains(tail[:eof], []byte("startxref"))
+}
+
+func extractPDF(ctx context.Context, data []byte, opts Options) (string, error) {
+	if pdfTruncated(data) {
+		if opts.Strict {
+			return "", ErrTruncatedPDF
 		}
+		addWarning(ctx, WarningTruncatedPDF+": no startxref and %%EOF at the end of the file; text may be incomplete")
+	}
+	text, err := pdfText(ctx, data, opts)
+	if err != nil {
+		return "", err
+	}
+	// pdftotext ends every page with a form feed
+	pages := strings.Count(text, "\f")
+	recordInfo(ctx, func(info *extractInfo) { info.pages = pages })
+	if opts.OCR {
+		if text, err = ocrSparsePDFPages(ctx, data, text, opts); err != nil {
+			return "", err
+		}
+	}
+	if opts.OCR && opts.PDFImageOCR {
+		images, err := pdfImageText(ctx, data, opts)
 		if err != nil {
 			return "", err
 		}
-		switch t := tok.(type) {
-		case xml.StartElement:
-			stack = append(stack, element{space: t.Name.Space, local: t.Name.Local})
-			switch t.Name.Local {
-			case "br":
-				b.WriteByte('\n')
-			case "tab":
-				b.WriteByte('\t')
-			case "t":
-				// read text until end of this element
-				var txt strings.Builder
-				for {
-					tok2, err2 := dec.Token()
-					if err2 == io.EOF {
-						break
-					}
-					if err2 != nil {
-						return "", err2
-					}
-					if char, ok := tok2.(xml.CharData); ok {
-						txt.WriteString(string(char))
-						continue
-					}
-					if end, ok := tok2.(xml.EndElement); ok && end.Name.Local == "t" {
-						break
-					}
-				}
-				b.WriteString(txt.String())
-			}
-		case xml.EndElement:
-			if len(stack) > 0 {
-				stack = stack[:len(stack)-1]
-			}
-			switch t.Name.Local {
-			case "p":
-				b.WriteByte('\n')
-			}
+		text += images
+	}
+	return text, nil
+}
+
+// pdfText returns the text of a PDF, pages separated by form feeds, from
+// pdftotext or, when it is not installed, from the built-in reader.
+func pdfText(ctx context.Context, data []byte, opts Options) (string, error) {
+	if !PDFToolAvailable() {
+		addWarning(ctx, "pdftotext_missing: pdftotext is not installed; the built-in PDF reader was used and the layout is approximate")
+		return pdfNativeText(data, opts)
+	}
+	out, err := runPdftotext(ctx, data, opts)
+	return string(out), err
+}
+
+// runPdftotext returns the layout text of a PDF, pages separated by form feeds.
+func runPdftotext(ctx context.Context, data []byte, opts Options) ([]byte, error) {
+	defer phaseTimer(ctx, PhaseSubprocess)()
+	// -layout keeps the physical layout, which interleaves the lines of side
+	// by side columns; the default mode follows the reading order instead
+	var args []string
+	if !opts.PDFColumnMode {
+		args = append(args, "-layout")
+	}
+	args = append(args, opts.pdfPageArgs()...)
+	return execPdftotext(ctx, data, args...)
+}
+
+// ErrPDFToolMissing is returned when pdftotext is needed but not installed.
+var ErrPDFToolMissing = errors.New("pdftotext is not installed")
+
+// pdftotextPath looks pdftotext up once per process; the installed tools do
+// not change while it runs.
+var pdftotextPath = sync.OnceValues(func() (string, error) {
+	return exec.LookPath("pdftotext")
+})
+
+// PDFToolAvailable reports whether pdftotext is in PATH. Without it PDFs are
+// read by the built-in parser and ExtractPDFWithLayout fails with
+// ErrPDFToolMissing.
+func PDFToolAvailable() bool {
+	_, err := pdftotextPath()
+	return err == nil
+}
+
+// execPdftotext runs pdftotext with args on data and returns its output.
+func execPdftotext(ctx context.Context, data []byte, args ...string) ([]byte, error) {
+	path, err := pdftotextPath()
+	if err != nil {
+		return nil, ErrPDFToolMissing
+	}
+	cmd := exec.CommandContext(ctx, path, append(args, "-", "-")...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	// feed stdin while stdout is read: pdftotext may start writing before it
+	// has read all input, and with both pipes full neither side would move.
+	// When ctx is done the process is killed, which also fails the write.
+	writeErr := make(chan error, 1)
+	go func() {
+		_, err := stdin.Write(data)
+		if cerr := stdin.Close(); err == nil {
+			err = cerr
 		}
+		writeErr <- err
+	}()
+	out, err := io.ReadAll(stdout)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		<-writeErr
+		_ = cmd.Wait()
+		return nil, err
+	}
+	werr := <-writeErr
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("pdftotext: %w: %s", err, strings.TrimSpace(stderr.String()))
 	}
-	return b.String(), nil
+	// a tool that exits cleanly without reading all input leaves a broken
+	// pipe behind; its output stands
+	if werr != nil && !errors.Is(werr, syscall.EPIPE) {
+		return nil, werr
+	}
+	return out, nil
 }
 
+var (
+	reRTFNewlines         = regexp.MustCompile(`\n{2,}`)
+	reRTFSpaces           = regexp.MustCompile(`[ \t]{2,}`)
+	reRTFSpaceBeforePunct = regexp.MustCompile(`\s+([,.:;!?])`)
+)
+
 func extractRTF(data []byte) (string, error) {
 	// Minimal, best-effort RTF to text converter
 	var b strings.Builder
 	depth := 0
 	skipUntilDepth := -1
+	// w is where text goes: b, or list while a \listtext group is read
+	w := &b
+	var list strings.Builder
+	listDepth := -1 // depth of the open \listtext or \pntext group
+	level := 0      // list level of the current paragraph, from \ilvl or \pnlvl
+	// set by \intbl: the current paragraph is in a table cell
+	inTable := false
+	// \ucN: how many fallback characters follow each \uN; \fN: the current
+	// font. Both are scoped to groups.
+	uc, font := 1, -1
+	var stack []rtfGroup
+	fallback := 0 // fallback characters of the last \uN still to skip
+	var high rune // a \uN high surrogate waiting for its low half
+	// \'hh bytes are in the code page of the current font's \fcharset, or
+	// else of the document's \ansicpg; they are kept in pending until the run
+	// of escapes ends, since double-byte code pages spread a character over two
+	ansi := rtfCodePage(1252)
+	fonts := make(map[int]encoding.Encoding)
+	fontTable, fontDef := -1, -1 // depth of the \fonttbl group, font being defined
+	var pending []byte
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		enc := ansi
+		if e := fonts[font]; e != nil {
+			enc = e
+		}
+		if text, err := enc.NewDecoder().Bytes(pending); err == nil {
+			w.Write(text)
+		}
+		pending = pending[:0]
+	}
 
 	isLetter := func(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
 	i := 0
 	for i < len(data) {
 		c := data[i]
+		if len(pending) > 0 && !(c == '\\' && i+1 < len(data) && data[i+1] == '\'') {
+			flush()
+		}
+		if fallback > 0 {
+			// a fallback character is a byte, a \'hh escape or a control word;
+			// a group boundary ends the fallback early
+			switch {
+			case c == '{' || c == '}':
+				fallback = 0
+			case c == '\r' || c == '\n':
+				i++
+				continue
+			case c == '\\':
+				i = rtfSkipControl(data, i)
+				fallback--
+				continue
+			default:
+				i++
+				fallback--
+				continue
+			}
+		}
 		switch c {
 		case '{':
 			depth++
+			stack = append(stack, rtfGroup{uc, font})
 			i++
 			continue
 		case '}':
+			if len(stack) > 0 {
+				uc, font = stack[len(stack)-1].uc, stack[len(stack)-1].font
+				stack = stack[:len(stack)-1]
+			}
+			if depth == fontTable {
+				fontTable = -1
+			}
 			if skipUntilDepth >= 0 && depth == skipUntilDepth {
 				skipUntilDepth = -1
 			}
+			if listDepth >= 0 && depth == listDepth {
+				// the marker the writer rendered, such as "1." or a bullet
+				// glyph, followed by a tab
+				listDepth = -1
+				w = &b
+				b.WriteString(strings.Repeat(string(rtfIndent), level) + rtfListMarker(list.String()))
+				list.Reset()
+			}
 			if depth > 0 {
 				depth--
 			}
@@ -188,18 +529,18 @@ func extractRTF(data []byte) (string, error) {
 				switch sym {
 				case '\\', '{', '}':
 					if skipUntilDepth < 0 {
-						b.WriteByte(sym)
+						w.WriteByte(sym)
 					}
 				case '~':
 					if skipUntilDepth < 0 {
-						b.WriteByte(' ')
+						w.WriteByte(' ')
 					}
 				case '-':
 					// optional hyphen – ignore
 				case '_':
 					// non-breaking hyphen – write '-'
 					if skipUntilDepth < 0 {
-						b.WriteByte('-')
+						w.WriteByte('-')
 					}
 				case '*':
 					// destination control – skip next group
@@ -214,7 +555,7 @@ func extractRTF(data []byte) (string, error) {
 						var dst [1]byte
 						if _, err := hex.Decode(dst[:], hh); err == nil {
 							if skipUntilDepth < 0 {
-								b.WriteByte(dst[0])
+								pending = append(pending, dst[0])
 							}
 						}
 					}
@@ -231,6 +572,7 @@ func extractRTF(data []byte) (string, error) {
 			word := string(data[start:i])
 			// optional numeric argument (can be negative)
 			neg := false
+			param := -1
 			if i < len(data) && (data[i] == '-' || (data[i] >= '0' && data[i] <= '9')) {
 				if data[i] == '-' {
 					neg = true
@@ -241,32 +583,117 @@ func extractRTF(data []byte) (string, error) {
 					i++
 				}
 				numStr := string(data[numStart:i])
+				if !neg {
+					param, _ = strconv.Atoi(numStr)
+				}
 				if word == "u" {
 					if v, err := strconv.Atoi(numStr); err == nil {
 						if neg {
-							v = -v
-						}
-						if skipUntilDepth < 0 {
-							b.WriteRune(rune(int32(v)))
+							// values past 32767 are written as signed 16-bit
+							v = 65536 - v
 						}
-						// skip optional replacement char
-						if i < len(data) && data[i] == '?' {
-							i++
+						r := rune(v)
+						switch {
+						case r >= 0xD800 && r < 0xDC00:
+							if high != 0 && skipUntilDepth < 0 {
+								w.WriteRune(utf8.RuneError)
+							}
+							high = r
+						case high != 0:
+							if skipUntilDepth < 0 {
+								if r >= 0xDC00 && r < 0xE000 {
+									w.WriteRune(utf16.DecodeRune(high, r))
+								} else {
+									w.WriteRune(utf8.RuneError)
+									w.WriteRune(r)
+								}
+							}
+							high = 0
+						case skipUntilDepth < 0:
+							w.WriteRune(r)
 						}
+						fallback = uc
 					}
 				}
 			}
 			// control words with direct effects
 			switch word {
 			case "par", "line":
+				// paragraphs of a cell run on with spaces
+				if skipUntilDepth < 0 && inTable {
+					w.WriteByte(' ')
+				} else if skipUntilDepth < 0 {
+					w.WriteByte('\n')
+				}
+			case "intbl":
+				inTable = true
+			case "cell":
+				if skipUntilDepth < 0 {
+					w.WriteRune(rtfCell)
+				}
+			case "row":
 				if skipUntilDepth < 0 {
-					b.WriteByte('\n')
+					w.WriteByte('\n')
+				}
+			case "nestcell", "nestrow":
+				// nested tables are written within their cell
+				if skipUntilDepth < 0 {
+					w.WriteByte(' ')
 				}
 			case "tab":
 				if skipUntilDepth < 0 {
-					b.WriteByte('\t')
+					w.WriteByte('\t')
+				}
+			case "listtext", "pntext":
+				if skipUntilDepth < 0 && listDepth < 0 {
+					listDepth = depth
+					w = &list
+				}
+			case "uc":
+				uc = max(param, 0)
+			case "ansicpg":
+				if enc := rtfCodePage(param); enc != nil {
+					ansi = enc
+				}
+			case "mac":
+				ansi = rtfCodePage(10000)
+			case "pc":
+				ansi = rtfCodePage(437)
+			case "pca":
+				ansi = rtfCodePage(850)
+			case "f":
+				if fontTable >= 0 {
+					fontDef = param
+				} else {
+					font = param
+				}
+			case "fcharset":
+				if cp, ok := rtfCharsetCodePages[param]; ok && fontTable >= 0 {
+					if enc := rtfCodePage(cp); enc != nil {
+						fonts[fontDef] = enc
+					}
+				}
+			case "pard":
+				// \pard inside \listtext does not end the list paragraph
+				if listDepth < 0 {
+					level = 0
+					inTable = false
+				}
+			case "ilvl":
+				// also used in the list table, which is skipped
+				if skipUntilDepth < 0 {
+					level = min(max(param, 0), 8)
 				}
-			case "fonttbl", "colortbl", "stylesheet", "info", "pict", "header", "footer":
+			case "pnlvl":
+				// old-style levels count from 1
+				level = min(max(param-1, 0), 8)
+			case "fonttbl":
+				// skipped as text, but read for the fonts' \fcharset
+				if skipUntilDepth < 0 {
+					fontTable = depth
+				}
+				fallthrough
+			case "colortbl", "stylesheet", "info", "pict", "header", "footer":
 				if skipUntilDepth < 0 {
 					skipUntilDepth = depth
 				}
@@ -275,6 +702,11 @@ func extractRTF(data []byte) (string, error) {
 			if i < len(data) && data[i] == ' ' {
 				i++
 			}
+			if word == "bin" && param > 0 {
+				// \binN is followed by N bytes of binary data, which may hold
+				// any byte including braces and backslashes
+				i += min(param, len(data)-i)
+			}
 			continue
 		default:
 			// In RTF, raw CR/LF are formatting-only; ignore them and rely on \par/\line
@@ -283,22 +715,35 @@ func extractRTF(data []byte) (string, error) {
 				continue
 			}
 			if skipUntilDepth < 0 {
-				b.WriteByte(c)
+				w.WriteByte(c)
 			}
 			i++
 		}
 	}
+	flush()
 	// ensure valid UTF-8; if not, try to interpret as UTF-16 LE/BE with BOM
 	out := b.String()
 	// Normalize whitespace: unify newlines, collapse multiples, remove spaces before punctuation
 	out = strings.ReplaceAll(out, "\r\n", "\n")
 	out = strings.ReplaceAll(out, "\r", "\n")
-	reNewlines := regexp.MustCompile(`\n{2,}`)
-	out = reNewlines.ReplaceAllString(out, "\n")
-	reSpaces := regexp.MustCompile(`[ \t]{2,}`)
-	out = reSpaces.ReplaceAllString(out, " ")
-	reSpaceBeforePunct := regexp.MustCompile(`\s+([,.:;!?])`)
-	out = reSpaceBeforePunct.ReplaceAllString(out, "$1")
+	out = reRTFNewlines.ReplaceAllString(out, "\n")
+	out = reRTFSpaces.ReplaceAllString(out, " ")
+	out = reRTFSpaceBeforePunct.ReplaceAllString(out, "$1")
+	out = strings.ReplaceAll(out, string(rtfIndent), "  ")
+	if strings.ContainsRune(out, rtfCell) {
+		// cells are trimmed, and the separator after the last cell of a row
+		// is dropped
+		cells := strings.Split(out, string(rtfCell))
+		for j := range cells {
+			if j > 0 {
+				cells[j] = strings.TrimLeft(cells[j], " ")
+			}
+			if j < len(cells)-1 {
+				cells[j] = strings.TrimRight(cells[j], " ")
+			}
+		}
+		out = strings.ReplaceAll(strings.Join(cells, "\t"), "\t\n", "\n")
+	}
 	if !utf8.ValidString(out) {
 		// try decode as UTF-16 with BOM
 		bs := []byte(out)
@@ -324,66 +769,182 @@ func extractRTF(data []byte) (string, error) {
 	return out, nil
 }
 
-func extractTXT(data []byte) (string, error) {
-	// Handle UTF-16 BOMs
-	if len(data) >= 2 {
-		if data[0] == 0xFF && data[1] == 0xFE { // UTF-16 LE
-			u := make([]uint16, 0, (len(data)-2)/2)
-			for i := 2; i+1 < len(data); i += 2 {
-				u = append(u, uint16(data[i])|uint16(data[i+1])<<8)
-			}
-			s := string(utf16.Decode(u))
-			s = strings.ReplaceAll(s, "\r\n", "\n")
-			s = strings.ReplaceAll(s, "\r", "\n")
-			return s, nil
-		}
-		if data[0] == 0xFE && data[1] == 0xFF { // UTF-16 BE
-			u := make([]uint16, 0, (len(data)-2)/2)
-			for i := 2; i+1 < len(data); i += 2 {
-				u = append(u, uint16(data[i+1])|uint16(data[i])<<8)
-			}
-			s := string(utf16.Decode(u))
-			s = strings.ReplaceAll(s, "\r\n", "\n")
-			s = strings.ReplaceAll(s, "\r", "\n")
-			return s, nil
+// rtfSkipControl returns the index after the control word or symbol at
+// data[i], which is a backslash, including a \'hh byte, a numeric parameter,
+// the delimiting space and the binary data of \binN.
+func rtfSkipControl(data []byte, i int) int {
+	i++
+	if i >= len(data) {
+		return i
+	}
+	c := data[i]
+	if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') {
+		if c == '\'' {
+			return min(i+3, len(data))
 		}
+		return i + 1
+	}
+	start := i
+	for i < len(data) && ((data[i] >= 'a' && data[i] <= 'z') || (data[i] >= 'A' && data[i] <= 'Z')) {
+		i++
+	}
+	word := string(data[start:i])
+	if i < len(data) && data[i] == '-' {
+		i++
 	}
-	if utf8.Valid(data) {
-		s := string(data)
-		s = strings.ReplaceAll(s, "\r\n", "\n")
-		s = strings.ReplaceAll(s, "\r", "\n")
-		return s, nil
+	numStart := i
+	for i < len(data) && data[i] >= '0' && data[i] <= '9' {
+		i++
+	}
+	n, _ := strconv.Atoi(string(data[numStart:i]))
+	if i < len(data) && data[i] == ' ' {
+		i++
+	}
+	if word == "bin" && data[numStart-1] != '-' {
+		i += min(n, len(data)-i)
+	}
+	return i
+}
+
+// rtfGroup is the state an RTF group restores when it closes.
+type rtfGroup struct {
+	uc, font int
+}
+
+// rtfCodePages names the code pages of \ansicpg and \fcharset by number;
+// Windows code pages 1250 to 1258 are named by rtfCodePage.
+var rtfCodePages = map[int]string{
+	866:   "ibm866",
+	874:   "windows-874",
+	932:   "shift_jis",
+	936:   "gbk",
+	949:   "euc-kr",
+	950:   "big5",
+	10000: "macintosh",
+	10007: "x-mac-cyrillic",
+	20866: "koi8-r",
+	21866: "koi8-u",
+	28591: "iso-8859-1",
+	28595: "iso-8859-5",
+}
+
+// rtfCharsetCodePages maps the \fcharset of a font to its code page. The
+// ANSI, default and symbol character sets are not here: their text is in
+// the document's \ansicpg.
+var rtfCharsetCodePages = map[int]int{
+	77:  10000,
+	128: 932,
+	129: 949,
+	134: 936,
+	136: 950,
+	161: 1253,
+	162: 1254,
+	163: 1258,
+	177: 1255,
+	178: 1256,
+	186: 1257,
+	204: 1251,
+	222: 874,
+	238: 1250,
+	255: 437,
+}
+
+// rtfCodePage returns the encoding of a Windows code page number, or nil for
+// one it does not know.
+func rtfCodePage(cp int) encoding.Encoding {
+	switch {
+	case cp == 437:
+		return charmap.CodePage437
+	case cp == 850:
+		return charmap.CodePage850
+	case cp >= 1250 && cp <= 1258:
+		return textEncoding("windows-" + strconv.Itoa(cp))
+	case rtfCodePages[cp] != "":
+		return textEncoding(rtfCodePages[cp])
+	}
+	return nil
+}
+
+// rtfIndent stands in for list indentation, and rtfCell for the tab after a
+// table cell, until whitespace is collapsed.
+const (
+	rtfIndent = '\uE000'
+	rtfCell   = '\uE001'
+)
+
+// rtfListMarker turns the text of a \listtext group into the marker of a list
+// item: numbers and letters such as "1." or "a)" are kept, anything else is
+// a bullet glyph (often a Symbol font character) and becomes "-".
+func rtfListMarker(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
 	}
-	// Try common Cyrillic encodings and pick the best match
-	if decoded, ok := decodeBestCyrillic(data); ok {
-		decoded = strings.ReplaceAll(decoded, "\r\n", "\n")
-		decoded = strings.ReplaceAll(decoded, "\r", "\n")
-		return decoded, nil
+	if strings.ContainsFunc(s, unicode.IsDigit) || (len(s) > 1 && strings.ContainsAny(s[len(s)-1:], ".)")) {
+		return s + " "
 	}
-	// Fallback: ISO-8859-1 mapping
-	runes := make([]rune, 0, len(data))
-	for _, by := range data {
-		runes = append(runes, rune(by))
+	return "- "
+}
+
+func extractTXT(data []byte, opts Options) (string, error) {
+	if opts.Encoding != "" {
+		return extractTXTWithEncoding(data, opts.Encoding)
 	}
-	s := string(runes)
+	s, _, _ := detectAndDecode(data, opts.encodingCandidates())
 	s = strings.ReplaceAll(s, "\r\n", "\n")
 	s = strings.ReplaceAll(s, "\r", "\n")
 	return s, nil
 }
 
-// decodeBestCyrillic tries a list of common Cyrillic encodings and returns the best-scoring text.
-func decodeBestCyrillic(data []byte) (string, bool) {
-	candidates := []struct {
-		name string
-		enc  *charmap.Charmap
-	}{
+// encodingCandidate is a single-byte code page decodeBest may choose.
+type encodingCandidate struct {
+	name string
+	enc  *charmap.Charmap
+}
+
+// cyrillicCandidates and westernCandidates are the code pages tried for text
+// that is neither UTF-8 nor UTF-16. On equal scores the earlier one wins.
+var (
+	cyrillicCandidates = []encodingCandidate{
 		{"windows-1251", charmap.Windows1251},
 		{"koi8-r", charmap.KOI8R},
 		{"iso-8859-5", charmap.ISO8859_5},
 		{"mac-cyrillic", charmap.MacintoshCyrillic},
 		{"cp866", charmap.CodePage866},
 	}
-	bestText := ""
+	westernCandidates = []encodingCandidate{
+		{"windows-1252", charmap.Windows1252},
+		{"iso-8859-15", charmap.ISO8859_15},
+		{"iso-8859-1", charmap.ISO8859_1},
+	}
+	// legacyCandidates serve mixed workloads of Russian and Western European text
+	legacyCandidates = append(append([]encodingCandidate{}, cyrillicCandidates...), westernCandidates...)
+)
+
+// extraCodePages may be named in Options.EncodingCandidates besides the
+// default candidates.
+var extraCodePages = []encodingCandidate{
+	{"koi8-u", charmap.KOI8U},
+}
+
+// codePage returns the code page with the given name, or nil.
+func codePage(name string) *charmap.Charmap {
+	name = strings.ToLower(name)
+	for _, list := range [][]encodingCandidate{legacyCandidates, extraCodePages} {
+		for _, c := range list {
+			if c.name == name {
+				return c.enc
+			}
+		}
+	}
+	return nil
+}
+
+// decodeBest decodes data with each candidate and returns the text that
+// scoreText rates best, with the name of its code page and its score. It
+// fails when no candidate decodes data without replacement characters.
+func decodeBest(data []byte, candidates []encodingCandidate) (string, string, int, bool) {
+	bestText, bestName := "", ""
 	bestScore := int(-1 << 31)
 
 	for _, c := range candidates {
@@ -393,39 +954,71 @@ func decodeBestCyrillic(data []byte) (string, bool) {
 			continue
 		}
 		text := string(decoded)
-		score := scoreCyrillicText(text)
+		score := scoreText(text)
 		if score > bestScore {
 			bestScore = score
 			bestText = text
+			bestName = c.name
 		}
 	}
 	if bestText == "" {
-		return "", false
+		return "", "", 0, false
 	}
 	// Heuristic: require some Cyrillic or at least no replacement chars
 	if strings.ContainsRune(bestText, '\uFFFD') {
-		return "", false
+		return "", "", 0, false
 	}
-	return bestText, true
+	return bestText, bestName, bestScore, true
 }
 
-func scoreCyrillicText(s string) int {
+// scoreText rates decoded text by how plausible its words are. Printable
+// ASCII is worth a point; a non-ASCII letter is worth 3 in a word of one
+// script: a Cyrillic word, or a Latin word that also has ASCII letters (café)
+// or is a single letter (à). A word whose letters are all accented Latin,
+// typical of Cyrillic read as windows-1252, earns nothing for them, and
+// Cyrillic letters mixed into a Latin word, typical of the reverse, count
+// against it. Replacement characters and C0 and C1 control characters count
+// against the text too. Line ends are not control characters here, whether
+// CRLF, LF or the lone CR of classic Mac OS files, so the line ending style
+// of a file does not affect which code page wins.
+func scoreText(s string) int {
 	if s == "" {
 		return -1_000_000
 	}
-	var cyr, asciiPrint, repl, ctrl int
+	score := 0
+	// letters of the current word: ASCII, Cyrillic and other Latin
+	var ascii, cyr, latin int
+	endWord := func() {
+		switch {
+		case cyr > 0 && ascii+latin > 0:
+			score -= 3 * (cyr + latin)
+		case cyr > 0:
+			score += 3 * cyr
+		case latin > 0 && (ascii > 0 || latin == 1):
+			score += 3 * latin
+		}
+		ascii, cyr, latin = 0, 0, 0
+	}
 	for _, r := range s {
 		switch {
-		case r == '\uFFFD':
-			repl++
+		case r < 0x80 && unicode.IsLetter(r):
+			ascii++
 		case r >= 0x0400 && r <= 0x052F: // Cyrillic + Extended
 			cyr++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		default:
+			endWord()
+		}
+		switch {
+		case r == '\uFFFD':
+			score -= 50
 		case r >= 0x20 && r <= 0x7E: // ASCII printable
-			asciiPrint++
-		case r < 0x20 && r != '\n' && r != '\t' && r != '\r':
-			ctrl++
+			score++
+		case (r < 0x20 && r != '\n' && r != '\t' && r != '\r') || (r >= 0x80 && r <= 0x9F):
+			score -= 5
 		}
 	}
-	// Penalize replacement and control chars, reward Cyrillic
-	return 3*cyr + asciiPrint - 50*repl - 5*ctrl
+	endWord()
+	return score
 }
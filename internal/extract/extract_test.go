@@ -0,0 +1,242 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExtractRecoversPanic(t *testing.T) {
+	opts := DefaultOptions()
+	opts.TextHook = func(string) (string, error) { panic("boom") }
+	cancelled, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for name, ctx := range map[string]context.Context{
+		"background":  context.Background(),
+		"cancellable": cancelled,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ExtractDetailedContext(ctx, "a.txt", []byte("text"), opts)
+			if err == nil || !strings.Contains(err.Error(), "extract txt: internal error: boom") {
+				t.Fatalf("err = %v, want the recovered panic", err)
+			}
+		})
+	}
+}
+
+func TestRTFBin(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{`{\rtf1 a{\*\objdata\bin6 }{\x\}b}c}`, "ac"},
+		{`{\rtf1 a\bin3 {{{b}`, "ab"},
+		{`{\rtf1 \u233\bin2 {}x}`, "éx"},
+		{`{\rtf1 a\bin99 {{`, "a"},
+		{`{\rtf1 a\bin9223372036854775807 b}`, "a"},
+		{`{\rtf1 \u233\bin9223372036854775807 b}`, "é"},
+		{`{\rtf1 a\bin99999999999999999999 b}`, "a"},
+	} {
+		got, err := extractRTF([]byte(tc.in))
+		if err != nil || got != tc.want {
+			t.Errorf("extractRTF(%q) = %q, %v; want %q", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestRTFLists(t *testing.T) {
+	rtf := `{\rtf1\ansi{\*\listtable{\list{\listlevel\levelnfc23{\leveltext\'01\u-3913 ?;}}}}` +
+		`\pard\ls1\ilvl0{\listtext\f1 \u-3913?\tab}First\par` +
+		`\pard\ls1\ilvl1{\listtext o\tab}Nested\par` +
+		`\pard\ls2\ilvl0{\listtext 1.\tab}One\par` +
+		`\pard\ls2\ilvl0{\listtext 2.\tab}Two\par` +
+		`\pard{\pntext\f1 \'b7\tab}Old style\par` +
+		`\pard Plain\par}`
+	want := "- First\n  - Nested\n1. One\n2. Two\n- Old style\nPlain\n"
+	if got, err := ExtractText("a.rtf", []byte(rtf)); err != nil || got != want {
+		t.Errorf("got %q, %v; want %q", got, err, want)
+	}
+}
+
+// benchmarkRTF is a short cp1251 letter of twenty paragraphs, the size of
+// most RTF files, where compiling the whitespace regexps on every call
+// showed up in the allocations.
+var benchmarkRTF = []byte(`{\rtf1\ansi\ansicpg1251{\fonttbl{\f0 Times New Roman;}}\f0\fs24 ` +
+	strings.Repeat(`\pard \'cf\'f0\'e8\'e2\'e5\'f2 ,  \'ec\'e8\'f0 !  Second   sentence .\par `, 20) + `}`)
+
+func BenchmarkExtractRTF(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchmarkRTF)))
+	for range b.N {
+		if _, err := extractRTF(benchmarkRTF); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestFirstUnitOnly(t *testing.T) {
+	pdf := testTextPDF("BT /F1 12 Tf 72 720 Td (first page) Tj ET", "BT /F1 12 Tf 72 720 Td (second page) Tj ET")
+	xlsx := testXLSX(t, []string{
+		`<row r="1"><c r="A1" t="inlineStr"><is><t>one</t></is></c></row>`,
+		`<row r="1"><c r="A1" t="inlineStr"><is><t>two</t></is></c></row>`,
+	})
+	pptx := testZip(t, "ppt/slides/slide1.xml", testSlide("first slide"), "ppt/slides/slide2.xml", testSlide("second slide"))
+	docx := testDOCX(t, `<w:p><w:pPr><w:sectPr/></w:pPr><w:r><w:t>intro</w:t></w:r></w:p><w:p><w:r><w:t>body</w:t></w:r></w:p><w:sectPr/>`)
+	for _, tc := range []struct {
+		filename   string
+		data       []byte
+		first, all string
+	}{
+		{"a.pdf", pdf, "first page", "second page"},
+		{"a.xlsx", xlsx, "one", "two"},
+		{"a.pptx", pptx, "first slide", "second slide"},
+		{"a.docx", docx, "intro", "body"},
+	} {
+		got, err := ExtractText(tc.filename, tc.data)
+		if err != nil || !strings.Contains(got, tc.first) || !strings.Contains(got, tc.all) {
+			t.Errorf("%s: got %q, %v; want both units", tc.filename, got, err)
+		}
+		opts := DefaultOptions()
+		opts.FirstUnitOnly = true
+		got, err = ExtractTextWithOptions(tc.filename, tc.data, opts)
+		if err != nil || !strings.Contains(got, tc.first) || strings.Contains(got, tc.all) {
+			t.Errorf("%s with FirstUnitOnly: got %q, %v; want only %q", tc.filename, got, err, tc.first)
+		}
+	}
+}
+
+func TestExtractAs(t *testing.T) {
+	rtf := []byte(`{\rtf1\ansi Hello\par World}`)
+	for _, tc := range []struct {
+		format string
+		want   string
+		ok     bool
+	}{
+		{"rtf", "Hello\nWorld", true},
+		{".RTF", "Hello\nWorld", true},
+		{"txt", string(rtf), true},
+		{"bogus", "", false},
+	} {
+		got, err := ExtractAs(tc.format, rtf)
+		if tc.ok && (err != nil || got != tc.want) {
+			t.Errorf("%s: got %q, %v; want %q", tc.format, got, err, tc.want)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("%s: got %q, want an error", tc.format, got)
+		}
+	}
+	// the override wins over a misleading name
+	opts := DefaultOptions()
+	opts.Format = "rtf"
+	if got, err := ExtractTextWithOptions("notes.txt", rtf, opts); err != nil || got != "Hello\nWorld" {
+		t.Errorf("rtf named .txt: got %q, %v", got, err)
+	}
+}
+
+func TestTextHook(t *testing.T) {
+	card := regexp.MustCompile(`\b\d{4}(?: \d{4}){3}\b`)
+	docx := testDOCX(t, `<w:p><w:r><w:t>Card 4111 1111 1111 1111, passport 4510 123456</w:t></w:r></w:p>`)
+	var seen string
+	opts := DefaultOptions()
+	opts.StripPatterns = []string{`, passport.*`}
+	opts.TextHook = func(s string) (string, error) {
+		seen = s
+		return card.ReplaceAllString(s, "[CARD]"), nil
+	}
+	got, err := ExtractTextWithOptions("a.docx", docx, opts)
+	if err != nil || got != "Card [CARD]\n" {
+		t.Errorf("got %q, %v; want %q", got, err, "Card [CARD]\n")
+	}
+	// the hook sees the post-processed text
+	if seen != "Card 4111 1111 1111 1111\n" {
+		t.Errorf("hook saw %q", seen)
+	}
+
+	errRejected := errors.New("rejected")
+	opts.TextHook = func(string) (string, error) { return "", errRejected }
+	if got, err := ExtractTextWithOptions("a.docx", docx, opts); !errors.Is(err, errRejected) || got != "" {
+		t.Errorf("failing hook: got %q, %v; want %v", got, err, errRejected)
+	}
+
+	called := false
+	opts.TextHook = func(s string) (string, error) { called = true; return s, nil }
+	if _, err := ExtractTextWithOptions("a.docx", []byte("not a zip"), opts); err == nil || called {
+		t.Errorf("failed extraction: err %v, hook called %v", err, called)
+	}
+}
+
+// fakePdftotext stands a shell script in for pdftotext until the test ends.
+func fakePdftotext(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake tool is a shell script")
+	}
+	tool := filepath.Join(t.TempDir(), "pdftotext")
+	if err := os.WriteFile(tool, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	saved := pdftotextPath
+	pdftotextPath = func() (string, error) { return tool, nil }
+	t.Cleanup(func() { pdftotextPath = saved })
+}
+
+func TestPDFColumnMode(t *testing.T) {
+	// like pdftotext on a two-column page: -layout keeps the columns side by
+	// side, the default mode reads them one after another
+	fakePdftotext(t, `cat > /dev/null
+case " $* " in
+*" -layout "*) printf 'Left one    Right one\nLeft two    Right two\n\f' ;;
+*) printf 'Left one\nLeft two\n\nRight one\nRight two\n\f' ;;
+esac
+`)
+	pdf := testTextPDF("BT /F1 12 Tf 72 720 Td (x) Tj ET")
+	for _, tc := range []struct {
+		columns bool
+		want    string
+	}{
+		{false, "Left one    Right one\nLeft two    Right two\n\f"},
+		{true, "Left one\nLeft two\n\nRight one\nRight two\n\f"},
+	} {
+		opts := DefaultOptions()
+		opts.PDFColumnMode = tc.columns
+		got, err := ExtractTextWithOptions("paper.pdf", pdf, opts)
+		if err != nil || got != tc.want {
+			t.Errorf("PDFColumnMode %v: got %q, %v; want %q", tc.columns, got, err, tc.want)
+		}
+	}
+}
+
+func TestTruncatedPDF(t *testing.T) {
+	pdf := testTextPDF("BT /F1 12 Tf 72 720 Td (page text) Tj ET")
+	for _, tc := range []struct {
+		name      string
+		data      []byte
+		truncated bool
+	}{
+		{"complete", pdf, false},
+		{"trailing garbage", append(append([]byte(nil), pdf...), "\x00\x00 junk\n"...), false},
+		{"cut off", pdf[:len(pdf)-len("startxref\n0\n%%EOF\n")], true},
+		{"cut in the eof marker", pdf[:len(pdf)-3], true},
+	} {
+		res, err := ExtractDetailed("a.pdf", tc.data, DefaultOptions())
+		if err != nil || !strings.Contains(res.Text, "page text") {
+			t.Errorf("%s: got %q, %v", tc.name, res.Text, err)
+		}
+		warned := false
+		for _, w := range res.Warnings {
+			warned = warned || strings.HasPrefix(w, WarningTruncatedPDF+":")
+		}
+		if warned != tc.truncated {
+			t.Errorf("%s: warnings %q, want truncated %v", tc.name, res.Warnings, tc.truncated)
+		}
+
+		opts := DefaultOptions()
+		opts.Strict = true
+		_, err = ExtractTextWithOptions("a.pdf", tc.data, opts)
+		if tc.truncated != errors.Is(err, ErrTruncatedPDF) {
+			t.Errorf("%s with Strict: err = %v, want truncated %v", tc.name, err, tc.truncated)
+		}
+	}
+}
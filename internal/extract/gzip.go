@@ -0,0 +1,35 @@
+package extract
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+)
+
+// DefaultMaxDecompressedBytes caps the size of decompressed input when
+// Options.MaxDecompressedBytes is zero.
+const DefaultMaxDecompressedBytes = 256 << 20
+
+// ErrDecompressionLimit is returned when compressed input expands beyond the
+// configured limit, which guards against decompression bombs.
+var ErrDecompressionLimit = errors.New("decompressed size exceeds limit")
+
+var gzipMagic = []byte{0x1F, 0x8B}
+
+// gunzip decompresses gzip data, failing once the output exceeds limit bytes.
+func gunzip(data []byte, limit int64) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, ErrDecompressionLimit
+	}
+	return out, nil
+}
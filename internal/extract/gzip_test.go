@@ -0,0 +1,55 @@
+package extract
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestExtractGzip(t *testing.T) {
+	docx := testDOCX(t, `<w:p><w:r><w:t>inside</w:t></w:r></w:p>`)
+	rtf := []byte(`{\rtf1 rich}`)
+	for _, tc := range []struct {
+		name, filename string
+		data           []byte
+		want           string
+	}{
+		{"inner extension", "report.docx.gz", gzipBytes(t, docx), "inside\n"},
+		{"bare gz, signature", "report.gz", gzipBytes(t, docx), "inside\n"},
+		{"bare gz, text", "notes.gz", gzipBytes(t, []byte("plain text")), "plain text"},
+		{"gzip signature without .gz", "doc.rtf", gzipBytes(t, rtf), "rich"},
+	} {
+		got, err := ExtractText(tc.filename, tc.data)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestExtractGzipLimit(t *testing.T) {
+	bomb := gzipBytes(t, []byte(strings.Repeat("a", 1<<20)))
+	opts := DefaultOptions()
+	opts.MaxDecompressedBytes = 1 << 10
+	if _, err := ExtractTextWithOptions("a.txt.gz", bomb, opts); !errors.Is(err, ErrDecompressionLimit) {
+		t.Errorf("err = %v, want ErrDecompressionLimit", err)
+	}
+	opts.MaxDecompressedBytes = 1 << 20
+	if _, err := ExtractTextWithOptions("a.txt.gz", bomb, opts); err != nil {
+		t.Errorf("at the limit: %v", err)
+	}
+}
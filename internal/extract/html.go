@@ -0,0 +1,168 @@
+package extract
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// htmlPrescanBytes is how much of a document is searched for a charset
+// declaration, as in the HTML encoding sniffing algorithm.
+const htmlPrescanBytes = 1024
+
+// htmlBlockElements start a new line in text output.
+var htmlBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "dl": true, "dt": true, "dd": true, "pre": true,
+	"blockquote": true, "section": true, "article": true, "header": true,
+	"footer": true, "title": true, "hr": true,
+}
+
+var htmlNewlines = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// htmlText renders an HTML document as plain text. Script and style subtrees are
+// skipped, block-level elements produce line breaks and whitespace is collapsed.
+func htmlText(r io.Reader) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+	return htmlNodeText(doc, false), nil
+}
+
+// extractHTML converts an HTML page, decoded by decodeHTML, to text like
+// htmlText.
+func extractHTML(data []byte) (string, error) {
+	text, err := decodeHTML(data)
+	if err != nil {
+		return "", err
+	}
+	return htmlText(strings.NewReader(text))
+}
+
+// decodeHTML decodes an HTML page to UTF-8. A page without a byte order mark
+// is decoded by its <meta charset> or http-equiv Content-Type declaration;
+// pages without one, or whose bytes do not fit it, are decoded like TXT,
+// which recognizes UTF-8 and the common Cyrillic code pages.
+func decodeHTML(data []byte) (string, error) {
+	var text string
+	bom := bytes.HasPrefix(data, utf8BOM) || bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF})
+	if label := htmlMetaCharset(data); label != "" && !bom {
+		if enc, name := charset.Lookup(label); enc != nil && (name != "utf-8" || utf8.Valid(data)) {
+			if b, err := enc.NewDecoder().Bytes(data); err == nil {
+				text = string(b)
+			}
+		}
+	}
+	if text == "" {
+		var err error
+		if text, err = extractTXT(data, DefaultOptions()); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimPrefix(text, "\uFEFF"), nil
+}
+
+// htmlMetaCharset returns the charset declared by a meta element near the
+// start of data, or "".
+func htmlMetaCharset(data []byte) string {
+	z := html.NewTokenizer(bytes.NewReader(data[:min(len(data), htmlPrescanBytes)]))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			if string(name) != "meta" || !hasAttr {
+				continue
+			}
+			var cs, httpEquiv, content string
+			for more := true; more; {
+				var k, v []byte
+				k, v, more = z.TagAttr()
+				switch string(k) {
+				case "charset":
+					cs = string(v)
+				case "http-equiv":
+					httpEquiv = strings.ToLower(string(v))
+				case "content":
+					content = string(v)
+				}
+			}
+			if cs != "" {
+				return strings.TrimSpace(cs)
+			}
+			if httpEquiv == "content-type" {
+				if i := strings.Index(strings.ToLower(content), "charset="); i >= 0 {
+					return strings.Trim(strings.TrimSpace(content[i+len("charset="):]), `"';`)
+				}
+			}
+		}
+	}
+}
+
+// htmlNodeText renders the subtree of n like htmlText. With skipTables, tables
+// nested below n are left out.
+func htmlNodeText(root *html.Node, skipTables bool) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			// source newlines inside text are just whitespace
+			b.WriteString(htmlNewlines.Replace(n.Data))
+			return
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style", "noscript", "template", "head":
+				if n.Data != "head" {
+					return
+				}
+				// keep only the title from the head
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					if c.Type == html.ElementNode && c.Data == "title" {
+						walk(c)
+					}
+				}
+				return
+			case "table":
+				if skipTables && n != root {
+					return
+				}
+			case "td", "th":
+				// keep adjacent cells apart
+				b.WriteByte(' ')
+			}
+		}
+		block := n.Type == html.ElementNode && htmlBlockElements[n.Data]
+		if block {
+			b.WriteByte('\n')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			b.WriteByte('\n')
+		}
+	}
+	walk(root)
+	return collapseHTMLWhitespace(b.String())
+}
+
+// collapseHTMLWhitespace collapses whitespace runs within lines, trims lines and
+// drops empty ones.
+func collapseHTMLWhitespace(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
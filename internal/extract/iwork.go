@@ -0,0 +1,525 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IWA message types used by the Numbers table model.
+const (
+	iwaDocumentArchive  = 1
+	iwaSheetArchive     = 2
+	iwaTableInfoArchive = 6000
+	iwaTableModel       = 6001
+	iwaTile             = 6002
+	iwaTableDataList    = 6005
+)
+
+// Numbers cell types as stored in the cell storage buffer.
+const (
+	numbersCellNumber   = 2
+	numbersCellText     = 3
+	numbersCellDate     = 5
+	numbersCellBool     = 6
+	numbersCellDuration = 7
+	numbersCellCurrency = 10
+)
+
+type iwaObject struct {
+	typ     uint32
+	payload []byte
+}
+
+// extractNumbers extracts cell text from a Numbers spreadsheet. Tables are read from
+// the IWA archives and rendered as tab-separated rows under the sheet name; if the
+// table model cannot be read, the QuickLook PDF preview is used instead.
+func extractNumbers(ctx context.Context, data []byte, opts Options) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	if objects, err := readIWAObjects(zr); err == nil {
+		if text := numbersText(objects); strings.TrimSpace(text) != "" {
+			return text, nil
+		}
+	}
+	if text, ok, err := iworkPreview(ctx, zr, opts); ok {
+		return text, err
+	}
+	return "", errors.New("no readable tables or preview found in numbers file")
+}
+
+// iworkPreview extracts text from the QuickLook PDF preview embedded in iWork packages,
+// as a nested pdf document under opts. ok is false when the package carries no PDF
+// preview.
+func iworkPreview(ctx context.Context, zr *zip.Reader, opts Options) (string, bool, error) {
+	for _, f := range zr.File {
+		if f.Name != "QuickLook/Preview.pdf" && f.Name != "preview.pdf" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", true, err
+		}
+		pdf, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return "", true, err
+		}
+		if ctx, err = enterNested(ctx, opts); err != nil {
+			return "", true, err
+		}
+		text, _, err := extractFormat(ctx, "pdf", pdf, opts)
+		return text, true, err
+	}
+	return "", false, nil
+}
+
+// readIWAObjects decodes every Index/*.iwa entry and indexes the archived objects by id.
+func readIWAObjects(zr *zip.Reader) (map[uint64]iwaObject, error) {
+	objects := make(map[uint64]iwaObject)
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, "Index/") || !strings.HasSuffix(f.Name, ".iwa") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		stream, err := decodeIWA(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if err := parseIWAArchives(stream, objects); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+	if len(objects) == 0 {
+		return nil, errors.New("no iwa archives found")
+	}
+	return objects, nil
+}
+
+// decodeIWA undoes the IWA chunk framing: each chunk is a zero byte, a 24-bit
+// little-endian length and a raw Snappy block.
+func decodeIWA(raw []byte) ([]byte, error) {
+	var out []byte
+	for len(raw) > 0 {
+		if len(raw) < 4 || raw[0] != 0 {
+			return nil, errors.New("invalid iwa chunk header")
+		}
+		n := int(raw[1]) | int(raw[2])<<8 | int(raw[3])<<16
+		raw = raw[4:]
+		if n > len(raw) {
+			return nil, errors.New("truncated iwa chunk")
+		}
+		block, err := snappyDecode(raw[:n])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, block...)
+		raw = raw[n:]
+	}
+	return out, nil
+}
+
+// snappyDecode decodes a raw (unframed) Snappy block.
+func snappyDecode(src []byte) ([]byte, error) {
+	n, k := binary.Uvarint(src)
+	if k <= 0 {
+		return nil, errors.New("invalid snappy length")
+	}
+	src = src[k:]
+	// no element of a block expands to more than 64 bytes, so a larger
+	// length is a lie that must not size the allocation
+	if n > uint64(len(src))*64 {
+		return nil, errors.New("invalid snappy length")
+	}
+	dst := make([]byte, 0, n)
+	for len(src) > 0 {
+		tag := src[0]
+		src = src[1:]
+		var length, offset int
+		switch tag & 3 {
+		case 0: // literal
+			length = int(tag >> 2)
+			if length >= 60 {
+				extra := length - 59
+				if len(src) < extra {
+					return nil, errors.New("truncated snappy literal")
+				}
+				length = 0
+				for i := extra - 1; i >= 0; i-- {
+					length = length<<8 | int(src[i])
+				}
+				src = src[extra:]
+			}
+			length++
+			if len(src) < length {
+				return nil, errors.New("truncated snappy literal")
+			}
+			if uint64(len(dst)+length) > n {
+				return nil, errors.New("snappy length mismatch")
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+		case 1:
+			if len(src) < 1 {
+				return nil, errors.New("truncated snappy copy")
+			}
+			length = 4 + int(tag>>2)&7
+			offset = int(tag>>5)<<8 | int(src[0])
+			src = src[1:]
+		case 2:
+			if len(src) < 2 {
+				return nil, errors.New("truncated snappy copy")
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src))
+			src = src[2:]
+		case 3:
+			if len(src) < 4 {
+				return nil, errors.New("truncated snappy copy")
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src))
+			src = src[4:]
+		}
+		if offset <= 0 || offset > len(dst) {
+			return nil, errors.New("invalid snappy copy offset")
+		}
+		if uint64(len(dst)+length) > n {
+			return nil, errors.New("snappy length mismatch")
+		}
+		// copies may overlap their own output, so go byte by byte
+		start := len(dst) - offset
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[start+i])
+		}
+	}
+	if uint64(len(dst)) != n {
+		return nil, errors.New("snappy length mismatch")
+	}
+	return dst, nil
+}
+
+// parseIWAArchives walks a decompressed IWA stream: a sequence of length-prefixed
+// ArchiveInfo headers, each followed by the payloads of its messages.
+func parseIWAArchives(stream []byte, objects map[uint64]iwaObject) error {
+	for len(stream) > 0 {
+		n, k := binary.Uvarint(stream)
+		if k <= 0 || uint64(len(stream)-k) < n {
+			return errors.New("invalid archive info")
+		}
+		info := stream[k : k+int(n)]
+		stream = stream[k+int(n):]
+
+		var id uint64
+		type msgInfo struct{ typ, length uint64 }
+		var msgs []msgInfo
+		for _, f := range pbParse(info) {
+			switch f.num {
+			case 1:
+				id = f.varint
+			case 2:
+				var m msgInfo
+				for _, mf := range pbParse(f.bytes) {
+					switch mf.num {
+					case 1:
+						m.typ = mf.varint
+					case 3:
+						m.length = mf.varint
+					}
+				}
+				msgs = append(msgs, m)
+			}
+		}
+		for i, m := range msgs {
+			if uint64(len(stream)) < m.length {
+				return errors.New("truncated archive payload")
+			}
+			// the first message is the object itself; the rest are mergeable fragments
+			if i == 0 {
+				objects[id] = iwaObject{typ: uint32(m.typ), payload: stream[:m.length]}
+			}
+			stream = stream[m.length:]
+		}
+	}
+	return nil
+}
+
+// numbersText renders every table of every sheet in document order.
+func numbersText(objects map[uint64]iwaObject) string {
+	var doc *iwaObject
+	for id := range objects {
+		if o := objects[id]; o.typ == iwaDocumentArchive {
+			doc = &o
+			break
+		}
+	}
+	if doc == nil {
+		return ""
+	}
+	var sheets []string
+	for _, sheetRef := range pbRefs(doc.payload, 1) {
+		sheet, ok := objects[sheetRef]
+		if !ok || sheet.typ != iwaSheetArchive {
+			continue
+		}
+		var b strings.Builder
+		b.WriteString(pbString(sheet.payload, 1))
+		b.WriteByte('\n')
+		for _, infoRef := range pbRefs(sheet.payload, 2) {
+			info, ok := objects[infoRef]
+			if !ok || info.typ != iwaTableInfoArchive {
+				continue
+			}
+			for _, modelRef := range pbRefs(info.payload, 2) {
+				if model, ok := objects[modelRef]; ok && model.typ == iwaTableModel {
+					b.WriteString(numbersTable(objects, model.payload))
+				}
+			}
+		}
+		sheets = append(sheets, strings.TrimRight(b.String(), "\n"))
+	}
+	return strings.Join(sheets, "\n\n")
+}
+
+// numbersTable renders one TableModelArchive as tab-separated rows.
+func numbersTable(objects map[uint64]iwaObject, model []byte) string {
+	store := pbBytes(model, 4) // base data store
+	strs := make(map[uint32]string)
+	for _, ref := range pbRefs(store, 4) { // string table
+		list, ok := objects[ref]
+		if !ok || list.typ != iwaTableDataList {
+			continue
+		}
+		for _, e := range pbAll(list.payload, 3) {
+			strs[uint32(pbVarint(e, 1))] = pbString(e, 3)
+		}
+	}
+
+	type tileRef struct {
+		index uint64
+		ref   uint64
+	}
+	var tiles []tileRef
+	for _, t := range pbAll(pbBytes(store, 3), 1) {
+		tiles = append(tiles, tileRef{index: pbVarint(t, 1), ref: pbVarint(pbBytes(t, 2), 1)})
+	}
+	sort.Slice(tiles, func(i, j int) bool { return tiles[i].index < tiles[j].index })
+
+	var b strings.Builder
+	for _, t := range tiles {
+		tile, ok := objects[t.ref]
+		if !ok || tile.typ != iwaTile {
+			continue
+		}
+		for _, row := range pbAll(tile.payload, 5) {
+			storage, offsets := pbBytes(row, 5), pbBytes(row, 6)
+			wide := pbVarint(row, 7) != 0
+			var cells []string
+			for col := 0; col+1 < len(offsets); col += 2 {
+				off := int(binary.LittleEndian.Uint16(offsets[col:]))
+				if off == 0xFFFF {
+					cells = append(cells, "")
+					continue
+				}
+				if wide {
+					off *= 4
+				}
+				if off >= len(storage) {
+					cells = append(cells, "")
+					continue
+				}
+				cells = append(cells, numbersCell(storage[off:], strs))
+			}
+			for len(cells) > 0 && cells[len(cells)-1] == "" {
+				cells = cells[:len(cells)-1]
+			}
+			b.WriteString(strings.Join(cells, "\t"))
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// numbersCell decodes a version 5 cell storage record.
+func numbersCell(buf []byte, strs map[uint32]string) string {
+	if len(buf) < 12 || buf[0] != 5 {
+		return ""
+	}
+	typ := buf[1]
+	flags := binary.LittleEndian.Uint32(buf[8:12])
+	buf = buf[12:]
+	var (
+		dec, dbl, secs float64
+		hasDec, hasDbl bool
+		stringID       uint32
+	)
+	if flags&0x1 != 0 && len(buf) >= 16 {
+		dec, hasDec = decimal128(buf[:16]), true
+		buf = buf[16:]
+	}
+	if flags&0x2 != 0 && len(buf) >= 8 {
+		dbl, hasDbl = math.Float64frombits(binary.LittleEndian.Uint64(buf)), true
+		buf = buf[8:]
+	}
+	if flags&0x4 != 0 && len(buf) >= 8 {
+		secs = math.Float64frombits(binary.LittleEndian.Uint64(buf))
+		buf = buf[8:]
+	}
+	if flags&0x8 != 0 && len(buf) >= 4 {
+		stringID = binary.LittleEndian.Uint32(buf)
+	}
+	number := dbl
+	if hasDec && !hasDbl {
+		number = dec
+	}
+	switch typ {
+	case numbersCellText:
+		return strs[stringID]
+	case numbersCellNumber, numbersCellCurrency, numbersCellDuration:
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	case numbersCellBool:
+		if number != 0 {
+			return "TRUE"
+		}
+		return "FALSE"
+	case numbersCellDate:
+		// dates are seconds since the Core Data epoch
+		t := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(secs * float64(time.Second)))
+		if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+			return t.Format("2006-01-02")
+		}
+		return t.Format("2006-01-02 15:04:05")
+	}
+	return ""
+}
+
+// decimal128 converts an IEEE 754-2008 BID decimal128 value to float64.
+func decimal128(b []byte) float64 {
+	exp := (int(b[15]&0x7F)<<7 | int(b[14])>>1) - 0x1820
+	mant := new(big.Int).SetUint64(uint64(b[14] & 1))
+	for i := 13; i >= 0; i-- {
+		mant.Lsh(mant, 8)
+		mant.Or(mant, big.NewInt(int64(b[i])))
+	}
+	v, err := strconv.ParseFloat(mant.String()+"e"+strconv.Itoa(exp), 64)
+	if err != nil {
+		return 0
+	}
+	if b[15]&0x80 != 0 {
+		v = -v
+	}
+	return v
+}
+
+// pbField is a single decoded protobuf field; bytes holds length-delimited payloads.
+type pbField struct {
+	num    uint64
+	varint uint64
+	bytes  []byte
+}
+
+// pbParse decodes the top-level fields of a protobuf message. Malformed input
+// stops decoding and returns the fields read so far.
+func pbParse(b []byte) []pbField {
+	var fields []pbField
+	for len(b) > 0 {
+		key, k := binary.Uvarint(b)
+		if k <= 0 {
+			return fields
+		}
+		b = b[k:]
+		f := pbField{num: key >> 3}
+		switch key & 7 {
+		case 0:
+			v, k := binary.Uvarint(b)
+			if k <= 0 {
+				return fields
+			}
+			f.varint = v
+			b = b[k:]
+		case 1:
+			if len(b) < 8 {
+				return fields
+			}
+			f.varint = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case 2:
+			n, k := binary.Uvarint(b)
+			if k <= 0 || uint64(len(b)-k) < n {
+				return fields
+			}
+			f.bytes = b[k : k+int(n)]
+			b = b[k+int(n):]
+		case 5:
+			if len(b) < 4 {
+				return fields
+			}
+			f.varint = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		default:
+			return fields
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func pbAll(b []byte, num uint64) [][]byte {
+	var out [][]byte
+	for _, f := range pbParse(b) {
+		if f.num == num {
+			out = append(out, f.bytes)
+		}
+	}
+	return out
+}
+
+func pbBytes(b []byte, num uint64) []byte {
+	for _, f := range pbParse(b) {
+		if f.num == num {
+			return f.bytes
+		}
+	}
+	return nil
+}
+
+func pbVarint(b []byte, num uint64) uint64 {
+	for _, f := range pbParse(b) {
+		if f.num == num {
+			return f.varint
+		}
+	}
+	return 0
+}
+
+func pbString(b []byte, num uint64) string { return string(pbBytes(b, num)) }
+
+// pbRefs returns the identifiers of the repeated TSP.Reference field num.
+func pbRefs(b []byte, num uint64) []uint64 {
+	var ids []uint64
+	for _, ref := range pbAll(b, num) {
+		ids = append(ids, pbVarint(ref, 1))
+	}
+	return ids
+}
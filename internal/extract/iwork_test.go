@@ -0,0 +1,27 @@
+package extract
+
+import "testing"
+
+func TestSnappyDecode(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   []byte
+		want string
+		ok   bool
+	}{
+		{"literal and copy", []byte{8, 0x0C, 'a', 'b', 'c', 'd', 0x01, 4}, "abcdabcd", true},
+		{"overlapping copy", []byte{6, 0x00, 'a', 0x05, 1}, "aaaaaa", true},
+		{"huge length", []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F}, "", false},
+		{"length past the data", []byte{0x80, 0x80, 0x80, 0x01, 0x0C, 'a', 'b', 'c', 'd'}, "", false},
+		{"copy past the length", []byte{5, 0x0C, 'a', 'b', 'c', 'd', 0x01, 4}, "", false},
+		{"short output", []byte{9, 0x0C, 'a', 'b', 'c', 'd', 0x01, 4}, "", false},
+	} {
+		got, err := snappyDecode(tc.in)
+		if tc.ok && (err != nil || string(got) != tc.want) {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("%s: got %q, want an error", tc.name, got)
+		}
+	}
+}
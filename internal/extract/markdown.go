@@ -0,0 +1,167 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	reMDFence      = regexp.MustCompile("^(`{3,}|~{3,})")
+	reMDHeading    = regexp.MustCompile(`^#{1,6}(?:\s+|$)`)
+	reMDClosingATX = regexp.MustCompile(`\s+#+$`)
+	reMDRule       = regexp.MustCompile(`^(?:(?:\*\s*){3,}|(?:-\s*){3,}|(?:_\s*){3,}|=+)$`)
+	reMDBullet     = regexp.MustCompile(`^[-*+]\s+(?:\[[ xX]\]\s+)?`)
+	reMDOrdered    = regexp.MustCompile(`^\d{1,9}[.)]\s+`)
+	reMDTableRule  = regexp.MustCompile(`^\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?$`)
+	reMDRefDef     = regexp.MustCompile(`^\[[^\]]+\]:\s*\S`)
+	reMDImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	reMDLink       = regexp.MustCompile(`\[([^\]]*)\]\(\s*<?([^)\s>]*)>?(?:\s+"[^"]*")?\s*\)`)
+	reMDRefLink    = regexp.MustCompile(`\[([^\]]+)\]\[[^\]]*\]`)
+	reMDAutolink   = regexp.MustCompile(`<((?:https?|ftp|mailto):[^>\s]+)>`)
+	reMDCode       = regexp.MustCompile("(`+)(.+?)(`+)")
+	reMDStrong     = regexp.MustCompile(`\*\*([^*]+)\*\*|\b__([^_]+)__\b`)
+	reMDEmphasis   = regexp.MustCompile(`\*([^*\s][^*]*)\*|\b_([^_\s][^_]*)_\b`)
+	reMDStrike     = regexp.MustCompile(`~~([^~]+)~~`)
+	reMDHTMLTag    = regexp.MustCompile(`<!--.*?-->|</?[a-zA-Z][a-zA-Z0-9-]*(?:\s[^<>]*)?/?>`)
+	reMDEscape     = regexp.MustCompile(`\\([\\` + "`" + `*_{}\[\]()#+\-.!|~<>])`)
+)
+
+// extractMarkdown converts Markdown to prose, line-based like extractRST:
+// YAML front matter, heading markers, rules, list bullets and quote markers
+// are dropped, fenced code is kept verbatim without its fences, tables become
+// tab-separated rows and inline markup is reduced to its text. The lines of
+// a paragraph are joined, so each paragraph, heading and list item is one
+// line. With linkURLs, link targets follow the link text in parentheses.
+func extractMarkdown(data []byte, linkURLs bool) (string, error) {
+	src := strings.TrimPrefix(string(data), "\uFEFF")
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	lines := strings.Split(src, "\n")
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if l := strings.TrimSpace(lines[i]); l == "---" || l == "..." {
+				lines = lines[i+1:]
+				break
+			}
+		}
+	}
+
+	var out, para []string
+	flush := func() {
+		if len(para) > 0 {
+			out = append(out, mdInline(strings.Join(para, " "), linkURLs))
+			para = para[:0]
+		}
+	}
+	fence := "" // the opening fence of the code block being read
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+				continue
+			}
+			out = append(out, strings.TrimRight(line, " \t"))
+			continue
+		}
+		// quote markers, possibly nested
+		for strings.HasPrefix(trimmed, ">") {
+			trimmed = strings.TrimSpace(trimmed[1:])
+		}
+		switch {
+		case trimmed == "":
+			flush()
+		case reMDFence.MatchString(trimmed):
+			flush()
+			fence = reMDFence.FindString(trimmed)
+		case reMDRule.MatchString(trimmed), reMDRefDef.MatchString(trimmed):
+			// rules, setext underlines and link reference definitions
+			flush()
+		case reMDHeading.MatchString(trimmed):
+			flush()
+			heading := reMDClosingATX.ReplaceAllString(reMDHeading.ReplaceAllString(trimmed, ""), "")
+			if heading = mdInline(heading, linkURLs); heading != "" {
+				out = append(out, heading)
+			}
+		case strings.HasPrefix(trimmed, "|") || (strings.Contains(trimmed, " | ") && reMDTableRule.MatchString(trimmed)):
+			flush()
+			if reMDTableRule.MatchString(trimmed) {
+				continue
+			}
+			cells := strings.Split(strings.Trim(trimmed, "|"), "|")
+			for i, c := range cells {
+				cells[i] = mdInline(strings.TrimSpace(c), linkURLs)
+			}
+			out = append(out, strings.Join(cells, "\t"))
+		case reMDBullet.MatchString(trimmed):
+			flush()
+			para = append(para, reMDBullet.ReplaceAllString(trimmed, ""))
+		case reMDOrdered.MatchString(trimmed):
+			flush()
+			para = append(para, trimmed)
+		default:
+			para = append(para, strings.TrimSuffix(trimmed, "\\"))
+		}
+	}
+	flush()
+
+	var kept []string
+	for _, line := range out {
+		if strings.TrimSpace(line) != "" {
+			kept = append(kept, line)
+		}
+	}
+	if len(kept) == 0 {
+		return "", nil
+	}
+	return strings.Join(kept, "\n") + "\n", nil
+}
+
+// mdInline reduces inline Markdown to its text. Code spans are kept as
+// written; the rest of the markup is removed around them.
+func mdInline(s string, linkURLs bool) string {
+	var b strings.Builder
+	for {
+		loc := reMDCode.FindStringSubmatchIndex(s)
+		if loc == nil || s[loc[2]:loc[3]] != s[loc[6]:loc[7]] {
+			b.WriteString(mdMarkup(s, linkURLs))
+			break
+		}
+		b.WriteString(mdMarkup(s[:loc[0]], linkURLs))
+		b.WriteString(strings.TrimSpace(s[loc[4]:loc[5]]))
+		s = s[loc[1]:]
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// mdEscaped is the private-use rune range that hides backslash-escaped ASCII
+// characters from the markup patterns.
+const mdEscaped = 0xE000
+
+// mdMarkup removes links, emphasis, HTML tags and escapes from text outside
+// code spans.
+func mdMarkup(s string, linkURLs bool) string {
+	s = reMDEscape.ReplaceAllStringFunc(s, func(m string) string {
+		return string(rune(mdEscaped + int(m[1])))
+	})
+	s = reMDImage.ReplaceAllString(s, "$1")
+	s = reMDLink.ReplaceAllStringFunc(s, func(m string) string {
+		sub := reMDLink.FindStringSubmatch(m)
+		if linkURLs && sub[2] != "" && sub[2] != sub[1] && !strings.HasPrefix(sub[2], "#") {
+			return sub[1] + " (" + sub[2] + ")"
+		}
+		return sub[1]
+	})
+	s = reMDRefLink.ReplaceAllString(s, "$1")
+	s = reMDAutolink.ReplaceAllString(s, "$1")
+	s = reMDHTMLTag.ReplaceAllString(s, "")
+	s = reMDStrong.ReplaceAllString(s, "$1$2")
+	s = reMDEmphasis.ReplaceAllString(s, "$1$2")
+	s = reMDStrike.ReplaceAllString(s, "$1")
+	return strings.Map(func(r rune) rune {
+		if r >= mdEscaped && r < mdEscaped+0x80 {
+			return r - mdEscaped
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,342 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/net/html"
+)
+
+// ExtractMetadataOnly returns document properties without extracting any
+// text, reading only the parts that hold them: docProps of DOCX, XLSX and
+// PPTX, meta.xml of ODT, the package metadata of EPUB, the /Info dictionary
+// of PDF and the title and meta elements of HTML. Keys are lower-case, such
+// as "title", "author", "subject", "keywords", "description", "created" and
+// "modified" (RFC 3339 where the source date parses); properties a document
+// lacks are absent.
+func ExtractMetadataOnly(filename string, data []byte) (map[string]string, error) {
+	return ExtractMetadataOnlyWithOptions(filename, data, DefaultOptions())
+}
+
+// ExtractMetadataOnlyWithOptions is like ExtractMetadataOnly but fails with
+// ErrFormatDisabled for a format left out of Options.EnabledFormats and caps
+// decompressed PDF streams at Options.MaxDecompressedBytes.
+func ExtractMetadataOnlyWithOptions(filename string, data []byte, opts Options) (map[string]string, error) {
+	format := DetectFormat(data)
+	if format == "" || format == "zip" || format == "ole2" {
+		format = fileFormat(filename)
+	}
+	if err := opts.checkFormat(format); err != nil {
+		return nil, err
+	}
+	meta := make(map[string]string)
+	var err error
+	switch format {
+	case "docx", "xlsx", "pptx":
+		err = ooxmlMetadata(data, meta)
+	case "odt":
+		err = odtMetadata(data, meta)
+	case "epub":
+		err = epubMetadata(data, meta)
+	case "pdf":
+		err = pdfMetadata(data, meta, opts.maxDecompressedBytes())
+	case "html":
+		err = htmlMetadata(data, meta)
+	default:
+		return nil, errors.New("metadata is not supported for " + format + " files")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range meta {
+		if v = strings.TrimSpace(v); v == "" {
+			delete(meta, k)
+		} else {
+			meta[k] = v
+		}
+	}
+	return meta, nil
+}
+
+// ooxmlMetadata reads the core and extended properties of an OOXML package.
+// Either part may be missing.
+func ooxmlMetadata(data []byte, meta map[string]string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	files := zipIndex(zr)
+	var core struct {
+		Title          string `xml:"title"`
+		Subject        string `xml:"subject"`
+		Creator        string `xml:"creator"`
+		Keywords       string `xml:"keywords"`
+		Description    string `xml:"description"`
+		LastModifiedBy string `xml:"lastModifiedBy"`
+		Revision       string `xml:"revision"`
+		Category       string `xml:"category"`
+		Created        string `xml:"created"`
+		Modified       string `xml:"modified"`
+	}
+	if files["docProps/core.xml"] != nil {
+		if err := unmarshalZipXML(files, "docProps/core.xml", &core); err != nil {
+			return err
+		}
+	}
+	var app struct {
+		Application string `xml:"Application"`
+		Company     string `xml:"Company"`
+		Pages       string `xml:"Pages"`
+		Words       string `xml:"Words"`
+		Slides      string `xml:"Slides"`
+	}
+	if files["docProps/app.xml"] != nil {
+		if err := unmarshalZipXML(files, "docProps/app.xml", &app); err != nil {
+			return err
+		}
+	}
+	meta["title"] = core.Title
+	meta["subject"] = core.Subject
+	meta["author"] = core.Creator
+	meta["keywords"] = core.Keywords
+	meta["description"] = core.Description
+	meta["last_modified_by"] = core.LastModifiedBy
+	meta["revision"] = core.Revision
+	meta["category"] = core.Category
+	meta["created"] = core.Created
+	meta["modified"] = core.Modified
+	meta["application"] = app.Application
+	meta["company"] = app.Company
+	meta["pages"] = app.Pages
+	meta["words"] = app.Words
+	meta["slides"] = app.Slides
+	return nil
+}
+
+// odtMetadata reads meta.xml of an OpenDocument file.
+func odtMetadata(data []byte, meta map[string]string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	files := zipIndex(zr)
+	if files["meta.xml"] == nil {
+		return nil
+	}
+	var doc struct {
+		Meta struct {
+			Title          string   `xml:"title"`
+			Subject        string   `xml:"subject"`
+			Description    string   `xml:"description"`
+			Keywords       []string `xml:"keyword"`
+			InitialCreator string   `xml:"initial-creator"`
+			Creator        string   `xml:"creator"`
+			Created        string   `xml:"creation-date"`
+			Modified       string   `xml:"date"`
+			Generator      string   `xml:"generator"`
+			Language       string   `xml:"language"`
+			Stats          struct {
+				Pages string `xml:"page-count,attr"`
+				Words string `xml:"word-count,attr"`
+			} `xml:"document-statistic"`
+		} `xml:"meta"`
+	}
+	if err := unmarshalZipXML(files, "meta.xml", &doc); err != nil {
+		return err
+	}
+	m := doc.Meta
+	meta["title"] = m.Title
+	meta["subject"] = m.Subject
+	meta["description"] = m.Description
+	meta["keywords"] = strings.Join(m.Keywords, ", ")
+	meta["author"] = m.InitialCreator
+	meta["last_modified_by"] = m.Creator
+	meta["created"] = m.Created
+	meta["modified"] = m.Modified
+	meta["application"] = m.Generator
+	meta["language"] = m.Language
+	meta["pages"] = m.Stats.Pages
+	meta["words"] = m.Stats.Words
+	return nil
+}
+
+// epubMetadata reads the Dublin Core metadata of the EPUB package file.
+func epubMetadata(data []byte, meta map[string]string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	files := zipIndex(zr)
+	opfPath, err := epubPackagePath(files)
+	if err != nil {
+		return err
+	}
+	var pkg struct {
+		Metadata struct {
+			Titles      []string `xml:"title"`
+			Creators    []string `xml:"creator"`
+			Subjects    []string `xml:"subject"`
+			Description string   `xml:"description"`
+			Publisher   string   `xml:"publisher"`
+			Language    string   `xml:"language"`
+			Date        string   `xml:"date"`
+			Identifier  string   `xml:"identifier"`
+		} `xml:"metadata"`
+	}
+	if err := unmarshalZipXML(files, opfPath, &pkg); err != nil {
+		return err
+	}
+	m := pkg.Metadata
+	if len(m.Titles) > 0 {
+		meta["title"] = m.Titles[0]
+	}
+	meta["author"] = strings.Join(m.Creators, ", ")
+	meta["subject"] = strings.Join(m.Subjects, ", ")
+	meta["description"] = m.Description
+	meta["publisher"] = m.Publisher
+	meta["language"] = m.Language
+	meta["created"] = m.Date
+	meta["identifier"] = m.Identifier
+	return nil
+}
+
+// pdfMetadata reads the document information dictionary and counts pages.
+// Strings of encrypted PDFs cannot be read, so only the page count is given.
+func pdfMetadata(data []byte, meta map[string]string, limit int64) error {
+	f, err := parsePDF(data, limit)
+	if err != nil {
+		return err
+	}
+	meta["pages"] = strconv.Itoa(len(f.pages()))
+	if f.trailer["Encrypt"] != nil {
+		return nil
+	}
+	info := f.dict(f.trailer["Info"])
+	for key, name := range map[pdfName]string{
+		"Title": "title", "Author": "author", "Subject": "subject",
+		"Keywords": "keywords", "Creator": "application", "Producer": "producer",
+		"CreationDate": "created", "ModDate": "modified",
+	} {
+		s, ok := f.resolve(info[key]).(pdfString)
+		if !ok {
+			continue
+		}
+		v := pdfTextString(s)
+		if key == "CreationDate" || key == "ModDate" {
+			v = pdfDate(v)
+		}
+		meta[name] = v
+	}
+	return nil
+}
+
+// pdfTextString decodes a PDF text string: UTF-16BE or UTF-8 with a byte
+// order mark, otherwise PDFDocEncoding, read here as Latin-1.
+func pdfTextString(s pdfString) string {
+	b := []byte(s)
+	switch {
+	case bytes.HasPrefix(b, []byte{0xFE, 0xFF}):
+		u := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(u))
+	case bytes.HasPrefix(b, utf8BOM):
+		return string(b[len(utf8BOM):])
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// pdfDate converts a PDF date (D:YYYYMMDDHHmmSSOHH'mm') to RFC 3339, leaving
+// values it cannot parse unchanged. Missing trailing fields default as in
+// the PDF specification; a missing offset is taken as UTC.
+func pdfDate(s string) string {
+	v := strings.TrimPrefix(strings.TrimSpace(s), "D:")
+	digits := len(v) - len(strings.TrimLeft(v, "0123456789"))
+	if digits < 4 || digits > 14 || digits%2 != 0 {
+		return s
+	}
+	stamp := v[:digits] + "0101000000"[digits-4:]
+	loc := time.UTC
+	if tz := strings.ReplaceAll(v[digits:], "'", ""); tz != "" && tz != "Z" {
+		if (len(tz) != 3 && len(tz) != 5) || (tz[0] != '+' && tz[0] != '-') {
+			return s
+		}
+		h, err1 := strconv.Atoi(tz[1:3])
+		m := 0
+		var err2 error
+		if len(tz) == 5 {
+			m, err2 = strconv.Atoi(tz[3:5])
+		}
+		if err1 != nil || err2 != nil {
+			return s
+		}
+		offset := h*3600 + m*60
+		if tz[0] == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+	t, err := time.ParseInLocation("20060102150405", stamp, loc)
+	if err != nil {
+		return s
+	}
+	return t.Format(time.RFC3339)
+}
+
+// htmlMetadata reads the title and the author, description and keywords meta
+// elements of an HTML page.
+func htmlMetadata(data []byte, meta map[string]string) error {
+	text, err := decodeHTML(data)
+	if err != nil {
+		return err
+	}
+	doc, err := html.Parse(strings.NewReader(text))
+	if err != nil {
+		return err
+	}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "title":
+				if meta["title"] == "" {
+					meta["title"] = htmlNodeText(n, false)
+				}
+			case "meta":
+				var name, content string
+				for _, a := range n.Attr {
+					switch a.Key {
+					case "name":
+						name = strings.ToLower(a.Val)
+					case "content":
+						content = a.Val
+					}
+				}
+				switch name {
+				case "author", "description", "keywords", "generator":
+					if name == "generator" {
+						name = "application"
+					}
+					meta[name] = content
+				}
+			case "body":
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return nil
+}
@@ -0,0 +1,47 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMetadataOnly(t *testing.T) {
+	// text extraction of these inputs fails, which shows the body is not read:
+	// the DOCX has no document.xml and pdftotext always fails
+	fakePdftotext(t, "exit 1\n")
+	docx := testZip(t,
+		"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`,
+		"word/styles.xml", `<w:styles `+testWordNS+`/>`,
+		"docProps/core.xml", `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/">`+
+			`<dc:title>Quarterly report</dc:title><dc:creator>Анна</dc:creator><cp:keywords> </cp:keywords><dcterms:created>2024-01-02T03:04:05Z</dcterms:created></cp:coreProperties>`,
+		"docProps/app.xml", `<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties"><Application>Microsoft Office Word</Application><Pages>3</Pages></Properties>`)
+	pdf := append(testTextPDF("BT /F1 12 Tf 72 720 Td (body) Tj ET", ""),
+		"20 0 obj\n<</Title (Annual) /Author <FEFF0410043D043D0430> /CreationDate (D:20240102030405+03'00')>>\nendobj\ntrailer\n<</Root 1 0 R/Info 20 0 R>>\n"...)
+	odt := testZip(t, "mimetype", "application/vnd.oasis.opendocument.text",
+		"meta.xml", `<office:document-meta xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:meta="urn:oasis:names:tc:opendocument:xmlns:meta:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/">`+
+			`<office:meta><dc:title>Notes</dc:title><meta:keyword>a</meta:keyword><meta:keyword>b</meta:keyword><meta:document-statistic meta:page-count="2"/></office:meta></office:document-meta>`)
+	html := []byte(`<html><head><title>Page</title><meta name="Author" content="Ivan"><meta name="generator" content="Hugo"></head><body>text</body></html>`)
+	for name, data := range map[string][]byte{"a.docx": docx, "a.pdf": pdf} {
+		if _, err := ExtractText(name, data); err == nil {
+			t.Errorf("%s: text extraction succeeded", name)
+		}
+	}
+	for _, tc := range []struct {
+		filename string
+		data     []byte
+		want     map[string]string
+	}{
+		{"a.docx", docx, map[string]string{"title": "Quarterly report", "author": "Анна", "created": "2024-01-02T03:04:05Z", "application": "Microsoft Office Word", "pages": "3"}},
+		{"a.pdf", pdf, map[string]string{"title": "Annual", "author": "Анна", "created": "2024-01-02T03:04:05+03:00", "pages": "2"}},
+		{"a.odt", odt, map[string]string{"title": "Notes", "keywords": "a, b", "pages": "2"}},
+		{"a.html", html, map[string]string{"title": "Page", "author": "Ivan", "application": "Hugo"}},
+	} {
+		got, err := ExtractMetadataOnly(tc.filename, tc.data)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, %v; want %v", tc.filename, got, err, tc.want)
+		}
+	}
+	if _, err := ExtractMetadataOnly("a.txt", []byte("text")); err == nil {
+		t.Error("metadata of a txt file gave no error")
+	}
+}
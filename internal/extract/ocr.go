@@ -0,0 +1,48 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrImageOnly is returned for documents that have no text but embed images,
+// typically exported scans, when OCR is not enabled.
+var ErrImageOnly = errors.New("document contains only images; enable ocr to recognize them")
+
+// ocrImages recognizes the text of each image with tesseract and joins the
+// results as paragraphs.
+func ocrImages(ctx context.Context, images [][]byte, opts Options) (string, error) {
+	tool := opts.TesseractPath
+	if tool == "" {
+		tool = "tesseract"
+	}
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return "", err
+	}
+	var parts []string
+	for _, img := range images {
+		args := []string{"stdin", "stdout"}
+		if opts.OCRLanguages != "" {
+			args = append(args, "-l", opts.OCRLanguages)
+		}
+		cmd := exec.CommandContext(ctx, path, args...)
+		cmd.Stdin = bytes.NewReader(img)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		stop := phaseTimer(ctx, PhaseSubprocess)
+		err := cmd.Run()
+		stop()
+		if err != nil {
+			return "", fmt.Errorf("tesseract: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		if text := strings.TrimSpace(stdout.String()); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
@@ -0,0 +1,199 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// odtChange is a tracked change of an ODT document, from text:tracked-changes.
+type odtChange struct {
+	ID        string    `xml:"id,attr"`
+	Insertion *struct{} `xml:"insertion"`
+	Deletion  *struct {
+		// the deleted content, walked like the body
+		Inner []byte `xml:",innerxml"`
+	} `xml:"deletion"`
+}
+
+// odtSkipElements are not part of the running text: notes, comments and the
+// metadata of tracked changes.
+var odtSkipElements = map[string]bool{
+	"note": true, "annotation": true, "change-info": true,
+}
+
+// extractODT reads content.xml of an OpenDocument text file. Paragraphs and
+// headings end with a newline; text:tab, text:line-break and text:s become a
+// tab, a newline and spaces. Tracked changes follow Options.TrackChanges.
+func extractODT(data []byte, opts Options) (string, error) {
+	if err := opts.TrackChanges.validate(); err != nil {
+		return "", err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	f, ok := zipIndex(zr)["content.xml"]
+	if !ok {
+		return "", errors.New("content.xml not found in odt")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	return odtText(rc, opts)
+}
+
+// odtText walks ODF text content.
+func odtText(r io.Reader, opts Options) (string, error) {
+	dec := xml.NewDecoder(r)
+	var b bytes.Buffer
+	changes := make(map[string]odtChange)
+	inserted := make(map[string]bool) // open insertions by change id
+	var paraStarts []int
+	var openRev string
+	switchRev := func(rev string) {
+		if rev == openRev {
+			return
+		}
+		switch openRev {
+		case "ins":
+			b.WriteString("+}")
+		case "del":
+			b.WriteString("-}")
+		}
+		switch rev {
+		case "ins":
+			b.WriteString("{+")
+		case "del":
+			b.WriteString("{-")
+		}
+		openRev = rev
+	}
+	write := func(s, rev string) {
+		switch opts.TrackChanges {
+		case TrackChangesOriginal:
+			if rev == "ins" {
+				return
+			}
+		case TrackChangesMarkup:
+			switchRev(rev)
+		case TrackChangesInsertions:
+			if rev != "ins" {
+				return
+			}
+		default:
+			if rev == "del" {
+				return
+			}
+		}
+		b.WriteString(s)
+	}
+	rev := func() string {
+		if len(inserted) > 0 {
+			return "ins"
+		}
+		return ""
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch name := t.Name.Local; {
+			case name == "tracked-changes":
+				var tc struct {
+					Regions []odtChange `xml:"changed-region"`
+				}
+				if err := dec.DecodeElement(&tc, &t); err != nil {
+					return "", err
+				}
+				for _, c := range tc.Regions {
+					changes[c.ID] = c
+				}
+			case odtSkipElements[name]:
+				if err := dec.Skip(); err != nil {
+					return "", err
+				}
+			case name == "p" || name == "h":
+				paraStarts = append(paraStarts, b.Len())
+			case name == "change-start":
+				id := xmlAttr(t, "change-id")
+				if changes[id].Insertion != nil {
+					inserted[id] = true
+				}
+			case name == "change-end":
+				delete(inserted, xmlAttr(t, "change-id"))
+			case name == "change":
+				// a deletion point; the removed content lives in the change
+				c := changes[xmlAttr(t, "change-id")]
+				if c.Deletion == nil || opts.TrackChanges == "" || opts.TrackChanges == TrackChangesClean || opts.TrackChanges == TrackChangesInsertions {
+					continue
+				}
+				deleted, err := odtText(bytes.NewReader(append(append([]byte("<deletion>"), c.Deletion.Inner...), "</deletion>"...)), Options{DropEmptyParagraphs: true})
+				if err != nil {
+					return "", err
+				}
+				write(strings.TrimRight(deleted, "\n"), "del")
+			case len(paraStarts) == 0:
+			case name == "tab":
+				write("\t", rev())
+			case name == "line-break":
+				write("\n", rev())
+			case name == "s":
+				n := 1
+				if c, err := strconv.Atoi(xmlAttr(t, "c")); err == nil && c > 1 {
+					n = c
+				}
+				write(strings.Repeat(" ", n), rev())
+			}
+		case xml.CharData:
+			if len(paraStarts) > 0 {
+				write(odtSpaces(string(t)), rev())
+			}
+		case xml.EndElement:
+			if name := t.Name.Local; (name == "p" || name == "h") && len(paraStarts) > 0 {
+				switchRev("")
+				start := paraStarts[len(paraStarts)-1]
+				paraStarts = paraStarts[:len(paraStarts)-1]
+				drop := opts.DropEmptyParagraphs || opts.TrackChanges == TrackChangesInsertions
+				if drop && len(bytes.TrimSpace(b.Bytes()[start:])) == 0 {
+					b.Truncate(start)
+				} else {
+					b.WriteByte('\n')
+				}
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// odtSpaces collapses whitespace in ODF paragraph text, where only text:s,
+// text:tab and text:line-break are significant.
+func odtSpaces(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
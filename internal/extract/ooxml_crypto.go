@@ -0,0 +1,150 @@
+package extract
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+)
+
+var (
+	// ErrPasswordRequired is returned for an encrypted OOXML package when no
+	// password is given.
+	ErrPasswordRequired = errors.New("document is password-protected")
+	// ErrWrongPassword is returned when the given password does not open an
+	// encrypted OOXML package.
+	ErrWrongPassword = errors.New("incorrect document password")
+)
+
+// decryptOOXML returns the zip package inside an encrypted OOXML file, an OLE2
+// container with EncryptionInfo and EncryptedPackage streams. Only ECMA-376
+// standard encryption (AES with SHA-1 key derivation) is supported; agile
+// encryption is reported as an error.
+func decryptOOXML(data []byte, password string) ([]byte, error) {
+	c, err := openCFB(data)
+	if err != nil {
+		return nil, err
+	}
+	info, err := c.stream("EncryptionInfo")
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := c.stream("EncryptedPackage")
+	if err != nil {
+		return nil, err
+	}
+	if password == "" {
+		return nil, ErrPasswordRequired
+	}
+	if len(info) < 8 {
+		return nil, errors.New("encryption info is truncated")
+	}
+	le := binary.LittleEndian
+	major, minor := le.Uint16(info), le.Uint16(info[2:])
+	if minor != 2 || major < 2 || major > 4 {
+		if major == 4 && minor == 4 {
+			return nil, errors.New("agile encryption is not supported")
+		}
+		return nil, errors.New("unsupported encryption version")
+	}
+
+	// EncryptionHeader, then EncryptionVerifier
+	if len(info) < 12 {
+		return nil, errors.New("encryption info is truncated")
+	}
+	headerSize := int(le.Uint32(info[8:]))
+	header := info[12:]
+	if headerSize < 32 || len(header) < headerSize {
+		return nil, errors.New("encryption info is truncated")
+	}
+	algID, keyBits := le.Uint32(header[8:]), int(le.Uint32(header[16:]))
+	switch algID {
+	case 0x660E, 0x660F, 0x6610: // AES-128, AES-192, AES-256
+	default:
+		return nil, errors.New("unsupported encryption algorithm")
+	}
+	switch keyBits {
+	case 0:
+		keyBits = 128
+	case 128, 192, 256:
+	default:
+		return nil, errors.New("unsupported key size")
+	}
+	verifier := header[headerSize:]
+	if len(verifier) < 4+16+16+4+32 {
+		return nil, errors.New("encryption verifier is truncated")
+	}
+	salt := verifier[4:20]
+	encVerifier, encHash := verifier[20:36], verifier[40:72]
+
+	key := standardEncryptionKey(password, salt, keyBits/8)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plainVerifier := decryptECB(block, encVerifier)
+	plainHash := decryptECB(block, encHash)
+	sum := sha1.Sum(plainVerifier)
+	if !bytes.Equal(sum[:], plainHash[:sha1.Size]) {
+		return nil, ErrWrongPassword
+	}
+
+	// EncryptedPackage: the plaintext size, then the AES-ECB ciphertext
+	if len(pkg) < 8 {
+		return nil, errors.New("encrypted package is truncated")
+	}
+	size := le.Uint64(pkg)
+	body := pkg[8:]
+	body = body[:len(body)/aes.BlockSize*aes.BlockSize]
+	plain := decryptECB(block, body)
+	if size > uint64(len(plain)) {
+		return nil, errors.New("encrypted package is truncated")
+	}
+	return plain[:size], nil
+}
+
+// standardEncryptionKey derives the key of ECMA-376 standard encryption
+// (MS-OFFCRYPTO 2.3.4.7): 50000 rounds of salted SHA-1 over the password, then
+// the CryptDeriveKey expansion.
+func standardEncryptionKey(password string, salt []byte, keyLen int) []byte {
+	pw := utf16.Encode([]rune(password))
+	buf := make([]byte, 0, len(salt)+2*len(pw))
+	buf = append(buf, salt...)
+	for _, u := range pw {
+		buf = binary.LittleEndian.AppendUint16(buf, u)
+	}
+	h := sha1.Sum(buf)
+	var iter [4 + sha1.Size]byte
+	for i := uint32(0); i < 50000; i++ {
+		binary.LittleEndian.PutUint32(iter[:4], i)
+		copy(iter[4:], h[:])
+		h = sha1.Sum(iter[:])
+	}
+	// block key 0
+	final := sha1.Sum(append(h[:], 0, 0, 0, 0))
+
+	derive := func(pad byte) [sha1.Size]byte {
+		var b [64]byte
+		for i := range b {
+			b[i] = pad
+		}
+		for i, v := range final {
+			b[i] ^= v
+		}
+		return sha1.Sum(b[:])
+	}
+	x1, x2 := derive(0x36), derive(0x5C)
+	return append(x1[:], x2[:]...)[:keyLen]
+}
+
+// decryptECB decrypts whole blocks in electronic codebook mode, which standard
+// encryption uses without an IV.
+func decryptECB(block interface{ Decrypt(dst, src []byte) }, src []byte) []byte {
+	out := make([]byte, len(src)/aes.BlockSize*aes.BlockSize)
+	for i := 0; i < len(out); i += aes.BlockSize {
+		block.Decrypt(out[i:i+aes.BlockSize], src[i:i+aes.BlockSize])
+	}
+	return out
+}
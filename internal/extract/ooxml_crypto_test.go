@@ -0,0 +1,91 @@
+package extract
+
+import (
+	"crypto/aes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// testEncryptedOOXML wraps pkg in ECMA-376 standard encryption with AES of
+// keyBits bits, which may be any value to exercise the header checks.
+func testEncryptedOOXML(t *testing.T, pkg []byte, password string, keyBits int) []byte {
+	t.Helper()
+	le := binary.LittleEndian
+	salt := []byte("0123456789abcdef")
+	keyLen := keyBits / 8
+	if keyBits != 128 && keyBits != 192 && keyBits != 256 {
+		keyLen = 16
+	}
+	block, err := aes.NewCipher(standardEncryptionKey(password, salt, keyLen))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypt := func(src []byte) []byte {
+		src = append(src, make([]byte, (aes.BlockSize-len(src)%aes.BlockSize)%aes.BlockSize)...)
+		out := make([]byte, len(src))
+		for i := 0; i < len(src); i += aes.BlockSize {
+			block.Encrypt(out[i:i+aes.BlockSize], src[i:i+aes.BlockSize])
+		}
+		return out
+	}
+
+	info := le.AppendUint16(nil, 3)
+	info = le.AppendUint16(info, 2)
+	info = le.AppendUint32(info, 0x24) // flags
+	header := make([]byte, 32)
+	le.PutUint32(header[8:], 0x660E)
+	le.PutUint32(header[12:], 0x8004)
+	le.PutUint32(header[16:], uint32(keyBits))
+	le.PutUint32(header[20:], 0x18)
+	info = le.AppendUint32(info, uint32(len(header)))
+	info = append(info, header...)
+	verifier := []byte("fedcba9876543210")
+	hash := sha1.Sum(verifier)
+	info = le.AppendUint32(info, uint32(len(salt)))
+	info = append(info, salt...)
+	info = append(info, encrypt(append([]byte(nil), verifier...))...)
+	info = le.AppendUint32(info, sha1.Size)
+	info = append(info, encrypt(append([]byte(nil), hash[:]...))...)
+
+	enc := le.AppendUint64(nil, uint64(len(pkg)))
+	enc = append(enc, encrypt(append([]byte(nil), pkg...))...)
+	return testCFB(t, "EncryptionInfo", string(info), "EncryptedPackage", string(enc))
+}
+
+func TestPasswordProtectedDOCX(t *testing.T) {
+	docx := testDOCX(t, `<w:p><w:r><w:t>secret text</w:t></w:r></w:p>`)
+	data := testEncryptedOOXML(t, docx, "pass", 128)
+	if got := DetectFormat(data); got != "encrypted-ooxml" {
+		t.Errorf("DetectFormat = %q, want encrypted-ooxml", got)
+	}
+	for _, tc := range []struct {
+		password string
+		want     string
+		err      error
+	}{
+		{"pass", "secret text\n", nil},
+		{"", "", ErrPasswordRequired},
+		{"wrong", "", ErrWrongPassword},
+	} {
+		opts := DefaultOptions()
+		opts.DOCXPassword = tc.password
+		got, err := ExtractTextWithOptions("secret.docx", data, opts)
+		if !errors.Is(err, tc.err) || got != tc.want {
+			t.Errorf("password %q: got %q, %v; want %q, %v", tc.password, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestPasswordProtectedDOCXKeySize(t *testing.T) {
+	docx := testDOCX(t, `<w:p><w:r><w:t>secret text</w:t></w:r></w:p>`)
+	for _, bits := range []int{64, 321, 4096, 1 << 31} {
+		opts := DefaultOptions()
+		opts.DOCXPassword = "pass"
+		_, err := ExtractTextWithOptions("secret.docx", testEncryptedOOXML(t, docx, "pass", bits), opts)
+		if err == nil || err.Error() != "unsupported key size" {
+			t.Errorf("key size %d: err = %v, want unsupported key size", bits, err)
+		}
+	}
+}
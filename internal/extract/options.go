@@ -0,0 +1,366 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options tunes extraction. The zero Options, which DefaultOptions returns,
+// reproduces the behavior of ExtractText: every zero field means the
+// documented default. Validate reports invalid values before any work is done.
+type Options struct {
+	// DropEmptyParagraphs drops DOCX and ODT paragraphs without visible text.
+	// By default they are kept as empty lines: the option says what to drop
+	// rather than what to keep so that the zero Options keep them.
+	DropEmptyParagraphs bool `json:"drop_empty_paragraphs"`
+
+	// DistinguishBreaks ends DOCX paragraphs with a blank line ("\n\n") so they
+	// stay distinguishable from line breaks within a paragraph (w:br), which
+	// remain a single "\n". By default both are a single "\n".
+	DistinguishBreaks bool `json:"distinguish_breaks"`
+
+	// RequireText makes extraction fail with ErrNoText when the output has no
+	// visible text. Without it such output is normalized to "" for every format.
+	RequireText bool `json:"require_text"`
+
+	// BestEffort enables recovery paths for damaged input, such as reading a DOCX
+	// body straight from its local zip header when the central directory is broken.
+	BestEffort bool `json:"best_effort"`
+
+	// Strict makes damage that is otherwise reported in Result.Warnings an
+	// error, such as a PDF cut off before its end (ErrTruncatedPDF).
+	Strict bool `json:"strict"`
+
+	// StripPatterns are regular expressions whose matches are removed from the
+	// output of every format, such as recurring stamps or OCR noise. Patterns
+	// apply to one line at a time; a line left empty by them is dropped.
+	StripPatterns []string `json:"strip_patterns"`
+
+	// TabHandling controls tabs in the output: keep (default), space or remove.
+	TabHandling TabHandling `json:"tab_handling"`
+
+	// ExpandTabs, when positive, replaces tabs with spaces up to the next tab stop
+	// every ExpandTabs columns, so columns line up in fixed-width output. It is
+	// applied before TabHandling, which then has no tabs left to handle. At
+	// most MaxExpandTabs.
+	ExpandTabs int `json:"expand_tabs"`
+
+	// MaxConsecutiveBlankLines, when positive, shortens runs of blank lines in
+	// the output of every format, such as the gaps of PDF layout text, to at
+	// most that many. Zero leaves them as the extractor produced them.
+	MaxConsecutiveBlankLines int `json:"max_consecutive_blank_lines"`
+
+	// FirstUnitOnly limits extraction to the first page of a PDF, the first
+	// section of a DOCX (up to the first section break), the first worksheet
+	// of an XLSX or the first slide of a PPTX, for quick previews.
+	FirstUnitOnly bool `json:"first_unit_only"`
+
+	// FirstPage and LastPage limit PDF extraction to a range of pages,
+	// numbered from 1. Zero starts at the first page or ends at the last.
+	// With FirstUnitOnly only FirstPage is extracted.
+	FirstPage int `json:"first_page"`
+	LastPage  int `json:"last_page"`
+
+	// PDFColumnMode extracts PDFs in reading order rather than in physical
+	// layout, so multi-column pages (papers, newsletters) come out one column
+	// after another instead of interleaved line by line. Table-like alignment
+	// within a line is not preserved.
+	PDFColumnMode bool `json:"pdf_column_mode"`
+
+	// EncodingCandidates lists the single-byte code pages tried, in order of
+	// preference on equal scores, for TXT, CSV and TSV input that is neither
+	// UTF-8 nor UTF-16, by the names DetectEncoding reports ("windows-1251",
+	// "koi8-u", "cp866", ...). Empty means the Cyrillic and Western European
+	// defaults.
+	EncodingCandidates []string `json:"encoding_candidates"`
+
+	// TrackChanges selects how tracked revisions are rendered: clean (default),
+	// original, markup or insertions.
+	TrackChanges TrackChangesMode `json:"track_changes"`
+
+	// ListMarkers prefixes DOCX list paragraphs with their rendered number or a
+	// "- " bullet, following numbering.xml start values, overrides and restarts.
+	ListMarkers bool `json:"list_markers"`
+
+	// AnnotateLanguage wraps DOCX runs whose w:lang differs from the document
+	// default in inline [lang:ru-RU]...[/lang] markers.
+	AnnotateLanguage bool `json:"annotate_language"`
+
+	// IncludeFootnotes marks DOCX footnote references inline as [n] and appends the
+	// notes after the body. Notes are numbered 1..n in order of first reference,
+	// regardless of the ids stored in the file. Endnotes follow the footnotes,
+	// marked and numbered the same way in lower-case roman numerals: [i], [ii].
+	IncludeFootnotes bool `json:"include_footnotes"`
+
+	// IncludeHeadersFooters puts the text of DOCX page headers before the body
+	// and of page footers after it. The first-page, odd and even variants of
+	// every section are included, each distinct text once.
+	IncludeHeadersFooters bool `json:"include_headers_footers"`
+
+	// IncludeComments appends review comments (author, location, text) to PPTX and
+	// XLSX output. ExtractComments returns them in structured form.
+	IncludeComments bool `json:"include_comments"`
+
+	// IncludeSpeakerNotes appends the speaker notes of each PPTX slide after
+	// its text, under a [Notes] line.
+	IncludeSpeakerNotes bool `json:"include_speaker_notes"`
+
+	// IncludeHiddenText keeps DOCX runs formatted as hidden (w:vanish, directly
+	// or through a style). By default hidden text is left out, and so are
+	// paragraphs that contain nothing else.
+	IncludeHiddenText bool `json:"include_hidden_text"`
+
+	// ExcludeTextboxes leaves out the text of DOCX text boxes and shapes, which
+	// otherwise follows the paragraph that anchors them. Set it when shapes
+	// hold decorative labels rather than content. Like DropEmptyParagraphs it
+	// is phrased as an exclusion so that the zero Options include the text.
+	ExcludeTextboxes bool `json:"exclude_textboxes"`
+
+	// KeepTeXMath keeps the math of TeX files ($...$, \[...\], equation and
+	// similar environments) as source instead of dropping it.
+	KeepTeXMath bool `json:"keep_tex_math"`
+
+	// IncludeLinkURLs appends the target of each Markdown and DOCX link to its
+	// text, as "text (https://...)". Links to anchors within the document are
+	// left as text.
+	IncludeLinkURLs bool `json:"include_link_urls"`
+
+	// AnnotateBookmarks emits a [#name] marker where a DOCX bookmark starts.
+	AnnotateBookmarks bool `json:"annotate_bookmarks"`
+
+	// DOCXPassword opens password-protected DOCX files that use ECMA-376
+	// standard encryption. Encrypted files without it fail with
+	// ErrPasswordRequired.
+	DOCXPassword string `json:"docx_password"`
+
+	// OCR recognizes with tesseract the embedded images of DOCX and PPTX files
+	// that contain no text, which otherwise fail with ErrImageOnly, and PDF pages
+	// without a text layer (see OCRMinPageChars).
+	OCR bool `json:"ocr"`
+
+	// OCRMinPageChars is the number of visible characters below which a PDF page
+	// is treated as scanned and, with OCR, rendered and recognized instead of
+	// using its text layer. Values below 1 mean only pages without text.
+	OCRMinPageChars int `json:"ocr_min_page_chars"`
+
+	// PDFImageOCR, together with OCR, also recognizes raster images embedded in
+	// PDFs (scanned figures) and appends their text labeled by page.
+	PDFImageOCR bool `json:"pdf_image_ocr"`
+
+	// OCRLanguages is passed to tesseract as -l, e.g. "rus+eng"; empty uses
+	// tesseract's default.
+	OCRLanguages string `json:"ocr_languages"`
+
+	// Format forces the extractor by format name ("pdf", "docx", "rtf", "txt",
+	// ...), bypassing extension and signature detection. It is set by ExtractAs
+	// and by the server's top-level format field.
+	Format string `json:"-"`
+
+	// Encoding forces the encoding of TXT, CSV and TSV input, skipping
+	// detection: "utf-8", "utf-16le", "utf-16be", a code page name as
+	// DetectEncoding reports it, or another WHATWG label such as "cp1251".
+	// It is set by the server's top-level encoding field.
+	Encoding string `json:"-"`
+
+	// MaxDecompressedBytes caps the size of compressed data after
+	// decompression: gzip input, PDF streams and a DOCX body recovered from a
+	// damaged archive. Zero means DefaultMaxDecompressedBytes.
+	MaxDecompressedBytes int64 `json:"-"`
+
+	// MaxRecursionDepth limits how deeply sub-documents may nest, such as gzip
+	// inside gzip or a DOCX imported into a DOCX, before extraction fails with
+	// ErrMaxDepthExceeded; zero means DefaultMaxRecursionDepth.
+	MaxRecursionDepth int `json:"-"`
+
+	// Timeout, when positive, abandons an extraction that takes longer, which
+	// then fails with context.DeadlineExceeded as if its context had that
+	// deadline. Zero means no limit. Text streamed by ExtractTextReader is
+	// not covered.
+	Timeout time.Duration `json:"-"`
+
+	// CHMTool is the program used to unpack .chm files: 7z (or 7za) or
+	// extract_chmLib. Empty means the first of those found in PATH.
+	CHMTool string `json:"-"`
+
+	// TesseractPath is the tesseract binary used for OCR; empty looks it up in PATH.
+	TesseractPath string `json:"-"`
+
+	// EnabledFormats, when not empty, lists the formats that may be extracted,
+	// by the names DetectFormat and the file extensions use ("pdf", "docx",
+	// "html", "txt", ...); any other format fails with ErrFormatDisabled,
+	// however it was detected. Embedders use it to rule out extractors, such
+	// as the pdftotext path in a sandbox. Aliases like "docm" and "htm" are
+	// covered by the format they map to.
+	EnabledFormats []string `json:"-"`
+
+	// TextHook, when set, receives the final text of every extraction and
+	// returns the text to use instead, e.g. with sensitive numbers redacted. An
+	// error from it fails the extraction.
+	TextHook func(text string) (string, error) `json:"-"`
+}
+
+// MaxExpandTabs is the widest tab stop Options.ExpandTabs accepts; wider ones
+// would let a single tab expand into an arbitrary amount of output.
+const MaxExpandTabs = 64
+
+// TrackChangesMode selects which side of tracked revisions ends up in the text.
+type TrackChangesMode string
+
+const (
+	TrackChangesClean      TrackChangesMode = "clean"      // accept all changes: keep insertions, drop deletions (default)
+	TrackChangesOriginal   TrackChangesMode = "original"   // reject all changes: drop insertions, keep deletions
+	TrackChangesMarkup     TrackChangesMode = "markup"     // keep both, as {+inserted+} and {-deleted-}
+	TrackChangesInsertions TrackChangesMode = "insertions" // only the inserted text, dropping paragraphs without any
+)
+
+func (m TrackChangesMode) validate() error {
+	switch m {
+	case "", TrackChangesClean, TrackChangesOriginal, TrackChangesMarkup, TrackChangesInsertions:
+		return nil
+	}
+	return errors.New("unknown track changes mode: " + string(m))
+}
+
+// Validate reports option values that are out of range, unknown or that
+// contradict each other. Extraction validates its options first.
+func (o Options) Validate() error {
+	if err := o.TrackChanges.validate(); err != nil {
+		return err
+	}
+	switch o.TabHandling {
+	case "", TabsKeep, TabsSpace, TabsRemove:
+	default:
+		return errors.New("unknown tab handling: " + string(o.TabHandling))
+	}
+	if o.ExpandTabs < 0 {
+		return errors.New("expand tabs must not be negative")
+	}
+	if o.ExpandTabs > MaxExpandTabs {
+		return fmt.Errorf("expand tabs must be at most %d", MaxExpandTabs)
+	}
+	if o.MaxConsecutiveBlankLines < 0 {
+		return errors.New("max consecutive blank lines must not be negative")
+	}
+	for _, p := range o.StripPatterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("invalid strip pattern %q: %w", p, err)
+		}
+	}
+	if o.PDFImageOCR && !o.OCR {
+		return errors.New("pdf image ocr requires ocr")
+	}
+	if o.Encoding != "" && textEncoding(o.Encoding) == nil {
+		return errors.New("unknown encoding: " + o.Encoding)
+	}
+	for _, name := range o.EncodingCandidates {
+		if codePage(name) == nil {
+			return errors.New("unknown encoding candidate: " + name)
+		}
+	}
+	if o.MaxDecompressedBytes < 0 {
+		return errors.New("max decompressed bytes must not be negative")
+	}
+	if o.MaxRecursionDepth < 0 {
+		return errors.New("max recursion depth must not be negative")
+	}
+	if o.FirstPage < 0 || o.LastPage < 0 {
+		return errors.New("page numbers must not be negative")
+	}
+	if o.FirstPage > 0 && o.LastPage > 0 && o.FirstPage > o.LastPage {
+		return fmt.Errorf("first page %d is after last page %d", o.FirstPage, o.LastPage)
+	}
+	if o.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+	return nil
+}
+
+// DefaultOptions returns the options used by ExtractText, which are the zero
+// Options.
+func DefaultOptions() Options {
+	return Options{}
+}
+
+// encodingCandidates resolves EncodingCandidates, which Validate has checked.
+func (o Options) encodingCandidates() []encodingCandidate {
+	if len(o.EncodingCandidates) == 0 {
+		return legacyCandidates
+	}
+	candidates := make([]encodingCandidate, 0, len(o.EncodingCandidates))
+	for _, name := range o.EncodingCandidates {
+		if enc := codePage(name); enc != nil {
+			candidates = append(candidates, encodingCandidate{strings.ToLower(name), enc})
+		}
+	}
+	return candidates
+}
+
+// pdfPageRange resolves FirstPage, LastPage and FirstUnitOnly to the pages to
+// extract, numbered from 1; last is 0 when the range runs to the end.
+func (o Options) pdfPageRange() (first, last int) {
+	first, last = max(o.FirstPage, 1), o.LastPage
+	if o.FirstUnitOnly {
+		last = first
+	}
+	return first, last
+}
+
+// pdfPageArgs returns the -f and -l arguments of the Poppler tools for
+// pdfPageRange, or none for the whole document.
+func (o Options) pdfPageArgs() []string {
+	first, last := o.pdfPageRange()
+	var args []string
+	if first > 1 {
+		args = append(args, "-f", strconv.Itoa(first))
+	}
+	if last > 0 {
+		args = append(args, "-l", strconv.Itoa(last))
+	}
+	return args
+}
+
+func (o Options) maxDecompressedBytes() int64 {
+	if o.MaxDecompressedBytes > 0 {
+		return o.MaxDecompressedBytes
+	}
+	return DefaultMaxDecompressedBytes
+}
+
+// ErrFormatDisabled is returned for a format left out of Options.EnabledFormats.
+var ErrFormatDisabled = errors.New("format is disabled")
+
+// checkFormat fails with ErrFormatDisabled for a format left out of
+// EnabledFormats.
+func (o Options) checkFormat(format string) error {
+	if !o.formatEnabled(format) {
+		return fmt.Errorf("%w: %s", ErrFormatDisabled, format)
+	}
+	return nil
+}
+
+func (o Options) formatEnabled(format string) bool {
+	if len(o.EnabledFormats) == 0 {
+		return true
+	}
+	for _, f := range o.EnabledFormats {
+		f = strings.TrimPrefix(strings.ToLower(f), ".")
+		if alias, ok := extensionAliases[f]; ok {
+			f = alias
+		}
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Options) maxRecursionDepth() int {
+	if o.MaxRecursionDepth > 0 {
+		return o.MaxRecursionDepth
+	}
+	return DefaultMaxRecursionDepth
+}
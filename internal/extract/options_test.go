@@ -0,0 +1,208 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateExpandTabs(t *testing.T) {
+	for _, tc := range []struct {
+		n  int
+		ok bool
+	}{
+		{-1, false}, {0, true}, {8, true}, {MaxExpandTabs, true}, {MaxExpandTabs + 1, false}, {1000000000, false},
+	} {
+		opts := DefaultOptions()
+		opts.ExpandTabs = tc.n
+		if err := opts.Validate(); (err == nil) != tc.ok {
+			t.Errorf("ExpandTabs %d: Validate() = %v", tc.n, err)
+		}
+	}
+	opts := DefaultOptions()
+	opts.ExpandTabs = 1000000000
+	if _, err := ExtractTextWithOptions("a.txt", []byte("a\tb"), opts); err == nil {
+		t.Error("extraction accepted ExpandTabs 1000000000")
+	}
+}
+
+func TestValidateRanges(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		set  func(*Options)
+		ok   bool
+	}{
+		{"page range", func(o *Options) { o.FirstPage, o.LastPage = 2, 5 }, true},
+		{"single page", func(o *Options) { o.FirstPage, o.LastPage = 3, 3 }, true},
+		{"open start", func(o *Options) { o.LastPage = 4 }, true},
+		{"open end", func(o *Options) { o.FirstPage = 4 }, true},
+		{"first after last", func(o *Options) { o.FirstPage, o.LastPage = 5, 2 }, false},
+		{"negative first page", func(o *Options) { o.FirstPage = -1 }, false},
+		{"negative last page", func(o *Options) { o.LastPage = -1 }, false},
+		{"timeout", func(o *Options) { o.Timeout = time.Second }, true},
+		{"negative timeout", func(o *Options) { o.Timeout = -time.Second }, false},
+		{"negative max blank lines", func(o *Options) { o.MaxConsecutiveBlankLines = -1 }, false},
+		{"negative max decompressed bytes", func(o *Options) { o.MaxDecompressedBytes = -1 }, false},
+		{"negative max recursion depth", func(o *Options) { o.MaxRecursionDepth = -1 }, false},
+	} {
+		opts := DefaultOptions()
+		tc.set(&opts)
+		if err := opts.Validate(); (err == nil) != tc.ok {
+			t.Errorf("%s: Validate() = %v, want ok %v", tc.name, err, tc.ok)
+		}
+	}
+}
+
+func TestPDFPageRange(t *testing.T) {
+	pdf := testTextPDF("BT /F1 12 Tf 72 720 Td (one) Tj ET", "BT /F1 12 Tf 72 720 Td (two) Tj ET", "BT /F1 12 Tf 72 720 Td (three) Tj ET")
+	for _, tc := range []struct {
+		first, last int
+		unitOnly    bool
+		want        []string
+	}{
+		{0, 0, false, []string{"one", "two", "three"}},
+		{2, 0, false, []string{"two", "three"}},
+		{0, 2, false, []string{"one", "two"}},
+		{2, 2, false, []string{"two"}},
+		{2, 3, true, []string{"two"}},
+		{3, 9, false, []string{"three"}},
+		{5, 0, false, nil},
+	} {
+		opts := DefaultOptions()
+		opts.FirstPage, opts.LastPage, opts.FirstUnitOnly = tc.first, tc.last, tc.unitOnly
+		got, err := ExtractTextWithOptions("a.pdf", pdf, opts)
+		if err != nil && !errors.Is(err, ErrPDFNoText) {
+			t.Errorf("pages %d-%d: %v", tc.first, tc.last, err)
+			continue
+		}
+		if words := strings.Fields(got); !slices.Equal(words, tc.want) {
+			t.Errorf("pages %d-%d, first unit only %v: got %q; want %q", tc.first, tc.last, tc.unitOnly, words, tc.want)
+		}
+	}
+
+	// pdftotext gets the same range as -f and -l
+	fakePdftotext(t, `cat > /dev/null; echo "$*"`)
+	opts := DefaultOptions()
+	opts.FirstPage, opts.LastPage = 2, 3
+	if got, err := ExtractTextWithOptions("a.pdf", pdf, opts); err != nil || !strings.Contains(got, "-f 2 -l 3") {
+		t.Errorf("pdftotext arguments: got %q, %v", got, err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Timeout = 100 * time.Millisecond
+	fakePdftotext(t, "sleep 5")
+	start := time.Now()
+	if _, err := ExtractTextWithOptions("a.pdf", testTextPDF("BT /F1 12 Tf 72 720 Td (x) Tj ET"), opts); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v; want context.DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 3*time.Second {
+		t.Errorf("extraction took %v", d)
+	}
+}
+
+func TestEnabledFormatsEveryEntryPoint(t *testing.T) {
+	pdf := append([]byte("%PDF-1.4\n"), testPDF("1 0 obj\n<</Title(t)>>\nendobj\n")...)
+	docx := testDOCX(t, `<w:p><w:r><w:t>text</w:t></w:r></w:p>`)
+	html := []byte(`<table><tr><td>cell</td></tr></table>`)
+	opts := DefaultOptions()
+	opts.EnabledFormats = []string{"rtf"}
+	for name, run := range map[string]func() error{
+		"ExtractTextWithOptions pdf": func() error { _, err := ExtractTextWithOptions("a.pdf", pdf, opts); return err },
+		"ExtractTextReader txt": func() error {
+			return ExtractTextReader("a.txt", bytes.NewReader([]byte("text")), io.Discard, opts)
+		},
+		"ExtractTextReader no extension": func() error {
+			return ExtractTextReader("", bytes.NewReader([]byte("text")), io.Discard, opts)
+		},
+		"ExtractTablesWithOptions html": func() error { _, err := ExtractTablesWithOptions("a.html", html, opts); return err },
+		"ExtractTablesWithOptions docx": func() error { _, err := ExtractTablesWithOptions("a.docx", docx, opts); return err },
+		"ExtractMetadataOnlyWithOptions pdf": func() error {
+			_, err := ExtractMetadataOnlyWithOptions("a.pdf", pdf, opts)
+			return err
+		},
+		"ExtractMetadataOnlyWithOptions docx": func() error {
+			_, err := ExtractMetadataOnlyWithOptions("a.docx", docx, opts)
+			return err
+		},
+		"ExtractOutlineWithOptions":   func() error { _, err := ExtractOutlineWithOptions("a.docx", docx, opts); return err },
+		"ExtractPDFLinksWithOptions":  func() error { _, err := ExtractPDFLinksWithOptions(pdf, opts); return err },
+		"ExtractPDFLayersWithOptions": func() error { _, err := ExtractPDFLayersWithOptions(pdf, opts); return err },
+		"ExtractPDFLayoutWithOptions": func() error { _, err := ExtractPDFLayoutWithOptions(pdf, opts); return err },
+	} {
+		if err := run(); !errors.Is(err, ErrFormatDisabled) {
+			t.Errorf("%s: err = %v, want ErrFormatDisabled", name, err)
+		}
+	}
+
+	opts.EnabledFormats = []string{"pdf"}
+	if _, err := ExtractMetadataOnlyWithOptions("a.pdf", pdf, opts); err != nil {
+		t.Errorf("pdf enabled: ExtractMetadataOnlyWithOptions: %v", err)
+	}
+}
+
+func TestZeroOptionsAreDefault(t *testing.T) {
+	if !reflect.DeepEqual(Options{}, DefaultOptions()) {
+		t.Errorf("DefaultOptions() = %+v, want the zero Options", DefaultOptions())
+	}
+	if err := (Options{}).Validate(); err != nil {
+		t.Errorf("Options{}.Validate() = %v", err)
+	}
+	docx := testDOCX(t, `<w:p><w:r><w:t>first</w:t></w:r></w:p><w:p/>`+
+		`<w:p><w:r><w:t>anchor</w:t></w:r><w:r><w:pict><v:textbox><w:txbxContent><w:p><w:r><w:t>boxed</w:t></w:r></w:p></w:txbxContent></v:textbox></w:pict></w:r></w:p>`)
+	want, err := ExtractText("a.docx", docx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ExtractTextWithOptions("a.docx", docx, Options{}); err != nil || got != want {
+		t.Errorf("Options{}: got %q, %v; want %q", got, err, want)
+	}
+	if got, err := ExtractTextWithOptions("a.docx", docx, Options{DropEmptyParagraphs: true}); err != nil || strings.Contains(got, "first\n\n") {
+		t.Errorf("DropEmptyParagraphs: got %q, %v; want the empty paragraph dropped", got, err)
+	}
+	if got, err := ExtractTextWithOptions("a.docx", docx, Options{ExcludeTextboxes: true}); err != nil || strings.Contains(got, "boxed") {
+		t.Errorf("ExcludeTextboxes: got %q, %v; want no text box text", got, err)
+	}
+	if !strings.Contains(want, "first\n\n") || !strings.Contains(want, "boxed") {
+		t.Errorf("default: got %q, want the empty paragraph and the text box", want)
+	}
+}
+
+func TestEnabledFormatsNested(t *testing.T) {
+	numbers := testZip(t, "preview.pdf", string(testTextPDF("BT /F1 12 Tf 72 720 Td (preview) Tj ET")))
+	rels := `<Relationships ` + testRelsNS + `><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/aFChunk" Target="chunk.html"/></Relationships>`
+	docx := testDOCX(t, `<w:p><w:r><w:t>before</w:t></w:r></w:p><w:altChunk r:id="rId1"/>`,
+		"word/_rels/document.xml.rels", rels,
+		"word/chunk.html", `<html><body><p>imported</p></body></html>`)
+	chmTool := fakeCHMTool(t)
+	for _, tc := range []struct {
+		name     string
+		filename string
+		data     []byte
+		formats  []string
+		want     string
+		err      error
+	}{
+		{"numbers preview, pdf disabled", "a.numbers", numbers, []string{"numbers"}, "", ErrFormatDisabled},
+		{"numbers preview", "a.numbers", numbers, []string{"numbers", "pdf"}, "preview\n\f", nil},
+		{"altChunk, html disabled", "a.docx", docx, []string{"docx"}, "before\n", nil},
+		{"altChunk", "a.docx", docx, []string{"docx", "html"}, "before\nimported\n", nil},
+		{"chm, html disabled", "help.chm", []byte("ITSF"), []string{"chm"}, "", ErrFormatDisabled},
+		{"chm", "help.chm", []byte("ITSF"), []string{"chm", "html"}, "Topic B\n\nTopic A\n\nAppendix", nil},
+	} {
+		opts := DefaultOptions()
+		opts.EnabledFormats = tc.formats
+		opts.CHMTool = chmTool
+		got, err := ExtractTextWithOptions(tc.filename, tc.data, opts)
+		if got != tc.want || !errors.Is(err, tc.err) {
+			t.Errorf("%s: got %q, %v; want %q, %v", tc.name, got, err, tc.want, tc.err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Heading is an entry of a document outline.
+type Heading struct {
+	Level int    `json:"level"` // 1 for top-level headings
+	Text  string `json:"text"`
+}
+
+// docxDefaultSize is the font size Word assumes when no level sets w:sz, in
+// half-points.
+const docxDefaultSize = 20
+
+// outlineMaxHeadingRunes bounds the length of a paragraph the formatting
+// heuristic accepts as a heading.
+const outlineMaxHeadingRunes = 120
+
+// ExtractOutline returns the headings of a DOCX file in document order.
+// Headings come from heading styles and w:outlineLvl. When the document uses
+// none, they are inferred from direct formatting: short paragraphs outside
+// tables set larger than the body text are headings, larger sizes ranking
+// higher, and bold paragraphs at body size rank lowest.
+func ExtractOutline(filename string, data []byte) ([]Heading, error) {
+	return ExtractOutlineWithOptions(filename, data, DefaultOptions())
+}
+
+// ExtractOutlineWithOptions is like ExtractOutline but fails with
+// ErrFormatDisabled when Options.EnabledFormats leaves out docx.
+func ExtractOutlineWithOptions(filename string, data []byte, opts Options) ([]Heading, error) {
+	if format := fileFormat(filename); format != "docx" && format != "" {
+		return nil, errors.New("outline is supported for docx only")
+	}
+	if err := opts.checkFormat("docx"); err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := zipIndex(zr)
+	f, ok := files["word/document.xml"]
+	if !ok {
+		return nil, errors.New("document.xml not found in docx")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	paras, err := outlineParagraphs(rc, loadDocxStyles(files))
+	if err != nil {
+		return nil, err
+	}
+
+	headings := []Heading{}
+	for _, p := range paras {
+		if p.level > 0 && p.text != "" {
+			headings = append(headings, Heading{Level: p.level, Text: p.text})
+		}
+	}
+	if len(headings) > 0 {
+		return headings, nil
+	}
+	return inferHeadings(paras), nil
+}
+
+// outlinePara is a paragraph with what the outline needs of its formatting.
+type outlinePara struct {
+	text    string
+	level   int  // from styles or w:outlineLvl, 0 for body text
+	inTable bool // inside a table cell
+	size    int  // smallest font size of the visible runs, in half-points
+	bold    bool // all visible runs are bold
+	runes   int  // visible characters, weighted into the body size
+}
+
+func outlineParagraphs(r io.Reader, styles *docxStyles) ([]outlinePara, error) {
+	var paras []outlinePara
+	var p *outlinePara
+	var b strings.Builder
+	var pStyle, rStyle string
+	var rPr docxRPr
+	tables := 0
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tbl":
+				tables++
+			case "p":
+				p = &outlinePara{inTable: tables > 0, bold: true}
+				b.Reset()
+				pStyle = ""
+			case "pPr":
+				if p == nil {
+					continue
+				}
+				var pPr struct {
+					Style      *xmlVal `xml:"pStyle"`
+					OutlineLvl *xmlVal `xml:"outlineLvl"`
+				}
+				if err := dec.DecodeElement(&pPr, &t); err != nil {
+					return nil, err
+				}
+				if pPr.Style != nil {
+					pStyle = pPr.Style.Val
+				}
+				p.level, _ = styles.headingLevel(pStyle)
+				if pPr.OutlineLvl != nil {
+					p.level, _ = docxOutlineLevel(pPr.OutlineLvl.Val)
+				}
+			case "r":
+				rStyle, rPr = "", docxRPr{}
+			case "rPr":
+				var direct struct {
+					docxRPr
+					Style *xmlVal `xml:"rStyle"`
+				}
+				if err := dec.DecodeElement(&direct, &t); err != nil {
+					return nil, err
+				}
+				rPr = direct.docxRPr
+				if direct.Style != nil {
+					rStyle = direct.Style.Val
+				}
+			case "tab":
+				b.WriteByte(' ')
+			case "t":
+				var s string
+				if err := dec.DecodeElement(&s, &t); err != nil {
+					return nil, err
+				}
+				if p == nil {
+					continue
+				}
+				props := styles.runProps(pStyle, rStyle, rPr)
+				if props.hidden {
+					continue
+				}
+				b.WriteString(s)
+				if n := utf8.RuneCountInString(strings.TrimSpace(s)); n > 0 {
+					size := props.size
+					if size == 0 {
+						size = docxDefaultSize
+					}
+					if p.size == 0 || size < p.size {
+						p.size = size
+					}
+					p.bold = p.bold && props.bold
+					p.runes += n
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "tbl":
+				tables--
+			case "p":
+				if p == nil {
+					continue
+				}
+				p.text = strings.Join(strings.Fields(b.String()), " ")
+				paras = append(paras, *p)
+				p = nil
+			}
+		}
+	}
+	return paras, nil
+}
+
+// inferHeadings ranks directly formatted pseudo-headings against the body
+// text size, the size most characters of the document are set in.
+func inferHeadings(paras []outlinePara) []Heading {
+	weight := make(map[int]int)
+	for _, p := range paras {
+		weight[p.size] += p.runes
+	}
+	body := 0
+	for size, n := range weight {
+		if size != 0 && (body == 0 || n > weight[body] || n == weight[body] && size < body) {
+			body = size
+		}
+	}
+
+	isHeading := func(p outlinePara) bool {
+		if p.inTable || p.runes == 0 || utf8.RuneCountInString(p.text) > outlineMaxHeadingRunes {
+			return false
+		}
+		if strings.HasSuffix(p.text, ".") || strings.HasSuffix(p.text, ",") {
+			return false
+		}
+		return p.size > body || p.size == body && p.bold
+	}
+	var sizes []int
+	seen := make(map[int]bool)
+	for _, p := range paras {
+		if isHeading(p) && p.size > body && !seen[p.size] {
+			seen[p.size] = true
+			sizes = append(sizes, p.size)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+	rank := make(map[int]int, len(sizes))
+	for i, size := range sizes {
+		rank[size] = i + 1
+	}
+
+	headings := []Heading{}
+	for _, p := range paras {
+		if !isHeading(p) {
+			continue
+		}
+		level := len(sizes) + 1 // bold at body size
+		if p.size > body {
+			level = rank[p.size]
+		}
+		headings = append(headings, Heading{Level: min(level, docxMaxLevels), Text: p.text})
+	}
+	return headings
+}
@@ -0,0 +1,43 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractOutline(t *testing.T) {
+	para := func(rPr, text string) string {
+		return `<w:p><w:r><w:rPr>` + rPr + `</w:rPr><w:t>` + text + `</w:t></w:r></w:p>`
+	}
+	styled := func(style, text string) string {
+		return `<w:p><w:pPr><w:pStyle w:val="` + style + `"/></w:pPr><w:r><w:t>` + text + `</w:t></w:r></w:p>`
+	}
+	styles := `<?xml version="1.0" encoding="UTF-8"?><w:styles ` + testWordNS + `>` +
+		`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Sub"><w:pPr><w:outlineLvl w:val="1"/></w:pPr></w:style>` +
+		`</w:styles>`
+	body := "The body text of the document is long enough to set the body size for the whole file"
+	for _, tc := range []struct {
+		name string
+		docx []byte
+		want []Heading
+	}{
+		{"heading styles", testDOCX(t, styled("Heading1", "Intro")+para("", body)+styled("Sub", "Details")+para(`<w:b/><w:sz w:val="40"/>`, "Ignored"),
+			"word/styles.xml", styles),
+			[]Heading{{1, "Intro"}, {2, "Details"}}},
+		{"direct formatting", testDOCX(t, para(`<w:b/><w:sz w:val="36"/>`, "Title")+para(`<w:sz w:val="22"/>`, body)+
+			para(`<w:sz w:val="28"/>`, "Section")+para(`<w:sz w:val="22"/>`, body)+
+			para(`<w:b/><w:sz w:val="22"/>`, "Subsection")+para(`<w:b/><w:sz w:val="22"/>`, "A bold sentence.")+
+			`<w:tbl><w:tr><w:tc>`+para(`<w:sz w:val="36"/>`, "Cell")+`</w:tc></w:tr></w:tbl>`+para(`<w:sz w:val="28"/>`, "Next section")),
+			[]Heading{{1, "Title"}, {2, "Section"}, {3, "Subsection"}, {2, "Next section"}}},
+		{"no headings", testDOCX(t, para("", body)), []Heading{}},
+	} {
+		got, err := ExtractOutline("a.docx", tc.docx)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, %v; want %v", tc.name, got, err, tc.want)
+		}
+	}
+	if _, err := ExtractOutline("a.pdf", []byte("%PDF-1.4")); err == nil {
+		t.Error("outline of a pdf gave no error")
+	}
+}
@@ -0,0 +1,93 @@
+package extract
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pdfDefaultLayer names the text that belongs to no optional content group.
+const pdfDefaultLayer = "default"
+
+// ExtractPDFLayers returns the text of a PDF grouped by optional content group
+// (layer) name, so that parallel translations kept in separate layers can be
+// told apart. Text outside any group is under "default", which is the only
+// key for a PDF without layers; other layers appear when they hold text.
+// Each layer's pages end with a form feed, as in the plain text. It uses the
+// built-in reader, whatever tools are installed, since pdftotext does not
+// tell layers apart.
+func ExtractPDFLayers(data []byte) (map[string]string, error) {
+	return ExtractPDFLayersWithOptions(data, DefaultOptions())
+}
+
+// ExtractPDFLayersWithOptions is like ExtractPDFLayers but fails with
+// ErrFormatDisabled when Options.EnabledFormats leaves out pdf and caps
+// decompressed streams at Options.MaxDecompressedBytes.
+func ExtractPDFLayersWithOptions(data []byte, opts Options) (map[string]string, error) {
+	if err := opts.checkFormat("pdf"); err != nil {
+		return nil, err
+	}
+	f, err := parsePDF(data, opts.maxDecompressedBytes())
+	if err != nil {
+		return nil, err
+	}
+	if f.trailer["Encrypt"] != nil {
+		return nil, fmt.Errorf("%w: the file is encrypted", ErrPDFNoText)
+	}
+	pages := f.pages()
+	texts := make(map[string]*strings.Builder)
+	ops := pdfMaxFormOperators
+	for i, ref := range pages {
+		page := f.dict(ref)
+		w := &pdfTextWriter{file: f, fonts: make(map[pdfRef]*pdfFont), layers: make(map[string]*strings.Builder), ops: &ops}
+		w.run(f.contents(page["Contents"]), f.dict(f.inherited(page, "Resources")), 0)
+		for name, b := range w.layers {
+			text := strings.TrimSpace(b.String())
+			if text == "" {
+				continue
+			}
+			if texts[name] == nil {
+				// pages before the first one with text in this layer
+				texts[name] = &strings.Builder{}
+				texts[name].WriteString(strings.Repeat("\f", i))
+			}
+			texts[name].WriteString(text + "\n")
+		}
+		for _, b := range texts {
+			b.WriteByte('\f')
+		}
+	}
+	layers := map[string]string{pdfDefaultLayer: strings.Repeat("\f", len(pages))}
+	for name, b := range texts {
+		layers[name] = b.String()
+	}
+	return layers, nil
+}
+
+// ocgName returns the name of the optional content group v refers to, or of
+// the first group of an optional content membership dictionary; it returns
+// "" when v is neither.
+func (f *pdfFile) ocgName(v any) string {
+	d := f.dict(v)
+	switch d["Type"] {
+	case pdfName("OCG"):
+		s, _ := f.resolve(d["Name"]).(pdfString)
+		if name := pdfTextString(s); name != "" {
+			return name
+		}
+		// an unnamed group is still a layer of its own
+		if ref, ok := v.(pdfRef); ok {
+			return fmt.Sprintf("ocg %d", ref.num)
+		}
+		return "ocg"
+	case pdfName("OCMD"):
+		switch ocgs := f.resolve(d["OCGs"]).(type) {
+		case pdfArray:
+			if len(ocgs) > 0 {
+				return f.ocgName(ocgs[0])
+			}
+		case pdfDict:
+			return f.ocgName(d["OCGs"])
+		}
+	}
+	return ""
+}
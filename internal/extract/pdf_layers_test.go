@@ -0,0 +1,46 @@
+package extract
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestExtractPDFLayers(t *testing.T) {
+	// object 6 is named through the page's Properties, object 7 appears inline
+	// in an OCMD, and the last line belongs to no group
+	content := "/OC /MC0 BDC BT /F1 12 Tf 72 700 Td (Hello) Tj ET EMC\n" +
+		"/OC /MC1 BDC BT /F1 12 Tf 72 680 Td (Bonjour) Tj ET EMC\n" +
+		"BT /F1 12 Tf 72 660 Td (Page 1) Tj ET"
+	layered := []byte("%PDF-1.4\n" +
+		"1 0 obj\n<</Type/Catalog/Pages 2 0 R/OCProperties <</OCGs [6 0 R 7 0 R]/D <<>>>>>>\nendobj\n" +
+		"2 0 obj\n<</Type/Pages/Kids [4 0 R]/Count 1>>\nendobj\n" +
+		"3 0 obj\n<</Type/Font/Subtype/Type1/BaseFont/Helvetica>>\nendobj\n" +
+		"4 0 obj\n<</Type/Page/Parent 2 0 R/MediaBox [0 0 612 792]/Resources <</Font <</F1 3 0 R>>/Properties <</MC0 6 0 R/MC1 <</Type/OCMD/OCGs [7 0 R]>>>>>>/Contents 5 0 R>>\nendobj\n" +
+		"5 0 obj\n<</Length " + strconv.Itoa(len(content)) + ">>\nstream\n" + content + "\nendstream\nendobj\n" +
+		"6 0 obj\n<</Type/OCG/Name (English)>>\nendobj\n" +
+		"7 0 obj\n<</Type/OCG/Name (French)>>\nendobj\n" +
+		"trailer\n<</Root 1 0 R/Size 8>>\nstartxref\n0\n%%EOF\n")
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want map[string]string
+	}{
+		{"two layers", layered, map[string]string{"default": "Page 1\n\f", "English": "Hello\n\f", "French": "Bonjour\n\f"}},
+		{"no layers", testTextPDF("BT /F1 12 Tf 72 700 Td (Hello) Tj ET", ""), map[string]string{"default": "Hello\n\f\f"}},
+		{"layer from the second page", testTextPDF("", "/OC <</Type/OCG/Name (English)>> BDC BT /F1 12 Tf 72 700 Td (Hello) Tj ET EMC"), map[string]string{"default": "\f\f", "English": "\fHello\n\f"}},
+	} {
+		got, err := ExtractPDFLayers(tc.data)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+
+	opts := DefaultOptions()
+	opts.EnabledFormats = []string{"docx"}
+	if _, err := ExtractPDFLayersWithOptions(layered, opts); !errors.Is(err, ErrFormatDisabled) {
+		t.Errorf("pdf disabled: err = %v, want ErrFormatDisabled", err)
+	}
+}
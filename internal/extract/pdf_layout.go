@@ -0,0 +1,87 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TextSpan is a word of a PDF page with its bounding box, in points from the
+// top-left corner of the page.
+type TextSpan struct {
+	Text string `json:"text"`
+	// Page is 1-based.
+	Page int     `json:"page"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	W    float64 `json:"w"`
+	H    float64 `json:"h"`
+}
+
+// ExtractPDFWithLayout returns the words of a PDF with their positions, for
+// drawing highlights over the rendered pages. It reads the word boxes of
+// pdftotext -bbox; spans are in the order pdftotext emits them, page by page.
+func ExtractPDFWithLayout(data []byte) ([]TextSpan, error) {
+	return ExtractPDFLayoutWithOptions(data, DefaultOptions())
+}
+
+// ExtractPDFLayoutWithOptions is like ExtractPDFWithLayout but fails with
+// ErrFormatDisabled when Options.EnabledFormats leaves out pdf.
+func ExtractPDFLayoutWithOptions(data []byte, opts Options) ([]TextSpan, error) {
+	if err := opts.checkFormat("pdf"); err != nil {
+		return nil, err
+	}
+	out, err := execPdftotext(context.Background(), data, "-bbox")
+	if err != nil {
+		return nil, err
+	}
+	return parseBBox(bytes.NewReader(out))
+}
+
+// parseBBox reads the XHTML of pdftotext -bbox or -bbox-layout: page elements
+// holding word elements with xMin, yMin, xMax and yMax attributes.
+func parseBBox(r io.Reader) ([]TextSpan, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	spans := []TextSpan{}
+	page := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return spans, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch el.Name.Local {
+		case "page":
+			page++
+		case "word":
+			var text string
+			if err := dec.DecodeElement(&text, &el); err != nil {
+				return nil, err
+			}
+			box := func(name string) float64 {
+				v, _ := strconv.ParseFloat(xmlAttr(el, name), 64)
+				return v
+			}
+			x, y := box("xMin"), box("yMin")
+			spans = append(spans, TextSpan{
+				Text: strings.TrimSpace(text),
+				Page: page,
+				X:    x,
+				Y:    y,
+				W:    box("xMax") - x,
+				H:    box("yMax") - y,
+			})
+		}
+	}
+}
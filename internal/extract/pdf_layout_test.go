@@ -0,0 +1,57 @@
+package extract
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testBBox is pdftotext -bbox output for a two-page document.
+const testBBox = `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml">
+<head>
+<title></title>
+<meta name="Producer" content="pdftotext"/>
+</head>
+<body>
+<doc>
+  <page width="612.000000" height="792.000000">
+    <word xMin="72.000000" yMin="70.500000" xMax="101.500000" yMax="82.500000">Hello</word>
+    <word xMin="104.000000" yMin="70.500000" xMax="140.250000" yMax="82.500000">&amp;world</word>
+  </page>
+  <page width="612.000000" height="792.000000">
+    <word xMin="72.000000" yMin="100.000000" xMax="96.000000" yMax="112.000000">Привет</word>
+  </page>
+</doc>
+</body>
+</html>
+`
+
+func TestParseBBox(t *testing.T) {
+	want := []TextSpan{
+		{Text: "Hello", Page: 1, X: 72, Y: 70.5, W: 29.5, H: 12},
+		{Text: "&world", Page: 1, X: 104, Y: 70.5, W: 36.25, H: 12},
+		{Text: "Привет", Page: 2, X: 72, Y: 100, W: 24, H: 12},
+	}
+	spans, err := parseBBox(strings.NewReader(testBBox))
+	if err != nil || !reflect.DeepEqual(spans, want) {
+		t.Errorf("got %+v, %v; want %+v", spans, err, want)
+	}
+	if spans, err := parseBBox(strings.NewReader(`<doc></doc>`)); err != nil || len(spans) != 0 || spans == nil {
+		t.Errorf("no words: got %#v, %v; want an empty list", spans, err)
+	}
+}
+
+func TestExtractPDFWithLayout(t *testing.T) {
+	fakePdftotext(t, "cat > /dev/null\n[ \"$1\" = -bbox ] || exit 1\ncat <<'EOF'\n"+testBBox+"EOF\n")
+	spans, err := ExtractPDFWithLayout(testTextPDF(""))
+	if err != nil || len(spans) != 3 || spans[2].Text != "Привет" || spans[2].Page != 2 {
+		t.Errorf("got %+v, %v", spans, err)
+	}
+
+	pdftotextPath = func() (string, error) { return "", errors.New("not found") }
+	if _, err := ExtractPDFWithLayout(testTextPDF("")); !errors.Is(err, ErrPDFToolMissing) {
+		t.Errorf("without pdftotext: err = %v, want ErrPDFToolMissing", err)
+	}
+}
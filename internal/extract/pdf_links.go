@@ -0,0 +1,144 @@
+package extract
+
+import "sort"
+
+// InternalLink is a link within a PDF, or a named destination.
+type InternalLink struct {
+	// SourcePage is the 1-based page holding the link annotation; it is 0 for
+	// a named destination that no link refers to.
+	SourcePage int `json:"source_page"`
+	// TargetPage is the 1-based destination page, 0 when it cannot be resolved.
+	TargetPage int `json:"target_page"`
+	// DestName is the named destination the link goes through, if any.
+	DestName string `json:"dest_name,omitempty"`
+}
+
+// ExtractPDFLinks returns the internal links of a PDF in page order, followed
+// by the named destinations (from /Dests and the /Names name tree) that no
+// link uses. External links (URIs, other files) are skipped. A PDF without
+// links yields an empty slice.
+func ExtractPDFLinks(data []byte) ([]InternalLink, error) {
+	return ExtractPDFLinksWithOptions(data, DefaultOptions())
+}
+
+// ExtractPDFLinksWithOptions is like ExtractPDFLinks but fails with
+// ErrFormatDisabled when Options.EnabledFormats leaves out pdf and caps
+// decompressed streams at Options.MaxDecompressedBytes.
+func ExtractPDFLinksWithOptions(data []byte, opts Options) ([]InternalLink, error) {
+	if err := opts.checkFormat("pdf"); err != nil {
+		return nil, err
+	}
+	f, err := parsePDF(data, opts.maxDecompressedBytes())
+	if err != nil {
+		return nil, err
+	}
+	pages := f.pages()
+	pageNum := make(map[pdfRef]int, len(pages))
+	for i, ref := range pages {
+		pageNum[ref] = i + 1
+	}
+	named := f.namedDests()
+	// target resolves an explicit destination array or a destination name
+	target := func(dest any) (page int, name string) {
+		dest = f.resolve(dest)
+		switch d := dest.(type) {
+		case pdfName:
+			name = string(d)
+		case pdfString:
+			name = string(d)
+		}
+		if name != "" {
+			dest = named[name]
+		}
+		if a := f.explicitDest(dest); len(a) > 0 {
+			if ref, ok := a[0].(pdfRef); ok {
+				page = pageNum[ref]
+			}
+		}
+		return page, name
+	}
+
+	links := []InternalLink{}
+	used := make(map[string]bool)
+	for i, ref := range pages {
+		for _, annot := range f.array(f.dict(ref)["Annots"]) {
+			a := f.dict(annot)
+			if a["Subtype"] != pdfName("Link") {
+				continue
+			}
+			dest := a["Dest"]
+			if dest == nil {
+				action := f.dict(a["A"])
+				if action["S"] != pdfName("GoTo") {
+					continue
+				}
+				dest = action["D"]
+			}
+			if dest == nil {
+				continue
+			}
+			page, name := target(dest)
+			used[name] = true
+			links = append(links, InternalLink{SourcePage: i + 1, TargetPage: page, DestName: name})
+		}
+	}
+
+	names := make([]string, 0, len(named))
+	for name := range named {
+		if !used[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		page, _ := target(pdfString(name))
+		links = append(links, InternalLink{TargetPage: page, DestName: name})
+	}
+	return links, nil
+}
+
+// explicitDest unwraps a destination dictionary (<< /D [...] >>) to its array.
+func (f *pdfFile) explicitDest(v any) pdfArray {
+	switch d := f.resolve(v).(type) {
+	case pdfArray:
+		return d
+	case pdfDict:
+		return f.array(d["D"])
+	}
+	return nil
+}
+
+// namedDests collects named destinations from the PDF 1.1 /Dests dictionary
+// and the /Names /Dests name tree of the catalog.
+func (f *pdfFile) namedDests() map[string]any {
+	dests := make(map[string]any)
+	root := f.dict(f.trailer["Root"])
+	for name, v := range f.dict(root["Dests"]) {
+		dests[string(name)] = v
+	}
+	seen := make(map[any]bool)
+	var walk func(node any, depth int)
+	walk = func(node any, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if seen[ref] {
+				return
+			}
+			seen[ref] = true
+		}
+		n := f.dict(node)
+		if n == nil || depth > pdfMaxDepth {
+			return
+		}
+		kv := f.array(n["Names"])
+		for i := 0; i+1 < len(kv); i += 2 {
+			if key, ok := f.resolve(kv[i]).(pdfString); ok {
+				dests[string(key)] = kv[i+1]
+			}
+		}
+		for _, kid := range f.array(n["Kids"]) {
+			walk(kid, depth+1)
+		}
+	}
+	walk(f.dict(root["Names"])["Dests"], 0)
+	return dests
+}
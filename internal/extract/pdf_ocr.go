@@ -0,0 +1,137 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ocrSparsePDFPages replaces the text of pages that have fewer than
+// Options.OCRMinPageChars visible characters (at least one) with OCR of the
+// rendered page, so scanned pages of a mixed PDF are recognized while pages
+// with a text layer keep it. text is pdftotext output, one page per form feed.
+func ocrSparsePDFPages(ctx context.Context, data []byte, text string, opts Options) (string, error) {
+	minChars := max(opts.OCRMinPageChars, 1)
+	pages := strings.Split(text, "\f")
+	// pdftotext ends every page with a form feed, the last element is not a page
+	n := len(pages)
+	if n > 1 && pages[n-1] == "" {
+		n--
+	}
+	var sparse []int
+	for i := 0; i < n; i++ {
+		visible := 0
+		for _, r := range pages[i] {
+			if !unicode.IsSpace(r) {
+				visible++
+			}
+		}
+		if visible < minChars {
+			sparse = append(sparse, i)
+		}
+	}
+	if len(sparse) == 0 {
+		return text, nil
+	}
+
+	dir, err := os.MkdirTemp("", "docparser-pdfocr-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "input.pdf")
+	if err := os.WriteFile(src, data, 0o600); err != nil {
+		return "", err
+	}
+	// the text starts at the first page of the range
+	first, _ := opts.pdfPageRange()
+	for _, i := range sparse {
+		page := strconv.Itoa(first + i)
+		out := filepath.Join(dir, "page"+page)
+		cmd := exec.CommandContext(ctx, "pdftoppm", "-r", "300", "-png", "-singlefile", "-f", page, "-l", page, src, out)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		stop := phaseTimer(ctx, PhaseSubprocess)
+		err := cmd.Run()
+		stop()
+		if err != nil {
+			return "", fmt.Errorf("pdftoppm: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		img, err := os.ReadFile(out + ".png")
+		if err != nil {
+			return "", err
+		}
+		ocr, err := ocrImages(ctx, [][]byte{img}, opts)
+		if err != nil {
+			return "", err
+		}
+		if ocr != "" {
+			pages[i] = ocr + "\n"
+		}
+	}
+	return strings.Join(pages, "\f"), nil
+}
+
+// pdfImageText extracts the raster images embedded in a PDF with pdfimages and
+// recognizes them with tesseract. Each image with text becomes a block labeled
+// with its page, so figures can be told apart from the text layer.
+func pdfImageText(ctx context.Context, data []byte, opts Options) (string, error) {
+	dir, err := os.MkdirTemp("", "docparser-pdfimages-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "input.pdf")
+	if err := os.WriteFile(src, data, 0o600); err != nil {
+		return "", err
+	}
+	// -p puts the page number into each file name: img-PPP-NNN.png
+	args := append([]string{"-png", "-p"}, opts.pdfPageArgs()...)
+	cmd := exec.CommandContext(ctx, "pdfimages", append(args, src, filepath.Join(dir, "img"))...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stop := phaseTimer(ctx, PhaseSubprocess)
+	err = cmd.Run()
+	stop()
+	if err != nil {
+		return "", fmt.Errorf("pdfimages: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	names, err := filepath.Glob(filepath.Join(dir, "img-*.png"))
+	if err != nil {
+		return "", err
+	}
+	// the zero-padded page and image numbers sort correctly as strings
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		parts := strings.Split(strings.TrimSuffix(filepath.Base(name), ".png"), "-")
+		if len(parts) != 3 {
+			continue
+		}
+		page, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		img, err := os.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		text, err := ocrImages(ctx, [][]byte{img}, opts)
+		if err != nil {
+			return "", err
+		}
+		if text != "" {
+			fmt.Fprintf(&b, "\n[Image, page %d]\n%s\n", page, text)
+		}
+	}
+	return b.String(), nil
+}
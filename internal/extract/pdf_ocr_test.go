@@ -0,0 +1,79 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeOCRTools puts scripts into PATH for pdftoppm, which renders page N as
+// "scanned page N", and pdfimages, which unpacks one figure on page 2, and
+// returns a tesseract script that recognizes an image as its content.
+func fakeOCRTools(t *testing.T) (tesseract string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake tools are shell scripts")
+	}
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"pdftoppm": `#!/bin/sh
+while [ $# -gt 2 ]; do
+	[ "$1" = -f ] && page=$2
+	shift
+done
+printf 'scanned page %s' "$page" > "$2.png"
+`,
+		"pdfimages": `#!/bin/sh
+for prefix; do :; done
+printf 'figure caption' > "$prefix-002-000.png"
+`,
+		"tesseract": "#!/bin/sh\ncat\n",
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return filepath.Join(dir, "tesseract")
+}
+
+func TestPDFImageOCR(t *testing.T) {
+	opts := DefaultOptions()
+	opts.OCR = true
+	opts.TesseractPath = fakeOCRTools(t)
+	pdf := testTextPDF("BT /F1 12 Tf 72 720 Td (text layer) Tj ET", "BT /F1 12 Tf 72 720 Td (second page) Tj ET")
+
+	got, err := ExtractTextWithOptions("a.pdf", pdf, opts)
+	if err != nil || !strings.Contains(got, "text layer") || strings.Contains(got, "[Image") {
+		t.Errorf("without PDFImageOCR: got %q, %v", got, err)
+	}
+	opts.PDFImageOCR = true
+	got, err = ExtractTextWithOptions("a.pdf", pdf, opts)
+	if want := "\n[Image, page 2]\nfigure caption\n"; err != nil || !strings.Contains(got, "text layer") || !strings.HasSuffix(got, want) {
+		t.Errorf("with PDFImageOCR: got %q, %v; want the text layer, then %q", got, err, want)
+	}
+}
+
+func TestOCRSparsePDFPages(t *testing.T) {
+	pdf := testTextPDF("BT /F1 12 Tf 72 720 Td (text layer) Tj ET", "0 0 612 792 re f")
+	for _, tc := range []struct {
+		minChars int
+		want     string
+	}{
+		{0, "text layer\n\fscanned page 2\n\f"},
+		{9, "text layer\n\fscanned page 2\n\f"},
+		{10, "scanned page 1\n\fscanned page 2\n\f"},
+	} {
+		opts := DefaultOptions()
+		opts.OCR = true
+		opts.OCRMinPageChars = tc.minChars
+		opts.TesseractPath = fakeOCRTools(t)
+		got, err := ExtractTextWithOptions("a.pdf", pdf, opts)
+		if err != nil || got != tc.want {
+			t.Errorf("min %d chars: got %q, %v; want %q", tc.minChars, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,668 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// A minimal native PDF object reader for the features pdftotext does not
+// expose, such as link annotations. It locates objects by scanning for
+// "N G obj" rather than trusting the xref table, which makes it tolerant of
+// broken offsets; later definitions win, as with incremental updates.
+//
+// Parsed values are nil (null), bool, int, float64, pdfName, pdfString,
+// pdfArray, pdfDict, pdfRef and *pdfStream.
+
+type (
+	pdfName   string
+	pdfString string
+	pdfArray  []any
+	pdfDict   map[pdfName]any
+	pdfRef    struct{ num, gen int }
+)
+
+type pdfStream struct {
+	dict pdfDict
+	raw  []byte // still encoded
+}
+
+// pdfMaxDepth bounds reference chasing and nesting in malformed files.
+const pdfMaxDepth = 64
+
+var rePDFObj = regexp.MustCompile(`(?:^|[\r\n\s])(\d+)\s+(\d+)\s+obj\b`)
+
+type pdfFile struct {
+	data    []byte
+	offsets map[int]int // object number -> offset of its "N G obj" header
+	// objects packed in object streams: number -> stream object and index
+	packed  map[int]pdfRef
+	cache   map[int]any
+	trailer pdfDict
+	// limit caps the decoded size of each stream
+	limit int64
+}
+
+func parsePDF(data []byte, limit int64) (*pdfFile, error) {
+	f := &pdfFile{
+		data:    data,
+		offsets: make(map[int]int),
+		packed:  make(map[int]pdfRef),
+		cache:   make(map[int]any),
+		limit:   limit,
+	}
+	for _, m := range rePDFObj.FindAllSubmatchIndex(data, -1) {
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		f.offsets[num] = m[2]
+	}
+	if len(f.offsets) == 0 {
+		return nil, errors.New("pdf: no objects found")
+	}
+	// index object streams, then find the trailer: the last classic trailer
+	// dictionary, or the last cross-reference stream dictionary
+	for num := range f.offsets {
+		s, ok := f.object(num).(*pdfStream)
+		if !ok {
+			continue
+		}
+		switch s.dict["Type"] {
+		case pdfName("ObjStm"):
+			f.indexObjStm(num, s)
+		}
+	}
+	if i := bytes.LastIndex(data, []byte("trailer")); i >= 0 {
+		p := &pdfParser{data: data, pos: i + len("trailer")}
+		if d, ok := p.value(0).(pdfDict); ok {
+			f.trailer = d
+		}
+	}
+	if f.trailer["Root"] == nil {
+		best := -1
+		for num, off := range f.offsets {
+			if s, ok := f.object(num).(*pdfStream); ok && s.dict["Type"] == pdfName("XRef") && off > best {
+				best, f.trailer = off, s.dict
+			}
+		}
+	}
+	if f.trailer["Root"] == nil {
+		// last resort: any catalog
+		for num := range f.offsets {
+			if d, ok := f.object(num).(pdfDict); ok && d["Type"] == pdfName("Catalog") {
+				f.trailer = pdfDict{"Root": pdfRef{num: num}}
+				break
+			}
+		}
+	}
+	if f.trailer["Root"] == nil {
+		return nil, errors.New("pdf: document catalog not found")
+	}
+	return f, nil
+}
+
+// indexObjStm records the objects packed in an object stream, unless a direct
+// definition of the same number exists.
+func (f *pdfFile) indexObjStm(num int, s *pdfStream) {
+	data, err := f.decode(s)
+	if err != nil {
+		return
+	}
+	n, _ := f.resolve(s.dict["N"]).(int)
+	p := &pdfParser{data: data}
+	for i := 0; i < n; i++ {
+		objNum, ok1 := p.value(0).(int)
+		_, ok2 := p.value(0).(int)
+		if !ok1 || !ok2 {
+			return
+		}
+		if _, direct := f.offsets[objNum]; !direct {
+			f.packed[objNum] = pdfRef{num: num, gen: i}
+		}
+	}
+}
+
+// object returns the value of object num, or nil when it is missing.
+func (f *pdfFile) object(num int) any {
+	if v, ok := f.cache[num]; ok {
+		return v
+	}
+	// a placeholder stops reference cycles, e.g. a /Length pointing back
+	f.cache[num] = nil
+	var v any
+	if off, ok := f.offsets[num]; ok {
+		v = f.parseObjectAt(off)
+	} else if loc, ok := f.packed[num]; ok {
+		v = f.packedObject(loc.num, loc.gen)
+	}
+	f.cache[num] = v
+	return v
+}
+
+func (f *pdfFile) parseObjectAt(off int) any {
+	p := &pdfParser{data: f.data, pos: off, file: f}
+	// skip "N G obj"
+	p.value(0)
+	p.value(0)
+	if !p.keyword("obj") {
+		return nil
+	}
+	return p.value(0)
+}
+
+// packedObject returns the index-th object of object stream stmNum.
+func (f *pdfFile) packedObject(stmNum, index int) any {
+	s, ok := f.object(stmNum).(*pdfStream)
+	if !ok {
+		return nil
+	}
+	data, err := f.decode(s)
+	if err != nil {
+		return nil
+	}
+	n, _ := f.resolve(s.dict["N"]).(int)
+	first, _ := f.resolve(s.dict["First"]).(int)
+	if index >= n || first > len(data) {
+		return nil
+	}
+	p := &pdfParser{data: data}
+	var off int
+	for i := 0; i <= index; i++ {
+		p.value(0)
+		off, _ = p.value(0).(int)
+	}
+	if first < 0 || off < 0 || off >= len(data)-first {
+		return nil
+	}
+	p = &pdfParser{data: data, pos: first + off, file: f}
+	return p.value(0)
+}
+
+// resolve follows references until it reaches a direct value.
+func (f *pdfFile) resolve(v any) any {
+	for i := 0; i < pdfMaxDepth; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = f.object(ref.num)
+	}
+	return nil
+}
+
+func (f *pdfFile) dict(v any) pdfDict {
+	switch v := f.resolve(v).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+func (f *pdfFile) array(v any) pdfArray {
+	a, _ := f.resolve(v).(pdfArray)
+	return a
+}
+
+// decode returns the decoded content of a stream.
+func (f *pdfFile) decode(s *pdfStream) ([]byte, error) {
+	data := s.raw
+	var filters pdfArray
+	switch v := f.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = pdfArray{v}
+	case pdfArray:
+		filters = v
+	}
+	var parms pdfArray
+	switch v := f.resolve(s.dict["DecodeParms"]).(type) {
+	case pdfDict:
+		parms = pdfArray{v}
+	case pdfArray:
+		parms = v
+	}
+	for i, filter := range filters {
+		var parm pdfDict
+		if i < len(parms) {
+			parm = f.dict(parms[i])
+		}
+		var err error
+		switch f.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			data, err = pdfInflate(data, f.limit)
+			if err == nil {
+				data, err = f.unpredict(data, parm)
+			}
+		default:
+			err = fmt.Errorf("pdf: unsupported filter %v", filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// pdfInflate decompresses zlib data, keeping whatever was decoded before a
+// truncated or corrupt tail and failing once the output exceeds limit bytes.
+func pdfInflate(data []byte, limit int64) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, limit+1))
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, ErrDecompressionLimit
+	}
+	return out, nil
+}
+
+// unpredict reverses the PNG predictors used by Flate-encoded streams.
+func (f *pdfFile) unpredict(data []byte, parm pdfDict) ([]byte, error) {
+	predictor, _ := f.resolve(parm["Predictor"]).(int)
+	if predictor < 10 {
+		return data, nil
+	}
+	columns, ok := f.resolve(parm["Columns"]).(int)
+	if !ok || columns <= 0 {
+		columns = 1
+	}
+	colors, ok := f.resolve(parm["Colors"]).(int)
+	if !ok || colors <= 0 {
+		colors = 1
+	}
+	bpc, ok := f.resolve(parm["BitsPerComponent"]).(int)
+	if !ok || bpc <= 0 {
+		bpc = 8
+	}
+	// a row is at least columns/8 bytes and no longer than the data
+	if colors > 32 || bpc > 16 || columns > len(data)*8 {
+		return nil, errors.New("pdf: invalid predictor parameters")
+	}
+	bpp := max((colors*bpc+7)/8, 1)
+	rowLen := (columns*colors*bpc + 7) / 8
+	if rowLen > len(data) {
+		return nil, errors.New("pdf: invalid predictor parameters")
+	}
+	var out []byte
+	prev := make([]byte, rowLen)
+	for len(data) >= rowLen+1 {
+		kind, row := data[0], append([]byte(nil), data[1:rowLen+1]...)
+		data = data[rowLen+1:]
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], prev[i-bpp]
+			}
+			up := prev[i]
+			switch kind {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// pages returns the page objects in document order.
+func (f *pdfFile) pages() []pdfRef {
+	root := f.dict(f.trailer["Root"])
+	var pages []pdfRef
+	seen := make(map[pdfRef]bool)
+	var walk func(v any, depth int)
+	walk = func(v any, depth int) {
+		ref, ok := v.(pdfRef)
+		if !ok || seen[ref] || depth > pdfMaxDepth {
+			return
+		}
+		seen[ref] = true
+		node := f.dict(ref)
+		if node == nil {
+			return
+		}
+		if node["Type"] == pdfName("Page") || node["Kids"] == nil {
+			pages = append(pages, ref)
+			return
+		}
+		for _, kid := range f.array(node["Kids"]) {
+			walk(kid, depth+1)
+		}
+	}
+	walk(root["Pages"], 0)
+	return pages
+}
+
+// pdfParser reads PDF values from data. file, when set, is used to resolve
+// indirect stream lengths.
+type pdfParser struct {
+	data []byte
+	pos  int
+	file *pdfFile
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelim(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func (p *pdfParser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case isPDFSpace(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.data) && p.data[p.pos] != '\r' && p.data[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// keyword consumes kw if it is the next token.
+func (p *pdfParser) keyword(kw string) bool {
+	p.skipSpace()
+	end := p.pos + len(kw)
+	if end > len(p.data) || string(p.data[p.pos:end]) != kw {
+		return false
+	}
+	if end < len(p.data) && !isPDFSpace(p.data[end]) && !isPDFDelim(p.data[end]) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+// token reads a bare token such as a number or keyword.
+func (p *pdfParser) token() string {
+	start := p.pos
+	for p.pos < len(p.data) && !isPDFSpace(p.data[p.pos]) && !isPDFDelim(p.data[p.pos]) {
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+// value parses the next value; it returns nil at the end of input or on
+// tokens that are not values.
+func (p *pdfParser) value(depth int) any {
+	if depth > pdfMaxDepth {
+		return nil
+	}
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil
+	}
+	switch c := p.data[p.pos]; {
+	case c == '/':
+		p.pos++
+		return pdfName(pdfUnescapeName(p.token()))
+	case c == '(':
+		return p.literalString()
+	case c == '<' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '<':
+		p.pos += 2
+		d := make(pdfDict)
+		for {
+			p.skipSpace()
+			if p.pos+1 < len(p.data) && p.data[p.pos] == '>' && p.data[p.pos+1] == '>' {
+				p.pos += 2
+				break
+			}
+			key, ok := p.value(depth + 1).(pdfName)
+			if !ok {
+				return d
+			}
+			d[key] = p.value(depth + 1)
+		}
+		if p.keyword("stream") {
+			return p.stream(d)
+		}
+		return d
+	case c == '<':
+		p.pos++
+		end := bytes.IndexByte(p.data[p.pos:], '>')
+		if end < 0 {
+			p.pos = len(p.data)
+			return nil
+		}
+		hex := p.data[p.pos : p.pos+end]
+		p.pos += end + 1
+		return pdfString(pdfDecodeHex(hex))
+	case c == '[':
+		p.pos++
+		var a pdfArray
+		for {
+			p.skipSpace()
+			if p.pos >= len(p.data) {
+				return a
+			}
+			if p.data[p.pos] == ']' {
+				p.pos++
+				return a
+			}
+			start := p.pos
+			a = append(a, p.value(depth+1))
+			if p.pos == start {
+				// an unparseable byte; skip it rather than loop
+				p.pos++
+			}
+		}
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		return nil
+	}
+
+	tok := p.token()
+	switch tok {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null", "":
+		return nil
+	}
+	if n, err := strconv.Atoi(tok); err == nil {
+		// "N G R" is a reference
+		save := p.pos
+		p.skipSpace()
+		if gen, err := strconv.Atoi(p.token()); err == nil && p.keyword("R") {
+			return pdfRef{num: n, gen: gen}
+		}
+		p.pos = save
+		return n
+	}
+	if x, err := strconv.ParseFloat(tok, 64); err == nil {
+		return x
+	}
+	// a keyword such as endobj: leave it for the caller
+	p.pos -= len(tok)
+	return nil
+}
+
+func (p *pdfParser) stream(d pdfDict) *pdfStream {
+	// the keyword is followed by CRLF or LF
+	if p.pos < len(p.data) && p.data[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+		p.pos++
+	}
+	start := p.pos
+	length := -1
+	switch v := d["Length"].(type) {
+	case int:
+		length = v
+	case pdfRef:
+		if p.file != nil {
+			if n, ok := p.file.resolve(v).(int); ok {
+				length = n
+			}
+		}
+	}
+	end := -1
+	if length >= 0 && length <= len(p.data)-start && bytes.Contains(p.data[start+length:min(start+length+32, len(p.data))], []byte("endstream")) {
+		end = start + length
+	}
+	if end < 0 {
+		// wrong or unknown length: fall back to the end marker
+		i := bytes.Index(p.data[start:], []byte("endstream"))
+		if i < 0 {
+			end = len(p.data)
+		} else {
+			end = start + i
+			for end > start && (p.data[end-1] == '\n' || p.data[end-1] == '\r') {
+				end--
+			}
+		}
+	}
+	p.pos = end
+	p.keyword("endstream")
+	return &pdfStream{dict: d, raw: p.data[start:end]}
+}
+
+func (p *pdfParser) literalString() pdfString {
+	p.pos++ // (
+	var b []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(b)
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				return pdfString(b)
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// line continuation
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						n = n*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(n)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return pdfString(b)
+}
+
+func pdfDecodeHex(hex []byte) []byte {
+	var out []byte
+	var hi byte
+	half := false
+	for _, c := range hex {
+		var v byte
+		switch {
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			continue
+		}
+		if half {
+			out = append(out, hi<<4|v)
+		} else {
+			hi = v
+		}
+		half = !half
+	}
+	if half {
+		// an odd final digit is followed by an implicit 0
+		out = append(out, hi<<4)
+	}
+	return out
+}
+
+// pdfUnescapeName decodes #xx escapes in a name.
+func pdfUnescapeName(s string) string {
+	if !bytes.Contains([]byte(s), []byte("#")) {
+		return s
+	}
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '#' && i+2 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				b = append(b, byte(v))
+				i += 2
+				continue
+			}
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
@@ -0,0 +1,100 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func zlibBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// testPDF is a PDF body of objs and a document catalog, enough for parsePDF.
+func testPDF(objs string) []byte {
+	return []byte(objs + "99 0 obj\n<</Type/Catalog>>\nendobj\n")
+}
+
+func TestPDFStreamHugeLength(t *testing.T) {
+	f, err := parsePDF(testPDF("1 0 obj\n<</Length 9223372036854775800>>\nstream\nabc\nendstream\nendobj\n"), DefaultMaxDecompressedBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := f.object(1).(*pdfStream)
+	if !ok {
+		t.Fatalf("object 1 = %#v, want a stream", f.object(1))
+	}
+	if string(s.raw) != "abc" {
+		t.Errorf("raw = %q, want %q", s.raw, "abc")
+	}
+}
+
+func TestPDFPackedObjectNegativeOffset(t *testing.T) {
+	for _, header := range []string{"7 -50", "7 9223372036854775800"} {
+		stm := header + " (x)"
+		data := testPDF("5 0 obj\n<</Type/ObjStm/N 1/First 0/Length " + strconv.Itoa(len(stm)) + ">>\nstream\n" + stm + "\nendstream\nendobj\n")
+		f, err := parsePDF(data, DefaultMaxDecompressedBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := f.packedObject(5, 0); v != nil {
+			t.Errorf("%s: packedObject = %#v, want nil", header, v)
+		}
+	}
+}
+
+func TestPDFPredictorBounds(t *testing.T) {
+	z := zlibBytes(t, []byte{2, 1, 2, 3, 2, 1, 1, 1})
+	for _, parms := range []string{
+		"/Predictor 12/Columns 9223372036854775807",
+		"/Predictor 12/Columns 3/Colors 1000000000",
+		"/Predictor 12/Columns 3/BitsPerComponent 1000000000",
+		"/Predictor 12/Columns 9",
+	} {
+		data := testPDF("1 0 obj\n<</Filter/FlateDecode/DecodeParms<<" + parms + ">>/Length " + strconv.Itoa(len(z)) + ">>\nstream\n" + string(z) + "\nendstream\nendobj\n")
+		f, err := parsePDF(data, DefaultMaxDecompressedBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, ok := f.object(1).(*pdfStream)
+		if !ok {
+			t.Fatalf("%s: object 1 is not a stream", parms)
+		}
+		if _, err := f.decode(s); err == nil {
+			t.Errorf("%s: decode succeeded, want an error", parms)
+		}
+	}
+	// a valid predictor still decodes
+	data := testPDF("1 0 obj\n<</Filter/FlateDecode/DecodeParms<</Predictor 12/Columns 3>>/Length " + strconv.Itoa(len(z)) + ">>\nstream\n" + string(z) + "\nendstream\nendobj\n")
+	f, err := parsePDF(data, DefaultMaxDecompressedBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := f.decode(f.object(1).(*pdfStream))
+	if err != nil || !bytes.Equal(out, []byte{1, 2, 3, 2, 3, 4}) {
+		t.Errorf("decode = %v, %v; want [1 2 3 2 3 4]", out, err)
+	}
+}
+
+func TestPDFInflateLimit(t *testing.T) {
+	z := zlibBytes(t, make([]byte, 1<<20))
+	data := testPDF("1 0 obj\n<</Filter/FlateDecode/Length " + strconv.Itoa(len(z)) + ">>\nstream\n" + string(z) + "\nendstream\nendobj\n")
+	f, err := parsePDF(data, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.decode(f.object(1).(*pdfStream)); !errors.Is(err, ErrDecompressionLimit) {
+		t.Errorf("err = %v, want ErrDecompressionLimit", err)
+	}
+}
@@ -0,0 +1,520 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// ErrPDFNoText is returned when neither pdftotext nor the built-in reader can
+// produce text from a PDF: pdftotext is not installed and the file is
+// encrypted, uses features the built-in reader lacks, or has no text layer.
+var ErrPDFNoText = errors.New("pdf: no text could be extracted")
+
+// pdfMaxRangeCodes bounds the codes a single ToUnicode bfrange may define.
+const pdfMaxRangeCodes = 1 << 16
+
+// pdfMaxFormOperators bounds the operators of form XObjects interpreted for
+// one document, counting a form's each time it is drawn, so that forms drawing
+// each other many times over cannot run without end. Page content streams are
+// read once and need no bound beyond their size.
+const pdfMaxFormOperators = 1 << 20
+
+// pdfNativeText extracts text without pdftotext by interpreting the text
+// operators (Tj, TJ, ' and ") of each page's content streams and of the form
+// XObjects they draw. Strings are decoded through the font's ToUnicode CMap,
+// or as WinAnsi for simple fonts without one. Lines follow the text
+// positioning operators, so the layout is approximate; like pdftotext's
+// output, every page ends with a form feed.
+func pdfNativeText(data []byte, opts Options) (string, error) {
+	f, err := parsePDF(data, opts.maxDecompressedBytes())
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrPDFNoText, err)
+	}
+	if f.trailer["Encrypt"] != nil {
+		return "", fmt.Errorf("%w: the file is encrypted", ErrPDFNoText)
+	}
+	pages := f.pages()
+	first, last := opts.pdfPageRange()
+	if last > 0 && last < len(pages) {
+		pages = pages[:last]
+	}
+	pages = pages[min(first-1, len(pages)):]
+	var b strings.Builder
+	found := false
+	ops := pdfMaxFormOperators
+	for _, ref := range pages {
+		page := f.dict(ref)
+		w := &pdfTextWriter{file: f, fonts: make(map[pdfRef]*pdfFont), ops: &ops}
+		w.run(f.contents(page["Contents"]), f.dict(f.inherited(page, "Resources")), 0)
+		if text := strings.TrimSpace(w.b.String()); text != "" {
+			b.WriteString(text + "\n")
+			found = true
+		}
+		b.WriteByte('\f')
+	}
+	if !found {
+		return "", fmt.Errorf("%w: no text layer found", ErrPDFNoText)
+	}
+	return b.String(), nil
+}
+
+// inherited returns the page attribute key, looking it up through the page
+// tree when the page does not set it.
+func (f *pdfFile) inherited(page pdfDict, key pdfName) any {
+	node := page
+	for i := 0; node != nil && i < pdfMaxDepth; i++ {
+		if v, ok := node[key]; ok {
+			return v
+		}
+		node = f.dict(node["Parent"])
+	}
+	return nil
+}
+
+// contents returns the decoded content of a page's content stream or array
+// of streams, which form one stream when joined.
+func (f *pdfFile) contents(v any) []byte {
+	var streams []*pdfStream
+	switch v := f.resolve(v).(type) {
+	case *pdfStream:
+		streams = append(streams, v)
+	case pdfArray:
+		for _, s := range v {
+			if s, ok := f.resolve(s).(*pdfStream); ok {
+				streams = append(streams, s)
+			}
+		}
+	}
+	var out []byte
+	for _, s := range streams {
+		data, err := f.decode(s)
+		if err != nil {
+			continue
+		}
+		out = append(append(out, data...), '\n')
+	}
+	return out
+}
+
+// pdfTextWriter collects the text shown by content streams.
+type pdfTextWriter struct {
+	file  *pdfFile
+	fonts map[pdfRef]*pdfFont
+	font  *pdfFont
+	b     strings.Builder
+	// layers, when set, receives the text by optional content group name
+	// instead of b; text outside any group goes to pdfDefaultLayer
+	layers map[string]*strings.Builder
+	// the open marked-content sequences: the name of the group each one
+	// shows, "" for those that are not optional content
+	marked []string
+	// y of the last text matrix set by Tm, to tell new lines from moves
+	// along the same line
+	lineY  float64
+	haveTm bool
+	// the form XObjects being drawn, innermost last, so that a form that
+	// draws itself is skipped
+	forms []pdfRef
+	// ops counts down the form operators left for the document
+	ops *int
+}
+
+// run interprets content with the given resources. depth counts nested form
+// XObjects.
+func (w *pdfTextWriter) run(content []byte, res pdfDict, depth int) {
+	if depth > pdfMaxDepth {
+		return
+	}
+	p := &pdfParser{data: content}
+	var operands []any
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return
+		}
+		start := p.pos
+		v := p.value(0)
+		if p.pos != start {
+			operands = append(operands, v)
+			continue
+		}
+		op := p.token()
+		if op == "" {
+			// a stray delimiter
+			p.pos++
+			operands = operands[:0]
+			continue
+		}
+		if depth > 0 {
+			if *w.ops <= 0 {
+				return
+			}
+			*w.ops--
+		}
+		w.operator(p, op, operands, res, depth)
+		operands = operands[:0]
+	}
+}
+
+func (w *pdfTextWriter) operator(p *pdfParser, op string, operands []any, res pdfDict, depth int) {
+	f := w.file
+	last := func() any {
+		if len(operands) == 0 {
+			return nil
+		}
+		return operands[len(operands)-1]
+	}
+	switch op {
+	case "BMC":
+		w.marked = append(w.marked, "")
+	case "BDC":
+		layer := ""
+		if len(operands) == 2 && operands[0] == pdfName("OC") {
+			props := operands[1]
+			if name, ok := props.(pdfName); ok {
+				props = f.dict(res["Properties"])[name]
+			}
+			layer = f.ocgName(props)
+		}
+		w.marked = append(w.marked, layer)
+	case "EMC":
+		if len(w.marked) > 0 {
+			w.marked = w.marked[:len(w.marked)-1]
+		}
+	case "Tf":
+		if len(operands) == 2 {
+			name, _ := operands[0].(pdfName)
+			w.font = w.fontFor(f.dict(res["Font"])[name])
+		}
+	case "Tj":
+		w.show(last())
+	case "'", "\"":
+		w.newline()
+		w.show(last())
+	case "TJ":
+		for _, v := range f.array(last()) {
+			// a large negative adjustment moves right by about a space
+			if n, ok := pdfNumber(v); ok {
+				if n < -200 {
+					w.space()
+				}
+				continue
+			}
+			w.show(v)
+		}
+	case "T*":
+		w.newline()
+	case "Td", "TD":
+		if len(operands) == 2 {
+			if ty, _ := pdfNumber(operands[1]); ty != 0 {
+				w.newline()
+			} else {
+				w.space()
+			}
+		}
+	case "Tm":
+		if len(operands) == 6 {
+			y, _ := pdfNumber(operands[5])
+			if !w.haveTm || y != w.lineY {
+				w.newline()
+			} else {
+				w.space()
+			}
+			w.lineY, w.haveTm = y, true
+		}
+	case "Do":
+		name, _ := last().(pdfName)
+		v := f.dict(res["XObject"])[name]
+		ref, isRef := v.(pdfRef)
+		for _, r := range w.forms {
+			if isRef && r == ref {
+				return
+			}
+		}
+		xobj, ok := f.resolve(v).(*pdfStream)
+		if !ok || xobj.dict["Subtype"] != pdfName("Form") {
+			return
+		}
+		data, err := f.decode(xobj)
+		if err != nil {
+			return
+		}
+		inner := f.dict(xobj.dict["Resources"])
+		if inner == nil {
+			inner = res
+		}
+		// a form may belong to a group itself
+		layer := f.ocgName(xobj.dict["OC"])
+		w.marked = append(w.marked, layer)
+		w.forms = append(w.forms, ref)
+		w.run(data, inner, depth+1)
+		w.forms = w.forms[:len(w.forms)-1]
+		w.marked = w.marked[:len(w.marked)-1]
+	case "ID":
+		// inline image data runs up to EI after whitespace
+		for i := p.pos; i+2 < len(p.data); i++ {
+			if isPDFSpace(p.data[i]) && p.data[i+1] == 'E' && p.data[i+2] == 'I' &&
+				(i+3 == len(p.data) || isPDFSpace(p.data[i+3]) || isPDFDelim(p.data[i+3])) {
+				p.pos = i + 3
+				return
+			}
+		}
+		p.pos = len(p.data)
+	}
+}
+
+func (w *pdfTextWriter) show(v any) {
+	s, ok := v.(pdfString)
+	if !ok {
+		return
+	}
+	if w.font == nil {
+		w.font = &pdfFont{codeLens: []int{1}}
+	}
+	w.out().WriteString(w.font.decode(s))
+}
+
+// out returns where text currently goes: b, or the builder of the innermost
+// open optional content group.
+func (w *pdfTextWriter) out() *strings.Builder {
+	if w.layers == nil {
+		return &w.b
+	}
+	layer := pdfDefaultLayer
+	for i := len(w.marked) - 1; i >= 0; i-- {
+		if w.marked[i] != "" {
+			layer = w.marked[i]
+			break
+		}
+	}
+	b := w.layers[layer]
+	if b == nil {
+		b = &strings.Builder{}
+		w.layers[layer] = b
+	}
+	return b
+}
+
+func (w *pdfTextWriter) newline() {
+	b := w.out()
+	if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+		b.WriteByte('\n')
+	}
+}
+
+func (w *pdfTextWriter) space() {
+	b := w.out()
+	if s := b.String(); s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		b.WriteByte(' ')
+	}
+}
+
+func (w *pdfTextWriter) fontFor(v any) *pdfFont {
+	ref, isRef := v.(pdfRef)
+	if isRef {
+		if font, ok := w.fonts[ref]; ok {
+			return font
+		}
+	}
+	font := w.file.font(w.file.dict(v))
+	if isRef {
+		w.fonts[ref] = font
+	}
+	return font
+}
+
+// pdfFont decodes the strings shown with one font.
+type pdfFont struct {
+	// toUnicode maps character codes to text; nil when the font has no
+	// ToUnicode CMap
+	toUnicode map[string]string
+	// codeLens are the code lengths in bytes, shortest first
+	codeLens []int
+	// composite fonts without ToUnicode cannot be decoded
+	composite bool
+}
+
+func (f *pdfFile) font(d pdfDict) *pdfFont {
+	font := &pdfFont{codeLens: []int{1}}
+	if d["Subtype"] == pdfName("Type0") {
+		font.composite = true
+		font.codeLens = []int{2}
+	}
+	s, ok := f.resolve(d["ToUnicode"]).(*pdfStream)
+	if !ok {
+		return font
+	}
+	data, err := f.decode(s)
+	if err != nil {
+		return font
+	}
+	font.toUnicode, font.codeLens = parseToUnicode(data, font.codeLens)
+	return font
+}
+
+// decode converts the codes of s to text. Codes the CMap does not map are
+// dropped.
+func (ft *pdfFont) decode(s pdfString) string {
+	if ft.toUnicode == nil {
+		if ft.composite {
+			return ""
+		}
+		text, err := charmap.Windows1252.NewDecoder().String(string(s))
+		if err != nil {
+			return ""
+		}
+		return text
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		n := ft.codeLens[0]
+		for _, l := range ft.codeLens {
+			if i+l <= len(s) {
+				if text, ok := ft.toUnicode[string(s[i:i+l])]; ok {
+					b.WriteString(text)
+					n = l
+					break
+				}
+			}
+		}
+		i += n
+	}
+	return b.String()
+}
+
+// parseToUnicode reads the bfchar and bfrange mappings of a ToUnicode CMap
+// and the code lengths of its codespace ranges, keeping defLens when it
+// declares none.
+func parseToUnicode(data []byte, defLens []int) (map[string]string, []int) {
+	m := make(map[string]string)
+	lens := make(map[int]bool)
+	p := &pdfParser{data: data}
+	var operands []any
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			break
+		}
+		start := p.pos
+		v := p.value(0)
+		if p.pos != start {
+			operands = append(operands, v)
+			continue
+		}
+		op := p.token()
+		if op == "" {
+			p.pos++
+			operands = operands[:0]
+			continue
+		}
+		switch op {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				if lo, ok := operands[i].(pdfString); ok && len(lo) > 0 {
+					lens[len(lo)] = true
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				code, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					m[string(code)] = utf16BEString(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 {
+					bfRange(m, lo, hi, operands[i+2])
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	if len(lens) == 0 {
+		for code := range m {
+			lens[len(code)] = true
+		}
+	}
+	if len(lens) == 0 {
+		return m, defLens
+	}
+	var sorted []int
+	for l := range lens {
+		sorted = append(sorted, l)
+	}
+	sort.Ints(sorted)
+	return m, sorted
+}
+
+// bfRange maps the codes lo..hi to consecutive characters starting at dst,
+// or to the strings of dst when it is an array.
+func bfRange(m map[string]string, lo, hi pdfString, dst any) {
+	first, last := codeValue(lo), codeValue(hi)
+	if last < first || last-first >= pdfMaxRangeCodes {
+		return
+	}
+	arr, _ := dst.(pdfArray)
+	start, isString := dst.(pdfString)
+	u := utf16BE(start)
+	code := []byte(lo)
+	for c, i := first, 0; c <= last; c, i = c+1, i+1 {
+		for j := len(code) - 1; j >= 0; j-- {
+			code[j] = byte(c >> (8 * (len(code) - 1 - j)))
+		}
+		switch {
+		case isString && len(u) > 0:
+			// the last UTF-16 unit is incremented for each code
+			next := append([]uint16(nil), u...)
+			next[len(next)-1] += uint16(i)
+			m[string(code)] = string(utf16.Decode(next))
+		case i < len(arr):
+			if s, ok := arr[i].(pdfString); ok {
+				m[string(code)] = utf16BEString(s)
+			}
+		}
+	}
+}
+
+// codeValue reads a character code as a big-endian number.
+func codeValue(s pdfString) int {
+	n := 0
+	for i := 0; i < len(s) && i < 4; i++ {
+		n = n<<8 | int(s[i])
+	}
+	return n
+}
+
+func utf16BE(s pdfString) []uint16 {
+	u := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		u = append(u, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return u
+}
+
+func utf16BEString(s pdfString) string {
+	if len(s) == 1 {
+		// some writers map to a single byte
+		return string(rune(s[0]))
+	}
+	return string(utf16.Decode(utf16BE(s)))
+}
+
+// pdfNumber returns an integer or real operand as a float64.
+func pdfNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
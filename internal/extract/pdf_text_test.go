@@ -0,0 +1,65 @@
+package extract
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFormPDF builds a one-page PDF whose page and form XObjects can all
+// draw form i, the object 4+i holding forms[i], as /Fi, and show text in F1,
+// a Helvetica font without a ToUnicode map.
+func testFormPDF(page string, forms ...string) []byte {
+	res := "<</Font <</F1 <</Type/Font/Subtype/Type1/BaseFont/Helvetica>>>>/XObject <<"
+	for i := range forms {
+		res += "/F" + strconv.Itoa(i) + " " + strconv.Itoa(4+i) + " 0 R"
+	}
+	res += ">>>>"
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n1 0 obj\n<</Type/Catalog/Pages 2 0 R>>\nendobj\n")
+	b.WriteString("2 0 obj\n<</Type/Pages/Kids [3 0 R]/Count 1>>\nendobj\n")
+	b.WriteString("3 0 obj\n<</Type/Page/Parent 2 0 R/MediaBox [0 0 612 792]/Resources " + res + "/Contents " + strconv.Itoa(4+len(forms)) + " 0 R>>\nendobj\n")
+	for i, content := range append(forms, page) {
+		dict := "<</Length " + strconv.Itoa(len(content))
+		if i < len(forms) {
+			dict += "/Type/XObject/Subtype/Form/BBox [0 0 612 792]/Resources " + res
+		}
+		b.WriteString(strconv.Itoa(4+i) + " 0 obj\n" + dict + ">>\nstream\n" + content + "\nendstream\nendobj\n")
+	}
+	b.WriteString("trailer\n<</Root 1 0 R/Size " + strconv.Itoa(5+len(forms)) + ">>\nstartxref\n0\n%%EOF\n")
+	return []byte(b.String())
+}
+
+func TestPDFFormRecursion(t *testing.T) {
+	// 40 forms, each drawing the next twice, hold 2^40 draws without a cycle
+	var chain []string
+	for i := 1; i < 40; i++ {
+		chain = append(chain, "/F"+strconv.Itoa(i)+" Do /F"+strconv.Itoa(i)+" Do")
+	}
+	chain = append(chain, "BT /F1 12 Tf 72 700 Td (leaf) Tj ET")
+
+	for _, tc := range []struct {
+		name  string
+		data  []byte
+		want  string
+		whole bool
+	}{
+		{"form drawn twice", testFormPDF("/F0 Do /F0 Do", "BT /F1 12 Tf 72 700 Td (form) Tj ET"), "form\nform\n\f", true},
+		{"form drawing itself", testFormPDF("/F0 Do", "BT /F1 12 Tf 72 700 Td (form) Tj ET /F0 Do /F0 Do"), "form\n\f", true},
+		{"forms drawing each other", testFormPDF("/F0 Do", "BT /F1 12 Tf 72 700 Td (a) Tj ET /F1 Do /F1 Do", "BT /F1 12 Tf 72 680 Td (b) Tj ET /F0 Do /F0 Do"), "a\nb\nb\n\f", true},
+		{"forms drawing the next twice", testFormPDF("/F0 Do", chain...), "leaf\nleaf\n", false},
+	} {
+		start := time.Now()
+		got, err := pdfNativeText(tc.data, DefaultOptions())
+		if err != nil || tc.whole && got != tc.want || !tc.whole && !strings.HasPrefix(got, tc.want) {
+			t.Errorf("%s: got %.40q, %v; want %q", tc.name, got, err, tc.want)
+		}
+		if d := time.Since(start); d > 10*time.Second {
+			t.Errorf("%s: took %v", tc.name, d)
+		}
+		if _, err := ExtractPDFLayers(tc.data); err != nil {
+			t.Errorf("%s: ExtractPDFLayers: %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ErrNoText is returned when Options.RequireText is set and the document has
+// no visible text: it is empty or consists only of whitespace, page breaks or
+// byte order marks.
+var ErrNoText = errors.New("document contains no text")
+
+// TabHandling selects what happens to tab characters in the output.
+type TabHandling string
+
+const (
+	TabsKeep   TabHandling = "keep"   // leave tabs as produced by the extractor (default)
+	TabsSpace  TabHandling = "space"  // replace each tab with a single space
+	TabsRemove TabHandling = "remove" // drop tabs
+)
+
+// postProcess applies format-independent output options. It runs after the
+// extractors, so it also rewrites tabs they emit as table cell delimiters.
+func postProcess(text string, opts Options) (string, error) {
+	if len(opts.StripPatterns) > 0 {
+		var err error
+		if text, err = stripPatterns(text, opts.StripPatterns); err != nil {
+			return "", err
+		}
+	}
+	if opts.ExpandTabs > 0 {
+		text = expandTabs(text, opts.ExpandTabs)
+	}
+	switch opts.TabHandling {
+	case "", TabsKeep:
+	case TabsSpace:
+		text = strings.ReplaceAll(text, "\t", " ")
+	case TabsRemove:
+		text = strings.ReplaceAll(text, "\t", "")
+	default:
+		return "", errors.New("unknown tab handling: " + string(opts.TabHandling))
+	}
+	if opts.MaxConsecutiveBlankLines > 0 {
+		text = collapseBlankLines(text, opts.MaxConsecutiveBlankLines)
+	}
+	// a blank result is always reported the same way, whatever whitespace the
+	// extractor happened to produce for it
+	if isBlankText(text) {
+		if opts.RequireText {
+			return "", ErrNoText
+		}
+		return "", nil
+	}
+	return text, nil
+}
+
+// stripPatterns removes every match of the patterns, line by line, and drops
+// lines that held nothing but matches. Lines that were blank to begin with
+// are kept.
+func stripPatterns(text string, patterns []string) (string, error) {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return "", fmt.Errorf("invalid strip pattern %q: %w", p, err)
+		}
+		res[i] = re
+	}
+	lines := strings.SplitAfter(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		stripped := line
+		for _, re := range res {
+			stripped = re.ReplaceAllString(stripped, "")
+		}
+		if stripped != line && strings.TrimSpace(stripped) == "" {
+			continue
+		}
+		kept = append(kept, stripped)
+	}
+	return strings.Join(kept, ""), nil
+}
+
+// collapseBlankLines shortens every run of blank lines, which hold nothing
+// but spaces and tabs, to at most max empty lines. Lines with a form feed
+// mark page breaks and are kept.
+func collapseBlankLines(text string, max int) string {
+	lines := strings.SplitAfter(text, "\n")
+	kept := lines[:0]
+	blank := 0
+	for _, line := range lines {
+		if strings.Trim(line, " \t\r\n") != "" || !strings.HasSuffix(line, "\n") {
+			blank = 0
+			kept = append(kept, line)
+			continue
+		}
+		if blank++; blank <= max {
+			kept = append(kept, "\n")
+		}
+	}
+	return strings.Join(kept, "")
+}
+
+// expandTabs replaces each tab with the spaces that reach the next tab stop,
+// counting columns in runes from the start of each line.
+func expandTabs(text string, width int) string {
+	if !strings.Contains(text, "\t") {
+		return text
+	}
+	var b strings.Builder
+	b.Grow(len(text))
+	col := 0
+	for _, r := range text {
+		switch r {
+		case '\t':
+			n := width - col%width
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+		case '\n', '\r':
+			b.WriteRune(r)
+			col = 0
+		default:
+			b.WriteRune(r)
+			col++
+		}
+	}
+	return b.String()
+}
+
+// isBlankText reports whether s has no characters other than whitespace
+// (including form feeds from page breaks) and stray BOMs.
+func isBlankText(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsSpace(r) && r != '\uFEFF'
+	}) < 0
+}
@@ -0,0 +1,145 @@
+package extract
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExpandTabs(t *testing.T) {
+	for _, tc := range []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{"a\tb", 4, "a   b"},
+		{"abcd\tb", 4, "abcd    b"},
+		{"\tx\nяб\ty", 4, "    x\nяб  y"},
+		{"no tabs", 4, "no tabs"},
+	} {
+		if got := expandTabs(tc.in, tc.width); got != tc.want {
+			t.Errorf("expandTabs(%q, %d) = %q, want %q", tc.in, tc.width, got, tc.want)
+		}
+	}
+}
+
+func TestTabHandling(t *testing.T) {
+	csv := []byte("name,qty\nbolt,3\n")
+	for _, tc := range []struct {
+		mode TabHandling
+		want string
+	}{
+		{"", "name\tqty\nbolt\t3\n"},
+		{TabsKeep, "name\tqty\nbolt\t3\n"},
+		{TabsSpace, "name qty\nbolt 3\n"},
+		{TabsRemove, "nameqty\nbolt3\n"},
+	} {
+		opts := DefaultOptions()
+		opts.TabHandling = tc.mode
+		got, err := ExtractTextWithOptions("a.csv", csv, opts)
+		if err != nil || got != tc.want {
+			t.Errorf("%q: got %q, %v; want %q", tc.mode, got, err, tc.want)
+		}
+	}
+	opts := DefaultOptions()
+	opts.TabHandling = "tabs"
+	if _, err := ExtractTextWithOptions("a.csv", csv, opts); err == nil {
+		t.Error("unknown tab handling accepted")
+	}
+}
+
+func TestMaxConsecutiveBlankLines(t *testing.T) {
+	txt := []byte("a\n\n\n\n\nb\n \t\n\nc\n\f\n\n\nd\n")
+	for _, tc := range []struct {
+		max  int
+		want string
+	}{
+		{0, "a\n\n\n\n\nb\n \t\n\nc\n\f\n\n\nd\n"},
+		{1, "a\n\nb\n\nc\n\f\n\nd\n"},
+		{2, "a\n\n\nb\n\n\nc\n\f\n\n\nd\n"},
+		{3, "a\n\n\n\nb\n\n\nc\n\f\n\n\nd\n"},
+	} {
+		opts := DefaultOptions()
+		opts.MaxConsecutiveBlankLines = tc.max
+		got, err := ExtractTextWithOptions("a.txt", txt, opts)
+		if err != nil || got != tc.want {
+			t.Errorf("max %d: got %q, %v; want %q", tc.max, got, err, tc.want)
+		}
+	}
+	opts := DefaultOptions()
+	opts.MaxConsecutiveBlankLines = -1
+	if _, err := ExtractTextWithOptions("a.txt", txt, opts); err == nil {
+		t.Error("negative MaxConsecutiveBlankLines accepted")
+	}
+}
+
+func TestBlankDocuments(t *testing.T) {
+	for _, tc := range []struct {
+		name, filename string
+		data           []byte
+	}{
+		{"empty", "a.txt", nil},
+		{"bom only", "a.txt", []byte("\xEF\xBB\xBF")},
+		{"utf-16 bom only", "a.txt", []byte{0xFF, 0xFE}},
+		{"whitespace", "a.txt", []byte(" \t\r\n\n  ")},
+		{"nbsp and bom", "a.txt", []byte("\u00a0\ufeff\n")},
+		{"page breaks", "a.txt", []byte("\f\n\f")},
+		{"empty rtf", "a.rtf", []byte(`{\rtf1\ansi \par \page \tab }`)},
+		{"empty docx", "a.docx", testDOCX(t, `<w:p/><w:p><w:r><w:t xml:space="preserve">   </w:t></w:r></w:p>`)},
+	} {
+		got, err := ExtractText(tc.filename, tc.data)
+		if err != nil || got != "" {
+			t.Errorf("%s: got %q, %v; want empty text", tc.name, got, err)
+		}
+		opts := DefaultOptions()
+		opts.RequireText = true
+		if _, err := ExtractTextWithOptions(tc.filename, tc.data, opts); !errors.Is(err, ErrNoText) {
+			t.Errorf("%s with RequireText: err = %v, want ErrNoText", tc.name, err)
+		}
+	}
+	opts := DefaultOptions()
+	opts.RequireText = true
+	if got, err := ExtractTextWithOptions("a.txt", []byte("\xEF\xBB\xBF x "), opts); err != nil || got != " x " {
+		t.Errorf("text with RequireText: got %q, %v", got, err)
+	}
+}
+
+func TestStripPatterns(t *testing.T) {
+	text := "Report\nCONFIDENTIAL — DO NOT COPY\n\nTotal: 10 CONFIDENTIAL — DO NOT COPY units\nPage 3 of 9\n"
+	for _, tc := range []struct {
+		name     string
+		patterns []string
+		want     string
+	}{
+		{"none", nil, text},
+		{"stamp", []string{`CONFIDENTIAL — DO NOT COPY ?`}, "Report\n\nTotal: 10 units\nPage 3 of 9\n"},
+		{"several", []string{`CONFIDENTIAL — DO NOT COPY ?`, `^Page \d+ of \d+`}, "Report\n\nTotal: 10 units\n"},
+		{"no match", []string{`SECRET`}, text},
+	} {
+		opts := DefaultOptions()
+		opts.StripPatterns = tc.patterns
+		for name, data := range map[string][]byte{
+			"a.txt":  []byte(text),
+			"a.docx": testDOCX(t, docxParagraphs(text)),
+		} {
+			got, err := ExtractTextWithOptions(name, data, opts)
+			if err != nil || got != tc.want {
+				t.Errorf("%s %s: got %q, %v; want %q", tc.name, name, got, err, tc.want)
+			}
+		}
+	}
+	opts := DefaultOptions()
+	opts.StripPatterns = []string{`(`}
+	if _, err := ExtractTextWithOptions("a.txt", []byte(text), opts); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
+
+// docxParagraphs turns each line of text into a DOCX paragraph.
+func docxParagraphs(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		b.WriteString(`<w:p><w:r><w:t xml:space="preserve">` + line + `</w:t></w:r></w:p>`)
+	}
+	return b.String()
+}
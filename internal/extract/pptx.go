@@ -0,0 +1,180 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var rePPTXSlide = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+// pptxSlide is a slide part and its number.
+type pptxSlide struct {
+	Num  int
+	Part string
+}
+
+// pptxSlides lists slide parts sorted by their numeric suffix, so slide10 follows slide9.
+func pptxSlides(files map[string]*zip.File) []pptxSlide {
+	var slides []pptxSlide
+	for name := range files {
+		if m := rePPTXSlide.FindStringSubmatch(name); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			slides = append(slides, pptxSlide{Num: n, Part: name})
+		}
+	}
+	sort.Slice(slides, func(i, j int) bool { return slides[i].Num < slides[j].Num })
+	return slides
+}
+
+// extractPPTX reads the text of each slide in numeric order, slides separated
+// by a form feed like PDF pages. Speaker notes follow their slide under a
+// [Notes] line when Options.IncludeSpeakerNotes is set. A presentation whose
+// slides carry no text but whose media does, such as scanned pages, fails
+// with ErrImageOnly or is recognized with Options.OCR.
+func extractPPTX(ctx context.Context, data []byte, opts Options) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	files := zipIndex(zr)
+	slides := pptxSlides(files)
+	if opts.FirstUnitOnly && len(slides) > 1 {
+		slides = slides[:1]
+	}
+	var out []string
+	for _, slide := range slides {
+		text, err := pptxPartText(files, slide.Part, false)
+		if err != nil {
+			return "", err
+		}
+		if opts.IncludeSpeakerNotes {
+			for _, rel := range sortedRels(readRels(files, slide.Part)) {
+				if !strings.HasSuffix(rel.Type, "/notesSlide") {
+					continue
+				}
+				notes, err := pptxPartText(files, relTarget(slide.Part, rel.Target), true)
+				if err != nil {
+					return "", err
+				}
+				if notes != "" {
+					text += "[Notes]\n" + notes
+				}
+			}
+		}
+		out = append(out, text)
+	}
+	text := strings.Join(out, "\f")
+	if isBlankText(text) {
+		if media := pptxMedia(files); len(media) > 0 {
+			if !opts.OCR {
+				return "", ErrImageOnly
+			}
+			return ocrImages(ctx, media, opts)
+		}
+	}
+	if opts.IncludeComments {
+		comments, err := pptxComments(files)
+		if err != nil {
+			return "", err
+		}
+		if opts.FirstUnitOnly && len(slides) > 0 {
+			var shown []Comment
+			for _, c := range comments {
+				if c.Location == "slide "+strconv.Itoa(slides[0].Num) {
+					shown = append(shown, c)
+				}
+			}
+			comments = shown
+		}
+		text += formatComments(comments)
+	}
+	return text, nil
+}
+
+// pptxMedia returns the files under ppt/media/ in name order.
+func pptxMedia(files map[string]*zip.File) [][]byte {
+	var names []string
+	for name := range files {
+		if strings.HasPrefix(name, "ppt/media/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var media [][]byte
+	for _, name := range names {
+		if data, err := readZipFile(files[name]); err == nil {
+			media = append(media, data)
+		}
+	}
+	return media
+}
+
+// pptxPartText reads a slide or notes part.
+func pptxPartText(files map[string]*zip.File, part string, notes bool) (string, error) {
+	f, ok := files[part]
+	if !ok {
+		return "", nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	return pptxText(rc, notes)
+}
+
+// pptxText collects the DrawingML text (a:t) of a slide part in document
+// order, one line per non-empty paragraph. With notes set only the body
+// placeholder is read, leaving out the slide image, number and header
+// placeholders of a notes page.
+func pptxText(r io.Reader, notes bool) (string, error) {
+	dec := xml.NewDecoder(r)
+	var b strings.Builder
+	var para strings.Builder
+	skipShape := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "ph":
+				skipShape = notes && xmlAttr(t, "type") != "body"
+			case "br":
+				para.WriteByte('\n')
+			case "t":
+				var s string
+				if err := dec.DecodeElement(&s, &t); err != nil {
+					return "", err
+				}
+				if !skipShape {
+					para.WriteString(s)
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "sp":
+				skipShape = false
+			case "p":
+				if strings.TrimSpace(para.String()) != "" {
+					b.WriteString(para.String())
+					b.WriteByte('\n')
+				}
+				para.Reset()
+			}
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,36 @@
+package extract
+
+import (
+	"errors"
+	"testing"
+)
+
+const testPresentationNS = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"`
+
+// testSlide is a slide part holding text in one shape, or only a picture when
+// text is empty.
+func testSlide(text string) string {
+	body := `<p:pic/>`
+	if text != "" {
+		body = `<p:sp><p:txBody><a:p><a:r><a:t>` + text + `</a:t></a:r></a:p></p:txBody></p:sp>`
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?><p:sld ` + testPresentationNS + `><p:cSld><p:spTree>` + body + `</p:spTree></p:cSld></p:sld>`
+}
+
+func TestPPTXImageOnly(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		files []string
+		want  string
+		err   error
+	}{
+		{"scanned", []string{"ppt/slides/slide1.xml", testSlide(""), "ppt/media/image1.png", "\x89PNG"}, "", ErrImageOnly},
+		{"text and pictures", []string{"ppt/slides/slide1.xml", testSlide("title"), "ppt/media/image1.png", "\x89PNG"}, "title\n", nil},
+		{"empty", []string{"ppt/slides/slide1.xml", testSlide("")}, "", nil},
+	} {
+		got, err := ExtractText("a.pptx", testZip(t, tc.files...))
+		if !errors.Is(err, tc.err) || got != tc.want {
+			t.Errorf("%s: got %q, %v; want %q, %v", tc.name, got, err, tc.want, tc.err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package extract
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Result is the detailed outcome of an extraction.
+type Result struct {
+	Text string `json:"text"`
+	// Format is the format whose extractor produced Text, such as "docx" or
+	// "csv"; for gzip input it is the format of the decompressed data. It is
+	// also set on failure when the format had been determined.
+	Format string `json:"format,omitempty"`
+	// DetectedEncoding is the character encoding of text formats (TXT, CSV,
+	// TSV) as DetectEncoding names it; other formats leave it empty.
+	DetectedEncoding string `json:"detected_encoding,omitempty"`
+	// PageCount is the number of PDF pages or PPTX slides extracted; formats
+	// without pages leave it 0.
+	PageCount int `json:"page_count,omitempty"`
+	// Warnings describe recoverable problems, such as a damaged archive read
+	// in best-effort mode. Each starts with a code and a colon, such as
+	// "truncated_pdf: ...".
+	Warnings []string `json:"warnings,omitempty"`
+	// InputSHA256 and TextSHA256 are hex-encoded SHA-256 digests of the input bytes
+	// and of Text, usable as cache keys and for integrity checks.
+	InputSHA256 string `json:"input_sha256"`
+	TextSHA256  string `json:"text_sha256"`
+	// Timings breaks the extraction time down by phase, keyed by the Phase*
+	// constants.
+	Timings map[string]time.Duration `json:"timings,omitempty"`
+}
+
+// ExtractResult is like ExtractText but returns a Result.
+func ExtractResult(filename string, data []byte) (Result, error) {
+	return ExtractDetailed(filename, data, DefaultOptions())
+}
+
+// ExtractDetailed is like ExtractTextWithOptions but returns a Result.
+func ExtractDetailed(filename string, data []byte, opts Options) (Result, error) {
+	return ExtractDetailedContext(context.Background(), filename, data, opts)
+}
+
+// ExtractDetailedContext is like ExtractDetailed but gives up when ctx is done,
+// returning ctx.Err(). External tools such as pdftotext are killed.
+func ExtractDetailedContext(ctx context.Context, filename string, data []byte, opts Options) (Result, error) {
+	res := Result{InputSHA256: sha256Hex(data)}
+	t := &timings{m: make(map[string]time.Duration)}
+	info := &extractInfo{}
+	start := time.Now()
+	text, err := extractText(context.WithValue(withTimings(ctx, t), infoKey{}, info), filename, data, opts)
+	res.Timings = t.snapshot()
+	res.Timings[PhaseTotal] = time.Since(start)
+	info.fill(&res)
+	if err != nil {
+		return res, err
+	}
+	res.Text = text
+	res.TextSHA256 = sha256Hex([]byte(text))
+	return res, nil
+}
+
+// extractInfo collects the details of an extraction that end up in Result.
+// Like timings it travels in the context; the extraction may still be running
+// after ExtractDetailedContext gave up on it.
+type extractInfo struct {
+	mu       sync.Mutex
+	format   string
+	encoding string
+	pages    int
+	warnings []string
+}
+
+type infoKey struct{}
+
+// recordInfo applies update to the context's extractInfo, if any.
+func recordInfo(ctx context.Context, update func(*extractInfo)) {
+	if info, _ := ctx.Value(infoKey{}).(*extractInfo); info != nil {
+		info.mu.Lock()
+		update(info)
+		info.mu.Unlock()
+	}
+}
+
+// recordFormat notes the format whose extractor ran. It is called as an
+// extractor returns, so of nested extractors the outermost is recorded.
+func recordFormat(ctx context.Context, format string) {
+	recordInfo(ctx, func(info *extractInfo) { info.format = format })
+}
+
+// addWarning records a recoverable problem for Result.Warnings.
+func addWarning(ctx context.Context, warning string) {
+	recordInfo(ctx, func(info *extractInfo) { info.warnings = append(info.warnings, warning) })
+}
+
+func (info *extractInfo) fill(res *Result) {
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	res.Format = info.format
+	res.DetectedEncoding = info.encoding
+	res.PageCount = info.pages
+	res.Warnings = append([]string(nil), info.warnings...)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
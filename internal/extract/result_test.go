@@ -0,0 +1,59 @@
+package extract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestExtractDetailedSHA256(t *testing.T) {
+	hash := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	input := "\xEF\xBB\xBFline\r\n"
+	res, err := ExtractDetailed("a.txt", []byte(input), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Text != "line\n" || res.InputSHA256 != hash(input) || res.TextSHA256 != hash("line\n") {
+		t.Errorf("got %q, input %s, text %s", res.Text, res.InputSHA256, res.TextSHA256)
+	}
+
+	// the input is hashed even when extraction fails
+	res, err = ExtractDetailed("a.docx", []byte("not a zip"), DefaultOptions())
+	if err == nil || res.InputSHA256 != hash("not a zip") || res.TextSHA256 != "" {
+		t.Errorf("failure: %v, input %q, text %q", err, res.InputSHA256, res.TextSHA256)
+	}
+}
+
+func TestExtractDetailedTimings(t *testing.T) {
+	ocr := DefaultOptions()
+	ocr.OCR = true
+	ocr.TesseractPath = fakeOCRTools(t)
+	for _, tc := range []struct {
+		name     string
+		filename string
+		data     []byte
+		opts     Options
+		phases   []string
+	}{
+		{"text", "a.txt", []byte("hello"), DefaultOptions(), []string{PhaseDetect, PhaseParse, PhaseNormalize, PhaseTotal}},
+		{"gzip", "a.txt.gz", gzipBytes(t, []byte("hello")), DefaultOptions(), []string{PhaseDetect, PhaseDecompress, PhaseParse, PhaseNormalize, PhaseTotal}},
+		{"pdf with ocr", "a.pdf", testTextPDF("BT /F1 12 Tf 72 720 Td (text) Tj ET", ""), ocr, []string{PhaseDetect, PhaseParse, PhaseSubprocess, PhaseNormalize, PhaseTotal}},
+	} {
+		res, err := ExtractDetailed(tc.filename, tc.data, tc.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		for _, phase := range tc.phases {
+			if _, ok := res.Timings[phase]; !ok {
+				t.Errorf("%s: no %s phase in %v", tc.name, phase, res.Timings)
+			}
+		}
+		if len(res.Timings) != len(tc.phases) {
+			t.Errorf("%s: got phases %v, want %v", tc.name, res.Timings, tc.phases)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+// rstTextDirectives take prose as their argument, such as the title of a
+// note; the arguments of other directives (paths, languages) are dropped.
+var rstTextDirectives = map[string]bool{
+	"note": true, "warning": true, "tip": true, "hint": true, "important": true,
+	"caution": true, "danger": true, "error": true, "attention": true,
+	"admonition": true, "topic": true, "sidebar": true, "rubric": true,
+	"seealso": true, "deprecated": true, "versionadded": true,
+	"versionchanged": true, "epigraph": true, "glossary": true,
+}
+
+// rstSkipDirectives are dropped together with their content.
+var rstSkipDirectives = map[string]bool{
+	"toctree": true, "raw": true, "image": true, "include": true,
+	"literalinclude": true, "highlight": true, "meta": true, "contents": true,
+	"sectnum": true, "index": true, "only": true, "ifconfig": true,
+}
+
+// rstLiteralDirectives have content that is kept verbatim.
+var rstLiteralDirectives = map[string]bool{
+	"code": true, "code-block": true, "sourcecode": true, "parsed-literal": true,
+	"doctest": true, "math": true,
+}
+
+var (
+	reRSTDirective  = regexp.MustCompile(`^\.\.\s+(?:\|[^|]+\|\s+)?([\w:-]+)::\s*(.*)$`)
+	reRSTFootnote   = regexp.MustCompile(`^\.\.\s+\[[^\]]+\]\s*(.*)$`)
+	reRSTField      = regexp.MustCompile(`^:([^:]+):(?:\s+|$)(.*)$`)
+	reRSTRole       = regexp.MustCompile(":[\\w:+.-]+:`([^`]*)`")
+	reRSTTitleRef   = regexp.MustCompile("`([^`<]*?)\\s*<[^>]*>`__?")
+	reRSTInline     = regexp.MustCompile("``([^`]+)``|`([^`]+)`_{0,2}")
+	reRSTEmphasis   = regexp.MustCompile(`\*\*([^*]+)\*\*|\*([^*\s][^*]*)\*`)
+	reRSTFootRef    = regexp.MustCompile(`\s?\[(?:\d+|#[\w-]*|\*|[\w-]+)\]_`)
+	reRSTTargetName = regexp.MustCompile(`\b(\w+)_\b`)
+)
+
+// extractRST converts reStructuredText to prose. Like extractTeX it is a
+// line-based pass, not a docutils parser: section adornments, comments and
+// directive markup are dropped, titles, admonition text and literal blocks
+// are kept, and inline markup is reduced to its text.
+func extractRST(data []byte) (string, error) {
+	src := strings.TrimPrefix(string(data), "\uFEFF")
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+
+	var out []string
+	skip := -1    // drop the block indented deeper than this
+	literal := -1 // keep verbatim the block indented deeper than this
+	base := -1    // indentation of the first literal line, removed from all
+	options := -1 // drop directive options indented deeper than this
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.ReplaceAll(line, "\t", "        ")
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if trimmed == "" {
+			options = -1
+			continue
+		}
+		if skip >= 0 {
+			if indent > skip {
+				continue
+			}
+			skip = -1
+		}
+		if options >= 0 && indent > options && strings.HasPrefix(trimmed, ":") {
+			continue
+		}
+		options = -1
+		if literal >= 0 {
+			if indent > literal {
+				if base < 0 {
+					base = indent
+				}
+				out = append(out, strings.TrimRight(line[min(indent, base):], " "))
+				continue
+			}
+			literal, base = -1, -1
+		}
+
+		if isRSTAdornment(trimmed) {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "..") && (len(trimmed) == 2 || trimmed[2] == ' ') {
+			switch m := reRSTDirective.FindStringSubmatch(trimmed); {
+			case m != nil:
+				name := strings.ToLower(m[1])
+				if i := strings.LastIndex(name, ":"); i >= 0 {
+					name = name[i+1:] // domain directives such as py:function
+				}
+				switch {
+				case rstSkipDirectives[name] || strings.HasPrefix(trimmed, ".. |"):
+					skip = indent
+					continue
+				case rstLiteralDirectives[name]:
+					literal = indent
+				case rstTextDirectives[name] && m[2] != "":
+					out = append(out, rstInline(m[2]))
+				}
+				options = indent
+			default:
+				if f := reRSTFootnote.FindStringSubmatch(trimmed); f != nil {
+					if f[1] != "" {
+						out = append(out, rstInline(f[1]))
+					}
+					continue
+				}
+				// comments and hyperlink targets, with their indented blocks
+				skip = indent
+			}
+			continue
+		}
+
+		if f := reRSTField.FindStringSubmatch(trimmed); f != nil {
+			trimmed = f[1] + ": " + f[2]
+		}
+		if strings.HasSuffix(trimmed, "::") {
+			// "Example::" introduces a literal block and reads as "Example:"
+			trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, ":"))
+			if strings.HasSuffix(trimmed, " :") || trimmed == ":" {
+				trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, ":"))
+			}
+			literal = indent
+		}
+		if trimmed = rstInline(trimmed); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+// isRSTAdornment reports a section underline, overline or transition: a
+// run of one punctuation character.
+func isRSTAdornment(s string) bool {
+	if len(s) < 3 || !strings.ContainsRune("=-~^\"'`#*+:.<>_!$%&(),/;?@[\\]{|}", rune(s[0])) {
+		return false
+	}
+	return strings.Trim(s, s[:1]) == ""
+}
+
+// rstInline reduces inline markup to its text.
+func rstInline(s string) string {
+	s = reRSTFootRef.ReplaceAllString(s, "")
+	s = reRSTRole.ReplaceAllStringFunc(s, func(m string) string {
+		text := reRSTRole.FindStringSubmatch(m)[1]
+		// :ref:`Title <target>` shows the title
+		if i := strings.Index(text, " <"); i > 0 && strings.HasSuffix(text, ">") {
+			return text[:i]
+		}
+		return strings.TrimPrefix(text, "~")
+	})
+	s = reRSTTitleRef.ReplaceAllString(s, "$1")
+	s = reRSTInline.ReplaceAllString(s, "$1$2")
+	s = reRSTEmphasis.ReplaceAllString(s, "$1$2")
+	s = reRSTTargetName.ReplaceAllString(s, "$1")
+	return strings.TrimSpace(s)
+}
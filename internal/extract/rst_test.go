@@ -0,0 +1,57 @@
+package extract
+
+import "testing"
+
+func TestExtractRST(t *testing.T) {
+	src := "=========\n" +
+		"User Guide\n" +
+		"=========\n" +
+		"\n" +
+		".. contents::\n" +
+		"   :depth: 2\n" +
+		"\n" +
+		"Install\n" +
+		"-------\n" +
+		"\n" +
+		"Call :func:`docparser.extract` or :ref:`the API <api>` with **bold** and *italic* text [1]_.\n" +
+		"See `the site <https://example.com>`_ and ``inline code``.\n" +
+		"\n" +
+		".. note:: Requires Go 1.23.\n" +
+		"   Older versions fail.\n" +
+		"\n" +
+		"Example::\n" +
+		"\n" +
+		"    go build ./...\n" +
+		"      indented\n" +
+		"\n" +
+		".. code-block:: go\n" +
+		"   :linenos:\n" +
+		"\n" +
+		"   fmt.Println(\"hi\")\n" +
+		"\n" +
+		".. image:: logo.png\n" +
+		"   :alt: Logo\n" +
+		"\n" +
+		".. a comment\n" +
+		"   spanning lines\n" +
+		"\n" +
+		":Author: Jane\n" +
+		"\n" +
+		".. [1] The footnote.\n"
+	want := "User Guide\n" +
+		"Install\n" +
+		"Call docparser.extract or the API with bold and italic text.\n" +
+		"See the site and inline code.\n" +
+		"Requires Go 1.23.\n" +
+		"Older versions fail.\n" +
+		"Example:\n" +
+		"go build ./...\n" +
+		"  indented\n" +
+		"fmt.Println(\"hi\")\n" +
+		"Author: Jane\n" +
+		"The footnote."
+	got, err := ExtractText("guide.rst", []byte(src))
+	if err != nil || got != want {
+		t.Errorf("got %q, %v; want %q", got, err, want)
+	}
+}
@@ -0,0 +1,150 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// streamSniffSize is how much of a streamed text file is inspected to pick its encoding.
+const streamSniffSize = 64 << 10
+
+// ExtractTextReader extracts text from r and writes it to w. Plain text files are
+// streamed: the encoding is detected from the first 64 KiB and the rest is decoded
+// and line-ending normalized chunk by chunk, so huge files are never held in memory.
+// Output post-processing options (such as TabHandling) do not apply to streamed text;
+// with Options.TextHook set, which needs the whole text, nothing is streamed.
+// Other formats, including input without an extension whose first 64 KiB carry
+// the signature of one, are read fully and extracted as by ExtractTextWithOptions.
+// Options.EnabledFormats applies to both paths.
+func ExtractTextReader(filename string, r io.Reader, w io.Writer, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	// a format forced by Options.Format is streamed only when it is txt
+	forced := strings.TrimPrefix(strings.ToLower(opts.Format), ".")
+	stream := (ext == ".txt" || ext == "") && (forced == "" || forced == "txt") && opts.TextHook == nil
+	var br *bufio.Reader
+	var prefix []byte
+	if stream {
+		br = bufio.NewReaderSize(r, streamSniffSize)
+		var err error
+		prefix, err = br.Peek(streamSniffSize)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return err
+		}
+		if ext == "" && forced == "" && DetectFormat(prefix) != "" {
+			stream, r = false, br
+		}
+	}
+	if stream {
+		if err := opts.checkFormat("txt"); err != nil {
+			return err
+		}
+	} else {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		text, err := ExtractTextWithOptions(filename, data, opts)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, text)
+		return err
+	}
+
+	var t transform.Transformer
+	switch {
+	case opts.Encoding != "":
+		// Validate has checked the name
+		t = textEncoding(opts.Encoding).NewDecoder()
+	case len(prefix) >= 2 && prefix[0] == 0xFF && prefix[1] == 0xFE:
+		t = unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder()
+	case len(prefix) >= 2 && prefix[0] == 0xFE && prefix[1] == 0xFF:
+		t = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()
+	case validUTF8Prefix(prefix, len(prefix) < streamSniffSize):
+		t = transform.Nop
+	default:
+		t = charmap.ISO8859_1.NewDecoder()
+		if _, name, _, ok := decodeBest(prefix, opts.encodingCandidates()); ok {
+			t = codePage(name).NewDecoder()
+		}
+	}
+	_, err := io.Copy(w, transform.NewReader(br, transform.Chain(t, &bomStripper{}, newlineNormalizer{})))
+	return err
+}
+
+// validUTF8Prefix reports whether p is valid UTF-8, allowing a rune cut off at the
+// end when p is only the beginning of the input.
+func validUTF8Prefix(p []byte, complete bool) bool {
+	if !complete {
+		for i := 0; i < utf8.UTFMax && len(p) > 0; i++ {
+			if r, _ := utf8.DecodeLastRune(p); r != utf8.RuneError {
+				break
+			}
+			p = p[:len(p)-1]
+		}
+	}
+	return utf8.Valid(p)
+}
+
+// bomStripper drops a byte order mark at the start of decoded UTF-8 text.
+type bomStripper struct{ started bool }
+
+func (s *bomStripper) Reset() { s.started = false }
+
+func (s *bomStripper) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	if !s.started {
+		// wait for enough of the text to tell
+		if len(src) < len(utf8BOM) && !atEOF && bytes.HasPrefix(utf8BOM, src) {
+			return 0, 0, transform.ErrShortSrc
+		}
+		s.started = true
+		if bytes.HasPrefix(src, utf8BOM) {
+			nSrc = len(utf8BOM)
+		}
+	}
+	n := copy(dst, src[nSrc:])
+	if nSrc += n; nSrc < len(src) {
+		err = transform.ErrShortDst
+	}
+	return n, nSrc, err
+}
+
+// newlineNormalizer converts CRLF and lone CR line endings to LF.
+type newlineNormalizer struct{ transform.NopResetter }
+
+func (newlineNormalizer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		if nDst >= len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		c := src[nSrc]
+		if c != '\r' {
+			dst[nDst] = c
+			nDst++
+			nSrc++
+			continue
+		}
+		// a CR at the end of the chunk may be the first half of CRLF
+		if nSrc+1 == len(src) && !atEOF {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+		dst[nDst] = '\n'
+		nDst++
+		nSrc++
+		if nSrc < len(src) && src[nSrc] == '\n' {
+			nSrc++
+		}
+	}
+	return nDst, nSrc, nil
+}
@@ -0,0 +1,68 @@
+package extract
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestExtractTextReader(t *testing.T) {
+	cp1251, _ := charmap.Windows1251.NewEncoder().String("Привет, мир\r\nвторая строка")
+	for _, tc := range []struct {
+		name, filename string
+		data           []byte
+		format         string
+		want           string
+	}{
+		{"utf-8", "a.txt", []byte("\xEF\xBB\xBFline 1\r\nline 2\rline 3"), "", "line 1\nline 2\nline 3"},
+		{"cp1251", "a.txt", []byte(cp1251), "", "Привет, мир\nвторая строка"},
+		{"no extension text", "", []byte("plain\r\ntext"), "", "plain\ntext"},
+		{"no extension rtf", "", []byte(`{\rtf1 rich text}`), "", "rich text"},
+		{"no extension docx", "", testDOCX(t, `<w:p><w:r><w:t>zipped text</w:t></w:r></w:p>`), "", "zipped text\n"},
+		{"forced format", "a.txt", []byte(`{\rtf1 rich text}`), "rtf", "rich text"},
+	} {
+		opts := DefaultOptions()
+		opts.Format = tc.format
+		var b bytes.Buffer
+		if err := ExtractTextReader(tc.filename, bytes.NewReader(tc.data), &b, opts); err != nil || b.String() != tc.want {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, b.String(), err, tc.want)
+		}
+	}
+}
+
+// benchmarkText is a large plain text file, as ExtractTextReader streams it.
+var benchmarkText = []byte(strings.Repeat("Съешь же ещё этих мягких французских булок, да выпей чаю.\r\n", 1<<16))
+
+// BenchmarkExtractTextReader and BenchmarkExtractTextWholeFile compare the
+// memory used for a 7 MB text file: streamed, it needs only the buffers.
+func BenchmarkExtractTextReader(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchmarkText)))
+	for range b.N {
+		if err := ExtractTextReader("big.txt", bytes.NewReader(benchmarkText), io.Discard, DefaultOptions()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExtractTextWholeFile(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchmarkText)))
+	for range b.N {
+		if _, err := ExtractText("big.txt", benchmarkText); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestExtractTextReaderTextHook(t *testing.T) {
+	opts := DefaultOptions()
+	opts.TextHook = func(s string) (string, error) { return strings.ReplaceAll(s, "secret", "[REDACTED]"), nil }
+	var b bytes.Buffer
+	if err := ExtractTextReader("a.txt", strings.NewReader("a secret line"), &b, opts); err != nil || b.String() != "a [REDACTED] line" {
+		t.Errorf("got %q, %v", b.String(), err)
+	}
+}
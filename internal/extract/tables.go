@@ -0,0 +1,244 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Table is a table as rows of cell texts. Rows may have different lengths
+// when cells are merged or missing.
+type Table [][]string
+
+// ExtractTables returns the tables of a DOCX, XLSX or HTML file in document
+// order. Each XLSX worksheet with data is one table; a table nested in a cell
+// is returned as a table of its own and is left out of the enclosing cell.
+func ExtractTables(filename string, data []byte) ([]Table, error) {
+	return ExtractTablesWithOptions(filename, data, DefaultOptions())
+}
+
+// ExtractTablesWithOptions is like ExtractTables but passes the text of every
+// cell through Options.TextHook, so that tables are redacted like the text,
+// and fails with ErrFormatDisabled for a format left out of
+// Options.EnabledFormats.
+func ExtractTablesWithOptions(filename string, data []byte, opts Options) ([]Table, error) {
+	tables, err := extractTables(filename, data, opts)
+	if err != nil || opts.TextHook == nil {
+		return tables, err
+	}
+	for _, table := range tables {
+		for _, row := range table {
+			for i, cell := range row {
+				if row[i], err = opts.TextHook(cell); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return tables, nil
+}
+
+func extractTables(filename string, data []byte, opts Options) ([]Table, error) {
+	format := ooxmlFormat(fileFormat(filename), data)
+	switch format {
+	case "html":
+		if err := opts.checkFormat("html"); err != nil {
+			return nil, err
+		}
+		return htmlTables(bytes.NewReader(data))
+	case "docx", "xlsx":
+	default:
+		if !bytes.HasPrefix(data, []byte("PK")) {
+			if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+				if err := opts.checkFormat("html"); err != nil {
+					return nil, err
+				}
+				return htmlTables(bytes.NewReader(data))
+			}
+			return nil, errors.New("tables are supported for docx, xlsx and html only")
+		}
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := zipIndex(zr)
+	switch {
+	case format == "docx" || (format != "xlsx" && files["word/document.xml"] != nil):
+		if err := opts.checkFormat("docx"); err != nil {
+			return nil, err
+		}
+		f, ok := files["word/document.xml"]
+		if !ok {
+			return nil, errors.New("document.xml not found in docx")
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return docxTables(rc)
+	case format == "xlsx" || files["xl/workbook.xml"] != nil:
+		if err := opts.checkFormat("xlsx"); err != nil {
+			return nil, err
+		}
+		return xlsxTables(files)
+	}
+	return nil, errors.New("tables are supported for docx, xlsx and html only")
+}
+
+// docxTables collects w:tbl elements of a document part. Paragraphs within a
+// cell are joined with newlines; deleted revisions are skipped.
+func docxTables(r io.Reader) ([]Table, error) {
+	type openTable struct {
+		index int // position in tables
+		cell  *strings.Builder
+		paras int // paragraphs seen in the current cell
+	}
+	var tables []Table
+	var open []*openTable
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var top *openTable
+		if len(open) > 0 {
+			top = open[len(open)-1]
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tbl":
+				tables = append(tables, Table{})
+				open = append(open, &openTable{index: len(tables) - 1})
+			case "tr":
+				if top != nil {
+					tables[top.index] = append(tables[top.index], []string{})
+				}
+			case "tc":
+				if top != nil {
+					top.cell, top.paras = &strings.Builder{}, 0
+				}
+			case "p":
+				if top != nil && top.cell != nil {
+					if top.paras > 0 {
+						top.cell.WriteByte('\n')
+					}
+					top.paras++
+				}
+			case "tab":
+				if top != nil && top.cell != nil {
+					top.cell.WriteByte('\t')
+				}
+			case "br", "cr":
+				if top != nil && top.cell != nil {
+					top.cell.WriteByte('\n')
+				}
+			case "t":
+				var s string
+				if err := dec.DecodeElement(&s, &t); err != nil {
+					return nil, err
+				}
+				if top != nil && top.cell != nil {
+					top.cell.WriteString(s)
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "tbl":
+				if top != nil {
+					open = open[:len(open)-1]
+				}
+			case "tc":
+				if top != nil && top.cell != nil {
+					tbl := tables[top.index]
+					if len(tbl) == 0 {
+						tbl = append(tbl, []string{})
+					}
+					last := len(tbl) - 1
+					tbl[last] = append(tbl[last], top.cell.String())
+					tables[top.index] = tbl
+					top.cell = nil
+				}
+			}
+		}
+	}
+	return tables, nil
+}
+
+// xlsxTables returns each non-empty worksheet as a table.
+func xlsxTables(files map[string]*zip.File) ([]Table, error) {
+	sheets, err := xlsxSheets(files)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := xlsxSharedStrings(files)
+	if err != nil {
+		return nil, err
+	}
+	var tables []Table
+	for _, sheet := range sheets {
+		tbl, err := xlsxSheetTable(files, sheet.Part, shared)
+		if err != nil {
+			return nil, err
+		}
+		if len(tbl) > 0 {
+			tables = append(tables, tbl)
+		}
+	}
+	return tables, nil
+}
+
+// htmlTables collects the table elements of an HTML document. Rows of
+// thead, tbody and tfoot are read in source order.
+func htmlTables(r io.Reader) ([]Table, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	var tables []Table
+	var collect func(n *html.Node)
+	// rows appends the rows of table t found under n, not descending into
+	// nested tables
+	var rows func(n *html.Node, t int)
+	rows = func(n *html.Node, t int) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "thead", "tbody", "tfoot":
+				rows(c, t)
+			case "tr":
+				row := []string{}
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						row = append(row, htmlNodeText(cell, true))
+					}
+				}
+				tables[t] = append(tables[t], row)
+			}
+		}
+	}
+	collect = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "table" {
+			tables = append(tables, Table{})
+			rows(n, len(tables)-1)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(doc)
+	return tables, nil
+}
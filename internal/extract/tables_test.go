@@ -0,0 +1,66 @@
+package extract
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractTablesWithOptionsTextHook(t *testing.T) {
+	data := []byte(`<table><tr><td>card</td><td>4111 1111 1111 1111</td></tr></table>`)
+	opts := DefaultOptions()
+	opts.TextHook = func(s string) (string, error) { return strings.ReplaceAll(s, "1111", "####"), nil }
+	tables, err := ExtractTablesWithOptions("t.html", data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Table{{{"card", "4111 #### #### ####"}}}
+	if !reflect.DeepEqual(tables, want) {
+		t.Errorf("tables = %q, want %q", tables, want)
+	}
+}
+
+func TestExtractTables(t *testing.T) {
+	cell := func(paras ...string) string {
+		s := `<w:tc>`
+		for _, p := range paras {
+			s += `<w:p><w:r><w:t>` + p + `</w:t></w:r></w:p>`
+		}
+		return s + `</w:tc>`
+	}
+	nested := `<w:tc><w:tbl><w:tr>` + cell("inner") + `</w:tr></w:tbl><w:p><w:r><w:t>outer</w:t></w:r></w:p></w:tc>`
+	docx := testDOCX(t, `<w:p><w:r><w:t>intro</w:t></w:r></w:p>`+
+		`<w:tbl><w:tr>`+cell("Name")+cell("Qty")+`</w:tr><w:tr>`+cell("apple", "green")+cell("3")+`</w:tr><w:tr>`+nested+`</w:tr></w:tbl>`+
+		`<w:tbl><w:tr>`+cell("kept")+`<w:tc><w:p><w:del><w:r><w:delText>gone</w:delText></w:r></w:del></w:p></w:tc></w:tr></w:tbl>`)
+	html := `<p>intro</p><table><thead><tr><th>Name</th><th>Qty</th></tr></thead>` +
+		`<tbody><tr><td>apple</td><td>3</td></tr><tr><td colspan="2">total</td></tr></tbody></table>` +
+		`<table><tr><td>second</td></tr></table>`
+	xlsx := testXLSX(t, []string{
+		`<row r="1"><c r="A1" t="inlineStr"><is><t>a</t></is></c><c r="B1"><v>1</v></c></row>`,
+		``,
+	})
+	for _, tc := range []struct {
+		filename string
+		data     []byte
+		want     []Table
+	}{
+		{"a.docx", docx, []Table{
+			{{"Name", "Qty"}, {"apple\ngreen", "3"}, {"outer"}},
+			{{"inner"}},
+			{{"kept", ""}},
+		}},
+		{"a.html", []byte(html), []Table{
+			{{"Name", "Qty"}, {"apple", "3"}, {"total"}},
+			{{"second"}},
+		}},
+		{"a.xlsx", xlsx, []Table{{{"a", "1"}}}},
+	} {
+		got, err := ExtractTables(tc.filename, tc.data)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %q, %v; want %q", tc.filename, got, err, tc.want)
+		}
+	}
+	if _, err := ExtractTables("a.pdf", []byte("%PDF-1.4")); err == nil {
+		t.Error("tables of a pdf gave no error")
+	}
+}
@@ -0,0 +1,171 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// testZip builds a zip archive from name and content pairs.
+func testZip(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for i := 0; i+1 < len(files); i += 2 {
+		w, err := zw.Create(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(files[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+const testWordNS = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+
+// testDOCX builds a DOCX package whose body is body, followed by extra name
+// and content pairs for other parts.
+func testDOCX(t *testing.T, body string, parts ...string) []byte {
+	t.Helper()
+	files := append([]string{
+		"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/></Types>`,
+		"word/document.xml", `<?xml version="1.0" encoding="UTF-8"?><w:document ` + testWordNS + `><w:body>` + body + `</w:body></w:document>`,
+	}, parts...)
+	return testZip(t, files...)
+}
+
+const testRelsNS = `xmlns="http://schemas.openxmlformats.org/package/2006/relationships"`
+
+// testXLSX builds a workbook with a sheet named SheetN for each sheetData
+// body, followed by extra name and content pairs for other parts.
+func testXLSX(t *testing.T, sheets []string, parts ...string) []byte {
+	t.Helper()
+	var list, rels string
+	var files []string
+	for i, data := range sheets {
+		n := strconv.Itoa(i + 1)
+		list += `<sheet name="Sheet` + n + `" sheetId="` + n + `" r:id="rId` + n + `"/>`
+		rels += `<Relationship Id="rId` + n + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet` + n + `.xml"/>`
+		files = append(files, "xl/worksheets/sheet"+n+".xml", `<?xml version="1.0" encoding="UTF-8"?><worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+data+`</sheetData></worksheet>`)
+	}
+	files = append([]string{
+		"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8"?><workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + list + `</sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8"?><Relationships ` + testRelsNS + `>` + rels + `</Relationships>`,
+	}, files...)
+	return testZip(t, append(files, parts...)...)
+}
+
+// testODT builds an ODF text document whose office:text holds body.
+func testODT(t *testing.T, body string) []byte {
+	t.Helper()
+	return testZip(t, "mimetype", "application/vnd.oasis.opendocument.text",
+		"content.xml", `<?xml version="1.0" encoding="UTF-8"?><office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"><office:body><office:text>`+body+`</office:text></office:body></office:document-content>`)
+}
+
+// testCFB builds a version 3 compound file holding the given name and
+// content pairs as streams of the root storage. The mini stream cutoff is
+// zero, so every stream lives in regular sectors.
+func testCFB(t *testing.T, streams ...string) []byte {
+	t.Helper()
+	const sector = 512
+	le := binary.LittleEndian
+	n := len(streams) / 2
+	if n > 3 {
+		t.Fatal("testCFB: at most three streams fit the directory sector")
+	}
+	// sector 0 is the FAT, sector 1 the directory, then the streams
+	fat := []uint32{0xFFFFFFFD, cfbEndOfChain}
+	dir := make([]byte, sector)
+	entry := func(i int, name string, typ byte, start uint32, size uint64) {
+		e := dir[128*i : 128*(i+1)]
+		u := utf16.Encode([]rune(name))
+		for j, v := range u {
+			le.PutUint16(e[2*j:], v)
+		}
+		le.PutUint16(e[64:], uint16(2*len(u)+2))
+		e[66] = typ
+		for _, off := range []int{68, 72, 76} {
+			le.PutUint32(e[off:], cfbFreeSect)
+		}
+		le.PutUint32(e[116:], start)
+		le.PutUint64(e[120:], size)
+	}
+	entry(0, "Root Entry", 5, cfbEndOfChain, 0)
+	var body []byte
+	for i := 0; i < n; i++ {
+		data := []byte(streams[2*i+1])
+		start := uint32(len(fat))
+		count := max((len(data)+sector-1)/sector, 1)
+		for j := 0; j < count; j++ {
+			if j == count-1 {
+				fat = append(fat, cfbEndOfChain)
+			} else {
+				fat = append(fat, uint32(len(fat)+1))
+			}
+		}
+		entry(i+1, streams[2*i], 2, start, uint64(len(data)))
+		padded := make([]byte, count*sector)
+		copy(padded, data)
+		body = append(body, padded...)
+	}
+	if len(fat) > sector/4 {
+		t.Fatal("testCFB: streams too large for one FAT sector")
+	}
+	fatSector := make([]byte, sector)
+	for i := range sector / 4 {
+		v := uint32(cfbFreeSect)
+		if i < len(fat) {
+			v = fat[i]
+		}
+		le.PutUint32(fatSector[4*i:], v)
+	}
+
+	hdr := make([]byte, sector)
+	copy(hdr, cfbMagic)
+	le.PutUint16(hdr[0x18:], 0x3E)
+	le.PutUint16(hdr[0x1A:], 3)
+	le.PutUint16(hdr[0x1C:], 0xFFFE)
+	le.PutUint16(hdr[0x1E:], 9)
+	le.PutUint16(hdr[0x20:], 6)
+	le.PutUint32(hdr[0x2C:], 1)
+	le.PutUint32(hdr[0x30:], 1)
+	le.PutUint32(hdr[0x3C:], cfbEndOfChain)
+	le.PutUint32(hdr[0x44:], cfbEndOfChain)
+	for i := 0; i < 109; i++ {
+		le.PutUint32(hdr[0x4C+4*i:], cfbFreeSect)
+	}
+	le.PutUint32(hdr[0x4C:], 0)
+
+	out := append(hdr, fatSector...)
+	out = append(out, dir...)
+	return append(out, body...)
+}
+
+// testTextPDF builds a PDF with one page per content stream. The streams
+// draw with F1, a Helvetica font without a ToUnicode map.
+func testTextPDF(pages ...string) []byte {
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n1 0 obj\n<</Type/Catalog/Pages 2 0 R>>\nendobj\n")
+	var kids []string
+	for i := range pages {
+		kids = append(kids, strconv.Itoa(4+2*i)+" 0 R")
+	}
+	b.WriteString("2 0 obj\n<</Type/Pages/Kids [" + strings.Join(kids, " ") + "]/Count " + strconv.Itoa(len(pages)) + ">>\nendobj\n")
+	b.WriteString("3 0 obj\n<</Type/Font/Subtype/Type1/BaseFont/Helvetica>>\nendobj\n")
+	for i, content := range pages {
+		page, stream := strconv.Itoa(4+2*i), strconv.Itoa(5+2*i)
+		b.WriteString(page + " 0 obj\n<</Type/Page/Parent 2 0 R/MediaBox [0 0 612 792]/Resources <</Font <</F1 3 0 R>>>>/Contents " + stream + " 0 R>>\nendobj\n")
+		b.WriteString(stream + " 0 obj\n<</Length " + strconv.Itoa(len(content)) + ">>\nstream\n" + content + "\nendstream\nendobj\n")
+	}
+	b.WriteString("trailer\n<</Root 1 0 R/Size " + strconv.Itoa(4+2*len(pages)) + ">>\nstartxref\n0\n%%EOF\n")
+	return []byte(b.String())
+}
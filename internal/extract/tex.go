@@ -0,0 +1,301 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+// texMathEnvs are environments whose content is math, dropped unless it is kept
+// as source.
+var texMathEnvs = map[string]bool{
+	"equation": true, "equation*": true,
+	"align": true, "align*": true,
+	"gather": true, "gather*": true,
+	"multline": true, "multline*": true,
+	"eqnarray": true, "eqnarray*": true,
+	"flalign": true, "flalign*": true,
+	"displaymath": true, "math": true,
+}
+
+// texDropCommands are commands removed together with all their arguments.
+var texDropCommands = map[string]bool{
+	"documentclass": true, "usepackage": true, "label": true, "ref": true,
+	"eqref": true, "pageref": true, "cite": true, "citep": true, "citet": true,
+	"includegraphics": true, "bibliographystyle": true, "bibliography": true,
+	"vspace": true, "hspace": true, "newcommand": true, "renewcommand": true,
+	"setlength": true, "addtolength": true, "input": true, "include": true,
+	"pagestyle": true, "thispagestyle": true, "setcounter": true,
+}
+
+// texBlockCommands are sectioning commands whose argument becomes a line of its own.
+var texBlockCommands = map[string]bool{
+	"part": true, "chapter": true, "section": true, "subsection": true,
+	"subsubsection": true, "paragraph": true, "subparagraph": true,
+	"title": true, "author": true, "caption": true,
+}
+
+var reTeXParagraphs = regexp.MustCompile(`\n[ \t]*\n\s*`)
+
+// extractTeX converts LaTeX source to prose. It is a heuristic pass, not a TeX engine:
+// comments and preamble are dropped, sectioning commands become lines and
+// other commands are replaced by the content of their arguments. Math is
+// dropped too, or copied as source, delimiters included, when keepMath is set;
+// display math then becomes a paragraph of its own.
+func extractTeX(data []byte, keepMath bool) (string, error) {
+	src := string(data)
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	src = stripTeXComments(src)
+	if i := strings.Index(src, `\begin{document}`); i >= 0 {
+		src = src[i+len(`\begin{document}`):]
+	}
+	if i := strings.Index(src, `\end{document}`); i >= 0 {
+		src = src[:i]
+	}
+
+	p := texParser{src: src, keepMath: keepMath}
+	p.run(len(src))
+
+	// blank lines separate paragraphs; single newlines inside a paragraph are spaces
+	var paras []string
+	for _, para := range reTeXParagraphs.Split(p.b.String(), -1) {
+		para = strings.Join(strings.Fields(strings.ReplaceAll(para, "\n", " ")), " ")
+		if para != "" {
+			paras = append(paras, para)
+		}
+	}
+	return strings.Join(paras, "\n"), nil
+}
+
+// stripTeXComments removes everything from an unescaped % to the end of the line.
+func stripTeXComments(s string) string {
+	lines := strings.Split(s, "\n")
+	for n, line := range lines {
+		for i := 0; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+				continue
+			}
+			if line[i] == '%' {
+				lines[n] = line[:i]
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+type texParser struct {
+	src      string
+	i        int
+	b        strings.Builder
+	keepMath bool
+}
+
+// math copies the math skipped since start when keepMath is set; display math
+// is set apart as a paragraph.
+func (p *texParser) math(start int, display bool) {
+	if !p.keepMath {
+		return
+	}
+	if display {
+		p.b.WriteString("\n\n" + p.src[start:p.i] + "\n\n")
+		return
+	}
+	p.b.WriteString(p.src[start:p.i])
+}
+
+// run converts source up to position end.
+func (p *texParser) run(end int) {
+	for p.i < end {
+		c := p.src[p.i]
+		switch c {
+		case '\\':
+			p.command()
+		case '{', '}':
+			p.i++
+		case '~':
+			p.b.WriteByte(' ')
+			p.i++
+		case '&':
+			p.b.WriteByte('\t')
+			p.i++
+		case '$':
+			p.skipDollarMath()
+		default:
+			p.b.WriteByte(c)
+			p.i++
+		}
+	}
+}
+
+func (p *texParser) command() {
+	start := p.i
+	p.i++ // backslash
+	if p.i >= len(p.src) {
+		return
+	}
+	if !isASCIILetter(p.src[p.i]) {
+		sym := p.src[p.i]
+		p.i++
+		switch sym {
+		case '\\':
+			p.b.WriteString("\n\n")
+		case '[':
+			p.skipPast(`\]`)
+			p.math(start, true)
+		case '(':
+			p.skipPast(`\)`)
+			p.math(start, false)
+		case ',', ' ', ';':
+			p.b.WriteByte(' ')
+		default:
+			p.b.WriteByte(sym)
+		}
+		return
+	}
+	for p.i < len(p.src) && isASCIILetter(p.src[p.i]) {
+		p.i++
+	}
+	name := p.src[start+1 : p.i]
+	if p.i < len(p.src) && p.src[p.i] == '*' {
+		p.i++
+	}
+
+	switch {
+	case name == "begin":
+		env := p.readGroup()
+		if texMathEnvs[env] {
+			p.skipPast(`\end{` + env + `}`)
+			p.math(start, true)
+			return
+		}
+		p.b.WriteString("\n\n")
+	case name == "end":
+		p.readGroup()
+		p.b.WriteString("\n\n")
+	case name == "item":
+		p.skipOptional()
+		p.b.WriteString("\n\n- ")
+	case name == "par":
+		p.b.WriteString("\n\n")
+	case name == "verb":
+		if p.i < len(p.src) {
+			delim := p.src[p.i]
+			p.i++
+			if j := strings.IndexByte(p.src[p.i:], delim); j >= 0 {
+				p.b.WriteString(p.src[p.i : p.i+j])
+				p.i += j + 1
+			}
+		}
+	case texDropCommands[name]:
+		for p.skipOptional() || p.skipGroup() {
+		}
+	case texBlockCommands[name]:
+		p.skipOptional()
+		p.b.WriteString("\n\n")
+		if end, ok := p.groupEnd(); ok {
+			p.i++
+			p.run(end)
+			p.i = end + 1
+		}
+		p.b.WriteString("\n\n")
+	default:
+		// formatting commands such as \textbf{...} keep their content: the braces
+		// are dropped by run, so only optional arguments need skipping here
+		p.skipOptional()
+		if p.i < len(p.src) && p.src[p.i] == ' ' {
+			// a control word swallows a single following space
+			p.i++
+		}
+	}
+}
+
+// groupEnd returns the index of the brace closing the group that starts at p.i.
+func (p *texParser) groupEnd() (int, bool) {
+	j := p.i
+	if j >= len(p.src) || p.src[j] != '{' {
+		return 0, false
+	}
+	depth := 0
+	for ; j < len(p.src); j++ {
+		switch p.src[j] {
+		case '\\':
+			j++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return j, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// readGroup consumes a {...} group and returns its raw content.
+func (p *texParser) readGroup() string {
+	end, ok := p.groupEnd()
+	if !ok {
+		return ""
+	}
+	s := p.src[p.i+1 : end]
+	p.i = end + 1
+	return s
+}
+
+func (p *texParser) skipGroup() bool {
+	if end, ok := p.groupEnd(); ok {
+		p.i = end + 1
+		return true
+	}
+	return false
+}
+
+// skipOptional consumes a [...] argument if one follows.
+func (p *texParser) skipOptional() bool {
+	if p.i >= len(p.src) || p.src[p.i] != '[' {
+		return false
+	}
+	if j := strings.IndexByte(p.src[p.i:], ']'); j >= 0 {
+		p.i += j + 1
+		return true
+	}
+	return false
+}
+
+func (p *texParser) skipPast(marker string) {
+	if j := strings.Index(p.src[p.i:], marker); j >= 0 {
+		p.i += j + len(marker)
+		return
+	}
+	p.i = len(p.src)
+}
+
+func (p *texParser) skipDollarMath() {
+	start := p.i
+	if strings.HasPrefix(p.src[p.i:], "$$") {
+		p.i += 2
+		p.skipPast("$$")
+		p.math(start, true)
+		return
+	}
+	p.i++
+	for p.i < len(p.src) {
+		switch p.src[p.i] {
+		case '\\':
+			p.i += 2
+			continue
+		case '$':
+			p.i++
+			p.math(start, false)
+			return
+		}
+		p.i++
+	}
+	p.i = min(p.i, len(p.src))
+	p.math(start, false)
+}
+
+func isASCIILetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
@@ -0,0 +1,30 @@
+package extract
+
+import "testing"
+
+func TestTeXMath(t *testing.T) {
+	src := `\documentclass{article}
+\begin{document}
+\section{Energy}
+Mass $m$ and energy are related: % comment
+\begin{equation}
+E = mc^2
+\end{equation}
+Also \(a+b\) and \[x^2\] and $$y$$ end.
+\end{document}
+`
+	for _, tc := range []struct {
+		keep bool
+		want string
+	}{
+		{false, "Energy\nMass and energy are related:\nAlso and and end."},
+		{true, "Energy\nMass $m$ and energy are related:\n\\begin{equation} E = mc^2 \\end{equation}\nAlso \\(a+b\\) and\n\\[x^2\\]\nand\n$$y$$\nend."},
+	} {
+		opts := DefaultOptions()
+		opts.KeepTeXMath = tc.keep
+		got, err := ExtractTextWithOptions("paper.tex", []byte(src), opts)
+		if err != nil || got != tc.want {
+			t.Errorf("KeepTeXMath %v: got %q, %v; want %q", tc.keep, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package extract
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Phase names used in Result.Timings. Phases that do not apply to a document
+// are absent; durations of repeated phases, such as several tool runs, add up.
+const (
+	PhaseDetect     = "detect"     // choosing the extractor from the name or content
+	PhaseDecompress = "decompress" // gzip decompression
+	PhaseParse      = "parse"      // running the extractor, including subprocesses
+	PhaseSubprocess = "subprocess" // time spent in external tools such as pdftotext
+	PhaseNormalize  = "normalize"  // post-processing options
+	PhaseTotal      = "total"
+)
+
+// timings collects phase durations of one extraction. It travels in the
+// context, so extractors need no extra parameter; a context without timings
+// records nothing.
+type timings struct {
+	mu sync.Mutex
+	m  map[string]time.Duration
+}
+
+type timingsKey struct{}
+
+func withTimings(ctx context.Context, t *timings) context.Context {
+	return context.WithValue(ctx, timingsKey{}, t)
+}
+
+// phaseTimer starts timing phase and returns the function that stops it.
+func phaseTimer(ctx context.Context, phase string) func() {
+	t, _ := ctx.Value(timingsKey{}).(*timings)
+	if t == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		t.mu.Lock()
+		t.m[phase] += time.Since(start)
+		t.mu.Unlock()
+	}
+}
+
+// snapshot copies the durations recorded so far; an abandoned extraction may
+// still be adding to them.
+func (t *timings) snapshot() map[string]time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m := make(map[string]time.Duration, len(t.m))
+	for k, v := range t.m {
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,105 @@
+package extract
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// WordCount is a word and the number of times it occurs.
+type WordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// DefaultStopwords are common Russian and English function words left out of
+// WordFrequencies.
+var DefaultStopwords = []string{
+	// English
+	"a", "about", "after", "all", "also", "an", "and", "any", "are", "as", "at",
+	"be", "been", "but", "by", "can", "could", "did", "do", "does", "for", "from",
+	"had", "has", "have", "he", "her", "his", "how", "i", "if", "in", "into", "is",
+	"it", "its", "may", "more", "my", "no", "not", "of", "on", "or", "our", "she",
+	"so", "than", "that", "the", "their", "them", "then", "there", "these", "they",
+	"this", "those", "to", "up", "was", "we", "were", "what", "when", "which", "who",
+	"will", "with", "would", "you", "your",
+	// Russian
+	"а", "без", "более", "бы", "был", "была", "были", "было", "быть", "в", "вам",
+	"вас", "весь", "во", "вот", "все", "всё", "всех", "вы", "где", "да", "даже",
+	"для", "до", "его", "ее", "её", "если", "есть", "еще", "ещё", "же", "за", "и",
+	"из", "или", "им", "их", "к", "как", "когда", "кто", "ли", "либо", "мне", "может",
+	"мы", "на", "над", "не", "нет", "ни", "них", "но", "ну", "о", "об", "он", "она",
+	"они", "оно", "от", "по", "под", "при", "с", "со", "так", "также", "такой", "там",
+	"те", "тем", "то", "того", "тоже", "только", "том", "ты", "у", "уже", "чем", "что",
+	"чтобы", "эта", "эти", "это", "этот", "я",
+}
+
+// WordFrequencies returns the topN most frequent words of text, skipping
+// DefaultStopwords. topN <= 0 returns all words.
+func WordFrequencies(text string, topN int) []WordCount {
+	return WordFrequenciesWithStopwords(text, topN, DefaultStopwords)
+}
+
+// WordFrequenciesWithStopwords is like WordFrequencies with a custom stopword
+// list. Words are runs of letters and digits, with inner hyphens and
+// apostrophes kept ("кто-то", "don't"), compared in lower case; tokens without
+// letters, such as numbers, are not counted. Ties are ordered alphabetically.
+func WordFrequenciesWithStopwords(text string, topN int, stopwords []string) []WordCount {
+	stop := make(map[string]bool, len(stopwords))
+	for _, w := range stopwords {
+		stop[strings.ToLower(w)] = true
+	}
+	counts := make(map[string]int)
+	for _, w := range splitWords(text) {
+		if w = strings.ToLower(w); !stop[w] {
+			counts[w]++
+		}
+	}
+
+	words := make([]WordCount, 0, len(counts))
+	for w, n := range counts {
+		words = append(words, WordCount{Word: w, Count: n})
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if words[i].Count != words[j].Count {
+			return words[i].Count > words[j].Count
+		}
+		return words[i].Word < words[j].Word
+	})
+	if topN > 0 && len(words) > topN {
+		words = words[:topN]
+	}
+	return words
+}
+
+// splitWords tokenizes text into words containing at least one letter.
+func splitWords(text string) []string {
+	var words []string
+	runes := []rune(text)
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) }
+	for i := 0; i < len(runes); {
+		if !isWord(runes[i]) {
+			i++
+			continue
+		}
+		start, letters := i, false
+		for i < len(runes) {
+			r := runes[i]
+			if isWord(r) {
+				letters = letters || unicode.IsLetter(r)
+				i++
+				continue
+			}
+			// a joiner counts only between two word characters
+			if (r == '-' || r == '\'' || r == '’') && i+1 < len(runes) && isWord(runes[i+1]) {
+				i++
+				continue
+			}
+			break
+		}
+		if letters {
+			words = append(words, string(runes[start:i]))
+		}
+	}
+	return words
+}
@@ -0,0 +1,32 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWordFrequencies(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		text string
+		topN int
+		want []WordCount
+	}{
+		{"english", "The cat and the dog. The CAT sat; a dog's bowl, the cat!", 0,
+			[]WordCount{{"cat", 3}, {"bowl", 1}, {"dog", 1}, {"dog's", 1}, {"sat", 1}}},
+		{"russian", "Кот и пёс. Кот сидит, а ПЁС спит — кот-то не спит!", 2,
+			[]WordCount{{"кот", 2}, {"пёс", 2}}},
+		{"numbers are not words", "2024 год, 2024 год; v2 2", 0,
+			[]WordCount{{"год", 2}, {"v2", 1}}},
+		{"only stopwords", "и в на the of", 0, []WordCount{}},
+	} {
+		if got := WordFrequencies(tc.text, tc.topN); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	got := WordFrequenciesWithStopwords("The cat and THE dog", 0, []string{"Cat"})
+	want := []WordCount{{"the", 2}, {"and", 1}, {"dog", 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("custom stopwords: got %v, want %v", got, want)
+	}
+}
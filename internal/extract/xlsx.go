@@ -0,0 +1,215 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// xlsxSheet is a worksheet listed in the workbook, in workbook order.
+type xlsxSheet struct {
+	Name string
+	Part string // archive path of the worksheet part
+}
+
+// xlsxSheets lists the workbook's worksheets with their resolved part paths.
+func xlsxSheets(files map[string]*zip.File) ([]xlsxSheet, error) {
+	const wbPart = "xl/workbook.xml"
+	f, ok := files[wbPart]
+	if !ok {
+		return nil, errors.New("workbook.xml not found in xlsx")
+	}
+	raw, err := readZipFile(f)
+	if err != nil {
+		return nil, err
+	}
+	var wb struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(raw, &wb); err != nil {
+		return nil, err
+	}
+	rels := readRels(files, wbPart)
+	var sheets []xlsxSheet
+	for _, s := range wb.Sheets {
+		rel, ok := rels[s.RID]
+		if !ok {
+			continue
+		}
+		sheets = append(sheets, xlsxSheet{Name: s.Name, Part: relTarget(wbPart, rel.Target)})
+	}
+	return sheets, nil
+}
+
+// xlsxWorksheet is the cell data of a worksheet part.
+type xlsxWorksheet struct {
+	Rows []struct {
+		R     int        `xml:"r,attr"`
+		Cells []xlsxCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+type xlsxCell struct {
+	Ref    string       `xml:"r,attr"`
+	Type   string       `xml:"t,attr"`
+	V      string       `xml:"v"`
+	Inline xlsxRichText `xml:"is"`
+}
+
+// value returns the cell as displayed text, without number formatting.
+func (c xlsxCell) value(shared []string) string {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(c.V))
+		if err != nil || i < 0 || i >= len(shared) {
+			return ""
+		}
+		return shared[i]
+	case "inlineStr":
+		return c.Inline.T + strings.Join(c.Inline.Runs, "")
+	case "b":
+		if strings.TrimSpace(c.V) == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return c.V
+}
+
+// xlsxSharedStrings reads the shared string table; a workbook without one has
+// no shared strings.
+func xlsxSharedStrings(files map[string]*zip.File) ([]string, error) {
+	if files["xl/sharedStrings.xml"] == nil {
+		return nil, nil
+	}
+	var sst struct {
+		Items []xlsxRichText `xml:"si"`
+	}
+	if err := unmarshalZipXML(files, "xl/sharedStrings.xml", &sst); err != nil {
+		return nil, err
+	}
+	shared := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		shared[i] = si.T + strings.Join(si.Runs, "")
+	}
+	return shared, nil
+}
+
+// xlsxCellRef splits an A1-style reference into 0-based column and row.
+func xlsxCellRef(ref string) (col, row int, ok bool) {
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 || i == len(ref) {
+		return 0, 0, false
+	}
+	row, err := strconv.Atoi(ref[i:])
+	if err != nil || row < 1 {
+		return 0, 0, false
+	}
+	return col - 1, row - 1, true
+}
+
+// xlsxSheetTable reads the cells of a worksheet part, placing them by their
+// reference so that skipped cells and rows come out empty.
+func xlsxSheetTable(files map[string]*zip.File, part string, shared []string) (Table, error) {
+	var ws xlsxWorksheet
+	if err := unmarshalZipXML(files, part, &ws); err != nil {
+		return nil, err
+	}
+	var tbl Table
+	next := 0 // row index used when a row has no reference
+	for _, row := range ws.Rows {
+		ri := next
+		if row.R > 0 {
+			ri = row.R - 1
+		}
+		next = ri + 1
+		var cells []string
+		for _, c := range row.Cells {
+			ci := len(cells)
+			if col, _, ok := xlsxCellRef(c.Ref); ok {
+				ci = col
+			}
+			v := c.value(shared)
+			if v == "" {
+				continue
+			}
+			for len(cells) <= ci {
+				cells = append(cells, "")
+			}
+			cells[ci] = v
+		}
+		if len(cells) == 0 {
+			continue
+		}
+		for len(tbl) <= ri {
+			tbl = append(tbl, []string{})
+		}
+		tbl[ri] = cells
+	}
+	return tbl, nil
+}
+
+// extractXLSX renders each worksheet under its name as tab-separated rows,
+// sheets separated by a blank line. Empty rows are skipped. Cell values are
+// shown unformatted, as stored.
+func extractXLSX(data []byte, opts Options) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	files := zipIndex(zr)
+	sheets, err := xlsxSheets(files)
+	if err != nil {
+		return "", err
+	}
+	shared, err := xlsxSharedStrings(files)
+	if err != nil {
+		return "", err
+	}
+	if opts.FirstUnitOnly && len(sheets) > 1 {
+		sheets = sheets[:1]
+	}
+	var out []string
+	for _, sheet := range sheets {
+		tbl, err := xlsxSheetTable(files, sheet.Part, shared)
+		if err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		b.WriteString(sheet.Name)
+		for _, row := range tbl {
+			if len(row) > 0 {
+				b.WriteByte('\n')
+				b.WriteString(strings.Join(row, "\t"))
+			}
+		}
+		out = append(out, b.String())
+	}
+	text := strings.Join(out, "\n\n") + "\n"
+	if opts.IncludeComments {
+		comments, err := xlsxComments(files)
+		if err != nil {
+			return "", err
+		}
+		var shown []Comment
+		for _, c := range comments {
+			for _, sheet := range sheets {
+				if strings.HasPrefix(c.Location, sheet.Name+"!") {
+					shown = append(shown, c)
+					break
+				}
+			}
+		}
+		text += formatComments(shown)
+	}
+	return text, nil
+}
@@ -0,0 +1,147 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+var zipLocalHeaderSig = []byte("PK\x03\x04")
+
+// scanZipEntry finds the local file header of name in a zip archive without using
+// the central directory and returns the entry's uncompressed content, failing
+// with ErrDecompressionLimit when it expands beyond limit bytes.
+func scanZipEntry(data []byte, name string, limit int64) ([]byte, error) {
+	for off := 0; ; {
+		i := bytes.Index(data[off:], zipLocalHeaderSig)
+		if i < 0 {
+			return nil, errors.New(name + " not found in archive")
+		}
+		h := data[off+i:]
+		off += i + len(zipLocalHeaderSig)
+		if len(h) < 30 {
+			continue
+		}
+		method := binary.LittleEndian.Uint16(h[8:])
+		compSize := int(binary.LittleEndian.Uint32(h[18:]))
+		nameLen := int(binary.LittleEndian.Uint16(h[26:]))
+		extraLen := int(binary.LittleEndian.Uint16(h[28:]))
+		if len(h) < 30+nameLen+extraLen || string(h[30:30+nameLen]) != name {
+			continue
+		}
+		body := h[30+nameLen+extraLen:]
+		switch method {
+		case 0: // stored
+			if compSize == 0 || compSize > len(body) {
+				return nil, errors.New(name + ": stored entry has no usable size")
+			}
+			return body[:compSize], nil
+		case 8: // deflate; the stream is self-terminating, so sizes may be missing
+			out, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(body)), limit+1))
+			if err != nil {
+				return nil, err
+			}
+			if int64(len(out)) > limit {
+				return nil, ErrDecompressionLimit
+			}
+			return out, nil
+		default:
+			return nil, errors.New(name + ": unsupported compression method")
+		}
+	}
+}
+
+// zipIndex maps archive entry names to their files.
+func zipIndex(zr *zip.Reader) map[string]*zip.File {
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	return files
+}
+
+// readZipFile returns the uncompressed content of an archive entry.
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// opcRel is a relationship from an OPC (Office Open XML) .rels part.
+type opcRel struct {
+	ID         string `xml:"Id,attr"`
+	Type       string `xml:"Type,attr"`
+	Target     string `xml:"Target,attr"`
+	TargetMode string `xml:"TargetMode,attr"`
+}
+
+// readRels returns the relationships of part keyed by id; missing or malformed
+// relationship parts yield an empty map.
+func readRels(files map[string]*zip.File, part string) map[string]opcRel {
+	rels := make(map[string]opcRel)
+	f, ok := files[path.Join(path.Dir(part), "_rels", path.Base(part)+".rels")]
+	if !ok {
+		return rels
+	}
+	raw, err := readZipFile(f)
+	if err != nil {
+		return rels
+	}
+	var doc struct {
+		Rels []opcRel `xml:"Relationship"`
+	}
+	if xml.Unmarshal(raw, &doc) != nil {
+		return rels
+	}
+	for _, r := range doc.Rels {
+		rels[r.ID] = r
+	}
+	return rels
+}
+
+// relTarget resolves a relationship target against the part that declares it.
+func relTarget(part, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(target, "/")
+	}
+	return path.Join(path.Dir(part), target)
+}
+
+// unmarshalZipXML decodes the XML archive entry name into v.
+func unmarshalZipXML(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return errors.New(name + " not found in archive")
+	}
+	raw, err := readZipFile(f)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(raw, v)
+}
+
+// sortedRels returns relationships ordered by id, numerically for rIdN ids.
+func sortedRels(rels map[string]opcRel) []opcRel {
+	out := make([]opcRel, 0, len(rels))
+	for _, r := range rels {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].ID, out[j].ID
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+	return out
+}
@@ -0,0 +1,33 @@
+package extract
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestScanZipEntryLimit(t *testing.T) {
+	body := `<w:p><w:r><w:t>` + strings.Repeat("a", 1<<16) + `</w:t></w:r></w:p>`
+	data := testDOCX(t, body)
+	// drop the end of central directory record
+	data = data[:len(data)-22]
+
+	doc, err := scanZipEntry(data, "word/document.xml", 1<<20)
+	if err != nil || !strings.Contains(string(doc), body) {
+		t.Errorf("scanZipEntry: %d bytes, %v", len(doc), err)
+	}
+	if _, err := scanZipEntry(data, "word/document.xml", 1000); !errors.Is(err, ErrDecompressionLimit) {
+		t.Errorf("scanZipEntry over the limit: err = %v, want ErrDecompressionLimit", err)
+	}
+
+	opts := DefaultOptions()
+	opts.BestEffort = true
+	opts.MaxDecompressedBytes = 1000
+	if _, err := ExtractTextWithOptions("a.docx", data, opts); !errors.Is(err, ErrDecompressionLimit) {
+		t.Errorf("ExtractTextWithOptions: err = %v, want ErrDecompressionLimit", err)
+	}
+	opts.MaxDecompressedBytes = 0
+	if text, err := ExtractTextWithOptions("a.docx", data, opts); err != nil || len(text) < 1<<16 {
+		t.Errorf("ExtractTextWithOptions: %d bytes, %v", len(text), err)
+	}
+}
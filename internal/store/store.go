@@ -0,0 +1,81 @@
+// Package store keeps extraction results between requests.
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// ResultStore stores opaque result blobs by id. Implementations must be safe for
+// concurrent use; a zero ttl means the entry does not expire.
+type ResultStore interface {
+	Put(id string, data []byte, ttl time.Duration) error
+	// Get returns the stored data and whether it was found. Expired entries are
+	// reported as not found.
+	Get(id string) ([]byte, bool, error)
+}
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time // zero: never
+}
+
+// MemoryStore is an in-process ResultStore. It is the default for single-instance
+// deployments; data is lost on restart and not shared between replicas.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	now       func() time.Time
+	nextSweep time.Time
+}
+
+// sweepInterval is how often Put drops expired entries; Get drops the entry it
+// finds expired right away.
+const sweepInterval = time.Minute
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry), now: time.Now}
+}
+
+// Put stores a copy of data under id, replacing any previous entry.
+func (s *MemoryStore) Put(id string, data []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	// expired entries are dropped on write so the map does not grow unbounded,
+	// but at most once per sweepInterval so that Put is not linear in the size
+	if !now.Before(s.nextSweep) {
+		for k, e := range s.entries {
+			if e.expired(now) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(sweepInterval)
+	}
+	e := memoryEntry{data: append([]byte(nil), data...)}
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+	}
+	s.entries[id] = e
+	return nil
+}
+
+// Get returns a copy of the data stored under id.
+func (s *MemoryStore) Get(id string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok {
+		return nil, false, nil
+	}
+	if e.expired(s.now()) {
+		delete(s.entries, id)
+		return nil, false, nil
+	}
+	return append([]byte(nil), e.data...), true, nil
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+// testClock is a settable time source for MemoryStore.now.
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time { return c.t }
+
+func newTestStore() (*MemoryStore, *testClock) {
+	c := &testClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore()
+	s.now = c.now
+	return s, c
+}
+
+// testResultStoreContract checks the behavior every ResultStore must have;
+// advance moves the store's clock forward.
+func testResultStoreContract(t *testing.T, s ResultStore, advance func(time.Duration)) {
+	t.Helper()
+	if _, ok, err := s.Get("missing"); ok || err != nil {
+		t.Errorf("Get(missing) = %v, %v; want not found", ok, err)
+	}
+
+	data := []byte("result")
+	if err := s.Put("a", data, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	data[0] = 'X'
+	got, ok, err := s.Get("a")
+	if !ok || err != nil || string(got) != "result" {
+		t.Errorf("Get(a) = %q, %v, %v; want the data as stored", got, ok, err)
+	}
+	got[0] = 'Y'
+	if again, _, _ := s.Get("a"); string(again) != "result" {
+		t.Errorf("Get(a) after modifying a returned slice = %q", again)
+	}
+
+	if err := s.Put("a", []byte("replaced"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _ := s.Get("a"); string(got) != "replaced" {
+		t.Errorf("Get(a) after Put = %q, want replaced", got)
+	}
+
+	if err := s.Put("forever", []byte("kept"), 0); err != nil {
+		t.Fatal(err)
+	}
+	advance(time.Minute)
+	if _, ok, _ := s.Get("a"); ok {
+		t.Error("Get(a) found an entry past its ttl")
+	}
+	if got, ok, _ := s.Get("forever"); !ok || string(got) != "kept" {
+		t.Errorf("Get(forever) = %q, %v; want an entry without ttl kept", got, ok)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s, c := newTestStore()
+	testResultStoreContract(t, s, func(d time.Duration) { c.t = c.t.Add(d) })
+}
+
+// mapStore is a minimal ResultStore, standing in for an external backend, to
+// check that the contract test describes the interface and not MemoryStore.
+type mapStore struct {
+	clock   *testClock
+	entries map[string]memoryEntry
+}
+
+func (m *mapStore) Put(id string, data []byte, ttl time.Duration) error {
+	e := memoryEntry{data: append([]byte(nil), data...)}
+	if ttl > 0 {
+		e.expires = m.clock.t.Add(ttl)
+	}
+	m.entries[id] = e
+	return nil
+}
+
+func (m *mapStore) Get(id string) ([]byte, bool, error) {
+	e, ok := m.entries[id]
+	if !ok || e.expired(m.clock.t) {
+		return nil, false, nil
+	}
+	return append([]byte(nil), e.data...), true, nil
+}
+
+func TestResultStoreContractMock(t *testing.T) {
+	c := &testClock{}
+	var s ResultStore = &mapStore{clock: c, entries: make(map[string]memoryEntry)}
+	testResultStoreContract(t, s, func(d time.Duration) { c.t = c.t.Add(d) })
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s, c := newTestStore()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := s.Put(id, []byte(id), time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.t = c.t.Add(time.Second)
+	// within sweepInterval of the first Put, expired entries stay in the map
+	if err := s.Put("d", nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.entries) != 4 {
+		t.Errorf("%d entries before the sweep, want 4", len(s.entries))
+	}
+	c.t = c.t.Add(sweepInterval)
+	if err := s.Put("e", nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.entries) != 2 {
+		t.Errorf("%d entries after the sweep, want 2", len(s.entries))
+	}
+}